@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"armanVersionControl/config"
+	"armanVersionControl/diff"
+	"armanVersionControl/mailbox"
+	"armanVersionControl/refs"
+	"armanVersionControl/structures"
+	"armanVersionControl/track"
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	amThreeWay bool
+)
+
+var amCmd = &cobra.Command{
+	Use:   "am [--3way] [mbox...]",
+	Short: "Apply patches from mailbox files as commits.",
+	Long: `Reads emails from the mailbox files, or the standard input when none or "-" is provided, and commits the patch of
+each of them on top of HEAD, in order. The patches are applied to both the index and the working tree. Each
+commit keeps the author, the date and the message of its email, like the ones format-patch writes, and the
+commiter is read from user.name and user.email, see commit-tree.
+
+Arguments:
+    mbox		The mailbox files to read the emails from, or "-" for the standard input.
+
+Options:
+	--3way		When the hunks of a file do not apply, merge the changes of the patch into the file, see apply.
+			Conflicts can not be committed, so only patches which merge without conflicts are applied.
+
+Note:
+	- The index should have no changes which are not committed, and the files the patches change have to be the same
+	  in the index and the working tree.
+	- Prefixes of the subject like "[PATCH 1/2]" and "Re:" are removed from the commit message.
+	- When an email does not apply, am stops: the emails before it are committed and nothing of it or the emails
+	  after it is applied.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+
+		var messages []mailbox.Message
+		for _, name := range args {
+			m, err := readMailbox(name)
+			if err != nil {
+				return err
+			}
+
+			messages = append(messages, m...)
+		}
+
+		if err := checkIndexCommitted(); err != nil {
+			return err
+		}
+
+		for _, m := range messages {
+			fmt.Printf("Applying: %v\n", m.Subject)
+			if err := applyMailboxMessage(m); err != nil {
+				return fmt.Errorf("applying %q failed, it and the emails after it were not applied: %w", m.Subject, err)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	amCmd.Flags().BoolVar(&amThreeWay, "3way", false, "Merge the patches of files whose hunks do not apply.")
+	RootCmd.AddCommand(amCmd)
+}
+
+// readMailbox reads the messages of the mailbox file name, or the standard
+// input when name is "-".
+func readMailbox(name string) ([]mailbox.Message, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	messages, err := mailbox.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("'%v': %w", name, err)
+	}
+
+	return messages, nil
+}
+
+// checkIndexCommitted returns an error when the Index differs from HEAD, so
+// commits of am do not record anything but their patch.
+func checkIndexCommitted() error {
+	i, err := track.FetchIndex()
+	if err != nil && !errors.Is(err, track.ErrIndexNotFound) {
+		return err
+	}
+
+	if len(i.Conflicts()) > 0 {
+		return track.ErrUnmergedEntries
+	}
+
+	index, err := i.Tree()
+	if err != nil {
+		return err
+	}
+
+	var head *structures.Tree
+	t, err := fetchRevisionTree(refs.HEAD)
+	if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+		return err
+	}
+	if err == nil {
+		head = &t
+	}
+
+	changes, err := diff.Trees(head, &index, diff.TreeOptions{Recursive: true})
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return errors.New("the index has changes which are not committed, commit them first")
+	}
+
+	return nil
+}
+
+// applyMailboxMessage applies the patch of m to the Index and the working tree
+// and commits it on top of HEAD with the author, date and message of m.
+func applyMailboxMessage(m mailbox.Message) error {
+	patches, err := diff.ParsePatch(strings.NewReader(m.Patch))
+	if err != nil {
+		return err
+	}
+	if len(patches) == 0 {
+		return errors.New("the email has no patch")
+	}
+
+	results, err := track.Apply(patches, track.ApplyOptions{Index: true, ThreeWay: amThreeWay})
+
+	w := bufio.NewWriter(os.Stderr)
+	writeApplyReport(w, results)
+	if flushErr := w.Flush(); flushErr != nil {
+		return flushErr
+	}
+	if err != nil {
+		return err
+	}
+
+	tree, err := track.WriteTree()
+	if err != nil {
+		return err
+	}
+
+	var parents []string
+	head, err := refs.Resolve(refs.HEAD)
+	if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+		return err
+	}
+	if err == nil {
+		parents = append(parents, head)
+	}
+
+	commiter, err := config.Commiter()
+	if err != nil {
+		return err
+	}
+
+	c := structures.New(tree, parents, m.Author, m.AuthorEmail, m.Date,
+		commiter.Name, commiter.Email, commiter.Date, m.CommitMessage())
+	h, err := c.StoreCommit()
+	if err != nil {
+		return err
+	}
+
+	return refs.Update(refs.HEAD, h, head, "am: "+m.Subject)
+}
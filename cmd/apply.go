@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"armanVersionControl/diff"
+	"armanVersionControl/track"
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+var (
+	applyReverse bool
+	applyOpts    track.ApplyOptions
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply [--check] [--cached | --index] [--3way] [-R] patch",
+	Short: "Apply a patch to the working tree or the index.",
+	Long: `Reads the patches of files in the unified format from patch, or the standard input when it is "-", and applies them
+to the working tree. Patches made by diff are understood, including added, deleted, renamed and copied files and
+changed modes, as well as plain unified diffs. Anything around the patches, like the text of an email, is skipped.
+
+Arguments:
+    patch		The file to read the patches from, or "-" for the standard input.
+
+Options:
+	--check		Only check whether the patches apply, without changing anything.
+	--cached	Apply the patches to the index instead of the working tree.
+	--index		Apply the patches to both the index and the working tree. The patched files have to be the same in both.
+	--3way		When the hunks of a file do not apply, merge the changes of the patch into the file, using the
+			file the patch was made from as the base. It has to be in the object database. Lines both changed
+			are left between conflict markers.
+	-R		Apply the patches in reverse, which undoes them.
+
+Note:
+	- Each hunk is looked for where it expects to be and, when lines were added or deleted before it, further away.
+	  When it is still not found, up to 2 lines of context at each end are ignored. Hunks which moved or needed
+	  this fuzz are reported.
+	- Nothing is changed when any of the patches does not apply, and the hunks which failed are reported.
+	- Conflicts can not be applied to the index, so --3way with --cached or --index only applies patches without conflicts.
+	- Binary patches are not supported.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if applyOpts.Cached && applyOpts.Index {
+			return errors.New("--cached and --index can not be used together")
+		}
+
+		patches, err := readPatches(args[0])
+		if err != nil {
+			return err
+		}
+
+		if applyReverse {
+			for i := range patches {
+				if patches[i], err = patches[i].Reverse(); err != nil {
+					return err
+				}
+			}
+		}
+
+		results, err := track.Apply(patches, applyOpts)
+
+		w := bufio.NewWriter(os.Stderr)
+		writeApplyReport(w, results)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if errors.Is(err, track.ErrPatchFailed) {
+			return fmt.Errorf("%w, nothing was changed", err)
+		}
+
+		return err
+	},
+}
+
+func init() {
+	applyCmd.Flags().BoolVar(&applyOpts.Check, "check", false, "Only check whether the patches apply.")
+	applyCmd.Flags().BoolVar(&applyOpts.Cached, "cached", false, "Apply the patches to the index.")
+	applyCmd.Flags().BoolVar(&applyOpts.Index, "index", false, "Apply the patches to the index and the working tree.")
+	applyCmd.Flags().BoolVar(&applyOpts.ThreeWay, "3way", false, "Merge the patches of files whose hunks do not apply.")
+	applyCmd.Flags().BoolVarP(&applyReverse, "reverse", "R", false, "Apply the patches in reverse.")
+	RootCmd.AddCommand(applyCmd)
+}
+
+// readPatches reads the patches in the file name, or the standard input when
+// name is "-".
+func readPatches(name string) ([]diff.FilePatch, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	patches, err := diff.ParsePatch(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no patches found in '%v'", name)
+	}
+
+	return patches, nil
+}
+
+// writeApplyReport writes the hunks of results which failed, moved or needed
+// fuzz, the files which were merged and why patches did not apply to w.
+func writeApplyReport(w io.Writer, results []track.ApplyResult) {
+	for _, r := range results {
+		var lines []string
+		for i, h := range r.Hunks {
+			if h.Failed || h.Offset != 0 || h.Fuzz > 0 {
+				lines = append(lines, fmt.Sprintf("Hunk #%v %v.", i+1, h))
+			}
+		}
+		if r.Merged && r.Conflicts == 0 {
+			lines = append(lines, "Merged cleanly.")
+		} else if r.Merged {
+			lines = append(lines, fmt.Sprintf("Merged with conflicts: %v.", r.Conflicts))
+		}
+		if r.Err != nil {
+			lines = append(lines, fmt.Sprintf("error: %v", r.Err))
+		}
+
+		if len(lines) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "%v:\n", r.Patch.Path)
+		for _, l := range lines {
+			fmt.Fprintf(w, "\t%v\n", l)
+		}
+	}
+}
@@ -3,35 +3,138 @@ package cmd
 import (
 	"armanVersionControl/storage"
 	"armanVersionControl/structures"
+	"bufio"
 	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	prettyPrint bool
+	showType    bool
+	showSize    bool
+	checkExists bool
+	plainOutput bool
+	batch       bool
+	batchCheck  bool
 )
 
-var prettyPrint = false
 var catFileCmd = &cobra.Command{
-	Use:   "cat-file {hash} ([-p | --pretty-print])",
+	Use:   "cat-file ({object} ([-p | --pretty-print] | [-t | --type] | [-s | --size] | [-e | --exists] | --plain) | --batch | --batch-check)",
 	Short: "Display content of object by its hash.",
 	Long: `This command will display the content of an object stored in object by its hash.
 
 Arguments:
-    hash		The required hash representing the object ID stored in object database.`,
-	Args: cobra.ExactArgs(1),
+    object		The required object stored in object database, by hash, prefix of one or revision like main or
+			HEAD~1.
+
+Options:
+	-p	Pretty print the object based on its type. Blobs are printed as is, trees are printed in the ls-tree format
+		and commits are printed as their tree, parents, author, commiter and message.
+	-t	Print the type of the object, which is blob, tree or commit.
+	-s	Print the size of the object content in bytes, not counting the header.
+	-e	Print nothing, exit with zero status if the object exists and is valid, otherwise exit with non-zero status.
+	--plain	Print the object content without the header and without any decoration or trailing new line, so it
+		can be piped and round-trips byte-for-byte.
+	--batch	Read objects from the standard input, one per line, and print "<hash> <type> <size>" followed by a new line,
+		the object content as --plain prints it and another new line for each of them. An object which is not found
+		is printed as "<object> missing" and an ambiguous hash as "<object> ambiguous". An object which can not be
+		read or is corrupt is printed as missing too, and its error is printed to the standard error.
+	--batch-check
+		Same as --batch, but only the "<hash> <type> <size>" line is printed.
+	Output is flushed after each object in batch modes, so avc can be driven as a coprocess.
+
+Note:
+	There are no tag objects in avc yet, so blob, tree and commit are the only object types.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		hash := args[0]
+		modes := 0
+		for _, f := range []bool{prettyPrint, showType, showSize, checkExists, plainOutput, batch, batchCheck} {
+			if f {
+				modes++
+			}
+		}
+		if modes > 1 {
+			return errors.New("only one of -p, -t, -s, -e, --plain, --batch and --batch-check can be provided")
+		}
+
+		if batch || batchCheck {
+			if len(args) != 0 {
+				return errors.New("object can not be provided in batch modes")
+			}
+
+			return catFileBatch(os.Stdin, os.Stdout, batch)
+		}
+
+		if len(args) != 1 {
+			return errors.New("object is required")
+		}
+
+		hash, err := resolveRevision(args[0])
+		if err != nil {
+			if checkExists {
+				return silentExit(cmd, 1)
+			}
+
+			return err
+		}
+
+		if showType || showSize {
+			_, info, err := structures.FetchInfo(hash)
+			if err != nil {
+				return err
+			}
+
+			if showType {
+				fmt.Println(info.Kind)
+			} else {
+				fmt.Println(info.Size)
+			}
+
+			return nil
+		}
+
 		c, err := storage.FetchByHash(hash)
+		if checkExists {
+			if err != nil {
+				return silentExit(cmd, 1)
+			}
+			if _, err = structures.Decode(c); err != nil {
+				return silentExit(cmd, 1)
+			}
+
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 
+		if plainOutput {
+			info, err := structures.Identify(c.Content)
+			if err != nil {
+				return err
+			}
+
+			_, err = os.Stdout.Write(c.Content[info.HeaderLen:])
+			return err
+		}
+
 		s, err := computeOriginalContent(c)
 		if err != nil {
 			return err
 		}
 
-		// the \033[1;32 part is the coloring. Read more at: https://stackoverflow.com/questions/4842424/list-of-ansi-color-escape-sequences
-		fmt.Println("\033[1;32mHere is the content of provided hash:\033[0m")
-		fmt.Println(s)
+		if !prettyPrint && isTerminal(os.Stdout) {
+			// the \033[1;32 part is the coloring. Read more at: https://stackoverflow.com/questions/4842424/list-of-ansi-color-escape-sequences
+			fmt.Println("\033[1;32mHere is the content of provided hash:\033[0m")
+		}
+		fmt.Print(s)
+		if !strings.HasSuffix(s, "\n") {
+			fmt.Println()
+		}
 
 		return nil
 	},
@@ -39,35 +142,109 @@ Arguments:
 
 func init() {
 	catFileCmd.Flags().BoolVarP(&prettyPrint, "pretty-print", "p", false, "Pretty print the content of hash object based on its type.")
+	catFileCmd.Flags().BoolVarP(&showType, "type", "t", false, "Print the type of the object.")
+	catFileCmd.Flags().BoolVarP(&showSize, "size", "s", false, "Print the size of the object content.")
+	catFileCmd.Flags().BoolVarP(&checkExists, "exists", "e", false, "Exit with zero status if the object exists and is valid.")
+	catFileCmd.Flags().BoolVar(&plainOutput, "plain", false, "Print the object content without the header and any decoration.")
+	catFileCmd.Flags().BoolVar(&batch, "batch", false, "Print type, size and content of objects read from the standard input.")
+	catFileCmd.Flags().BoolVar(&batchCheck, "batch-check", false, "Print type and size of objects read from the standard input.")
 	RootCmd.AddCommand(catFileCmd)
 }
 
-func computeOriginalContent(o storage.Object) (string, error) {
-	if !prettyPrint {
-		return string(o.Content), nil
-	}
+// catFileBatch reads an object from each line of r and writes a record for it
+// to w. When withContent is true, the object content follows each record.
+// Objects which can not be read or are corrupt are reported as missing and
+// their error is printed to the standard error, so one bad object does not
+// end the batch.
+func catFileBatch(r io.Reader, w io.Writer, withContent bool) error {
+	bw := bufio.NewWriter(w)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		hash := strings.TrimSpace(sc.Text())
 
-	if structures.IsBlobB(o.Content) {
-		blob, err := structures.NewBlobFromB(o.Content)
-		if err != nil {
-			if errors.Is(err, structures.ErrNotABlob) {
-				panic("expected a blob but the content is not a blob")
+		// Only the header is needed to print the record line.
+		fullHash, err := resolveRevision(hash)
+		var info structures.ObjectInfo
+		if err == nil {
+			_, info, err = structures.FetchInfo(fullHash)
+		}
+		var content []byte
+		if err == nil && withContent {
+			content, err = fetchPlainContent(fullHash)
+		}
+
+		var hce *storage.HashCollisionError
+		switch {
+		case errors.As(err, &hce):
+			fmt.Fprintf(bw, "%v ambiguous\n", hash)
+		case errors.Is(err, errUnknownRevision), errors.Is(err, storage.ErrObjectNotFound), errors.Is(err, storage.ErrHashIsShort),
+			errors.Is(err, storage.ErrInvalidHash):
+			fmt.Fprintf(bw, "%v missing\n", hash)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "object %v: %v\n", hash, err)
+			fmt.Fprintf(bw, "%v missing\n", hash)
+		default:
+			fmt.Fprintf(bw, "%v %v %v\n", fullHash, info.Kind, info.Size)
+			if withContent {
+				bw.Write(content)
+				bw.WriteByte('\n')
 			}
+		}
 
-			return "", err
+		if err = bw.Flush(); err != nil {
+			return err
 		}
+	}
 
-		return string(blob.Content), nil
+	return sc.Err()
+}
+
+// fetchPlainContent returns the content of the object with hash without its
+// header.
+func fetchPlainContent(hash string) ([]byte, error) {
+	o, err := storage.FetchByHash(hash)
+	if err != nil {
+		return nil, err
 	}
 
-	if structures.IsTreeB(o.Content) {
-		t, err := structures.NewTreeFromObject(o)
-		if err != nil {
-			return "", err
+	info, err := structures.Identify(o.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Content[info.HeaderLen:], nil
+}
+
+// isTerminal checks whether f is a terminal rather than a pipe or a file.
+func isTerminal(f *os.File) bool {
+	s, err := f.Stat()
+	return err == nil && s.Mode()&os.ModeCharDevice != 0
+}
+
+func computeOriginalContent(o storage.Object) (string, error) {
+	if !prettyPrint {
+		return string(o.Content), nil
+	}
+
+	obj, err := structures.Decode(o)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := obj.(type) {
+	case structures.Blob:
+		return string(v.Content), nil
+	case structures.Tree:
+		var sb strings.Builder
+		for _, te := range v.Entries {
+			sb.WriteString(formatTreeEntry(te.Name, te))
+			sb.WriteRune('\n')
 		}
 
-		return t.String(), nil
+		return sb.String(), nil
+	case structures.Commit:
+		return v.String(), nil
 	}
 
-	return "", errors.New("invalid content, content should either be a Blob or a Tree")
+	panic("A new unexpected object kind detected.")
 }
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"armanVersionControl/track"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkoutOurs   bool
+	checkoutTheirs bool
+	checkoutMTime  bool
+)
+
+var checkoutCmd = &cobra.Command{
+	Use:   "checkout [--ours | --theirs] [--restore-mtime] path...",
+	Short: "Restore files of the working tree from the index.",
+	Long: `Overwrites the files in the working tree with their version in the index, which discards their changes that are not added.
+For files with conflicts of a merge, --ours and --theirs choose the version of HEAD or of the merged commit.
+
+Arguments:
+    path		The files to restore.
+
+Options:
+	--ours		Write the version of HEAD of files with conflicts.
+	--theirs	Write the version of the merged commit of files with conflicts.
+	--restore-mtime	Set the modification time of each file to the one recorded in the index, so build tools do not see
+			them as changed.
+
+Note:
+	- The conflicts stay in the index until the files are added, which records the version in the working tree.
+	- Trees do not record modification times, read-tree keeps the times in the index of files it does not change.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if checkoutOurs && checkoutTheirs {
+			return errors.New("--ours and --theirs can not be used together")
+		}
+
+		opts := track.CheckoutIndexOptions{Force: true, RestoreMTime: checkoutMTime}
+		if checkoutOurs {
+			opts.Stage = track.StageOurs
+		}
+		if checkoutTheirs {
+			opts.Stage = track.StageTheirs
+		}
+
+		if err := track.CheckoutIndex(args, opts); err != nil {
+			return err
+		}
+
+		fmt.Printf("Updated %v paths from the index\n", len(args))
+		return nil
+	},
+}
+
+func init() {
+	checkoutCmd.Flags().BoolVar(&checkoutOurs, "ours", false, "Write the version of HEAD of files with conflicts.")
+	checkoutCmd.Flags().BoolVar(&checkoutTheirs, "theirs", false, "Write the version of the merged commit of files with conflicts.")
+	checkoutCmd.Flags().BoolVar(&checkoutMTime, "restore-mtime", false, "Restore the modification time recorded in the index.")
+	RootCmd.AddCommand(checkoutCmd)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"armanVersionControl/track"
+	"errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkoutAll  bool
+	checkoutOpts track.CheckoutIndexOptions
+)
+
+var checkoutIndexCmd = &cobra.Command{
+	Use:   "checkout-index [-a | --all] [-f | --force] [--prefix string] [--restore-mtime] [paths...]",
+	Short: "Write files from the index to the working tree.",
+	Long: `Writes the content of the provided paths, or all paths with --all, from the index to the working tree.
+Executable bits and symbolic links are restored as they were added.
+
+Notes:
+	- Existing files are not overwritten unless --force is provided. Without it, nothing is written when any of the files exists.
+	- --prefix is prepended to each path as is, so --prefix=export/ writes the files into the export directory.
+	- --restore-mtime sets the modification time of each file to the one recorded in the index.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if checkoutAll == (len(args) > 0) {
+			return errors.New("either provide paths or --all")
+		}
+
+		return track.CheckoutIndex(args, checkoutOpts)
+	},
+}
+
+func init() {
+	checkoutIndexCmd.Flags().BoolVarP(&checkoutAll, "all", "a", false, "Write all paths in the index.")
+	checkoutIndexCmd.Flags().BoolVarP(&checkoutOpts.Force, "force", "f", false, "Overwrite existing files.")
+	checkoutIndexCmd.Flags().StringVar(&checkoutOpts.Prefix, "prefix", "", "Prepend this string to the path of each written file.")
+	checkoutIndexCmd.Flags().BoolVar(&checkoutOpts.RestoreMTime, "restore-mtime", false, "Restore the modification time recorded in the index.")
+	RootCmd.AddCommand(checkoutIndexCmd)
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"armanVersionControl/config"
+	"armanVersionControl/diff"
+	"armanVersionControl/mailbox"
+	"armanVersionControl/refs"
+	"armanVersionControl/structures"
+	"armanVersionControl/track"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var (
+	commitMessages []string
+)
+
+var commitCmd = &cobra.Command{
+	Use:   "commit [(-m message)...]",
+	Short: "Record the index as a new commit on top of HEAD.",
+	Long: `Creates a commit with the tree of the index and HEAD as its parent, and moves HEAD, or the branch it points to,
+to it. When a merge with conflicts is in progress, the commit merges it, with the merged commit as the second parent.
+
+Options:
+	-m		A paragraph of the message, can be repeated. It is required unless a merge is committed, whose
+			message defaults to the one merge chose.
+
+Note:
+	- Files with conflicts have to be resolved and added before committing, see status.
+	- Author and commiter are read like commit-tree does.
+	- A commit without any change compared to HEAD is refused, unless it commits a merge.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// cobra validated the arguments, so the usage does not help with any
+		// error from here on, like files with conflicts.
+		cmd.SilenceUsage = true
+
+		i, err := track.FetchIndex()
+		if err != nil && !errors.Is(err, track.ErrIndexNotFound) {
+			return err
+		}
+
+		if conflicts := i.Conflicts(); len(conflicts) > 0 {
+			var paths []string
+			for _, c := range conflicts {
+				paths = append(paths, c.Path)
+			}
+
+			return fmt.Errorf("%w:\n%v", track.ErrUnmergedEntries, strings.Join(paths, "\n"))
+		}
+
+		mergeHead, mergeMsg, merging, err := refs.ReadMergeHead()
+		if err != nil {
+			return err
+		}
+
+		message := strings.Join(commitMessages, "\n\n")
+		if len(commitMessages) == 0 {
+			if !merging {
+				return errors.New("a message is required, provide it with -m")
+			}
+			message = mergeMsg
+		}
+		message = strings.TrimSpace(message) + "\n"
+
+		head, err := refs.Resolve(refs.HEAD)
+		if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+			return err
+		}
+
+		if !merging {
+			if err = checkIndexChanged(i, head); err != nil {
+				return err
+			}
+		}
+
+		author, err := config.Author()
+		if err != nil {
+			return err
+		}
+
+		commiter, err := config.Commiter()
+		if err != nil {
+			return err
+		}
+
+		tree, err := track.WriteTree()
+		if err != nil {
+			return err
+		}
+
+		var parentHashes []string
+		if head != "" {
+			parentHashes = append(parentHashes, head)
+		}
+		if merging {
+			parentHashes = append(parentHashes, mergeHead)
+		}
+
+		c := structures.New(tree, parentHashes, author.Name, author.Email, author.Date,
+			commiter.Name, commiter.Email, commiter.Date, message)
+		h, err := c.StoreCommit()
+		if err != nil {
+			return err
+		}
+
+		subject, _ := mailbox.SplitMessage(message)
+		kind := "commit"
+		if head == "" {
+			kind = "commit (initial)"
+		} else if merging {
+			kind = "commit (merge)"
+		}
+		if err = refs.Update(refs.HEAD, h, head, fmt.Sprintf("%v: %v", kind, subject)); err != nil {
+			return err
+		}
+
+		if merging {
+			if err = refs.RemoveMergeHead(); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("[%v] %v\n", h[:min(len(h), 7)], subject)
+		return nil
+	},
+}
+
+func init() {
+	commitCmd.Flags().StringArrayVarP(&commitMessages, "message", "m", nil, "A paragraph of the commit message, can be repeated.")
+	RootCmd.AddCommand(commitCmd)
+}
+
+// checkIndexChanged returns an error when the Index i has the same files as
+// the commit head, or is empty before the first commit.
+func checkIndexChanged(i track.Index, head string) error {
+	index, err := i.Tree()
+	if err != nil {
+		return err
+	}
+
+	var t *structures.Tree
+	if head != "" {
+		tree, err := structures.FetchTreeish(head)
+		if err != nil {
+			return err
+		}
+		t = &tree
+	}
+
+	changes, err := diff.Trees(t, &index, diff.TreeOptions{Recursive: true})
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return errors.New("nothing to commit, add the changes to the index first")
+	}
+
+	return nil
+}
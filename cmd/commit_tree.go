@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"armanVersionControl/config"
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	parents  []string
+	messages []string
+)
+
+var commitTreeCmd = &cobra.Command{
+	Use:   "commit-tree tree [(-p parent)...] [(-m message)...]",
+	Short: "Create a new commit object from a tree.",
+	Long: `Creates a new commit object for the provided tree, stores it in the object database and prints its hash.
+No reference is updated, the commit is only reachable through the printed hash.
+
+Arguments:
+    tree		The hash of an existing tree object.
+
+Notes:
+	- Each -p adds a parent commit, a commit without any parent is a root commit.
+	- Each -m is a paragraph of the message. When -m is not provided, the message is read from the standard input.
+	- Author and commiter are read from user.name and user.email which can be overridden with the AVC_AUTHOR_NAME,
+	  AVC_AUTHOR_EMAIL, AVC_AUTHOR_DATE, AVC_COMMITER_NAME, AVC_COMMITER_EMAIL and AVC_COMMITER_DATE environment variables.
+	  Dates are in RFC3339 format.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := fetchObjectOfType(args[0], structures.IsTreeB, "tree")
+		if err != nil {
+			return err
+		}
+
+		var parentHashes []string
+		for _, p := range parents {
+			c, err := fetchObjectOfType(p, structures.IsCommitB, "commit")
+			if err != nil {
+				return err
+			}
+
+			parentHashes = append(parentHashes, c.Hash)
+		}
+
+		message := strings.Join(messages, "\n\n")
+		if len(messages) == 0 {
+			b, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			message = string(b)
+		}
+		if !strings.HasSuffix(message, "\n") {
+			message += "\n"
+		}
+
+		author, err := config.Author()
+		if err != nil {
+			return err
+		}
+
+		commiter, err := config.Commiter()
+		if err != nil {
+			return err
+		}
+
+		c := structures.New(t.Hash, parentHashes, author.Name, author.Email, author.Date,
+			commiter.Name, commiter.Email, commiter.Date, message)
+		h, err := c.StoreCommit()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(h)
+		return nil
+	},
+}
+
+func init() {
+	commitTreeCmd.Flags().StringArrayVarP(&parents, "parent", "p", nil, "Hash of a parent commit, can be repeated.")
+	commitTreeCmd.Flags().StringArrayVarP(&messages, "message", "m", nil, "A paragraph of the commit message, can be repeated.")
+	RootCmd.AddCommand(commitTreeCmd)
+}
+
+// fetchObjectOfType fetches the object with hash and makes sure is has the
+// expected type using isType.
+func fetchObjectOfType(hash string, isType func([]byte) bool, typeName string) (storage.Object, error) {
+	o, err := storage.FetchByHash(hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			return storage.Object{}, fmt.Errorf("%v %v: %w", typeName, hash, err)
+		}
+
+		return storage.Object{}, err
+	}
+
+	if !isType(o.Content) {
+		return storage.Object{}, fmt.Errorf("object %v is not a %v", hash, typeName)
+	}
+
+	return o, nil
+}
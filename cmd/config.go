@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"armanVersionControl/config"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config key [value]",
+	Short: "Get or set repository options.",
+	Long: `Prints the value of key, or sets it to value when value is provided. Options are stored in the config file of the avc repository.
+
+Known options:
+	user.name	The name used as author and commiter of new commits.
+	user.email	The email used as author and commiter of new commits.
+	gc.pruneExpire	How long gc keeps unreachable objects, e.g. 2w, 14d, 36h, now or never. Defaults to 2w.
+	color.diff	When diff highlights its output: always, never or auto, which only highlights for a terminal. Defaults to auto.
+	diff.algorithm	How diff matches lines: myers, patience or histogram. Defaults to myers.
+	diff.renames	Whether diff, status and log detect renamed files: true, false or copies to detect copies too. Defaults to true.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+		if len(args) == 2 {
+			return config.Set(key, args[1])
+		}
+
+		v, ok, err := config.Get(key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%v is not set", key)
+		}
+
+		fmt.Println(v)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,460 @@
+package cmd
+
+import (
+	"armanVersionControl/config"
+	"armanVersionControl/diff"
+	"armanVersionControl/refs"
+	"armanVersionControl/structures"
+	"armanVersionControl/track"
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	diffStaged     bool
+	diffStat       bool
+	diffNumStat    bool
+	diffNameOnly   bool
+	diffNameStatus bool
+	diffUnified    int
+	diffColor      string
+	diffAlgorithm  string
+	diffWordDiff   string
+	ignoreAllSpace bool
+	ignoreSpace    bool
+	ignoreBlank    bool
+	findRenames    string
+	findCopies     string
+	noRenames      bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [--staged] [options] [revision [revision]] [-- path...]",
+	Short: "Show changes between the working tree, the index and commits.",
+	Long: `Shows the changes of tracked files in the unified diff format git uses.
+
+	avc diff [-- path...]				Changes in the working tree which are not added to the index yet.
+	avc diff --staged [revision] [-- path...]	Changes in the index compared to revision, which defaults to HEAD.
+	avc diff revision [-- path...]			Changes in the working tree compared to revision.
+	avc diff revision revision [-- path...]	Changes between two revisions, "A..B" is the same as "A B".
+
+Arguments:
+    revision		A ref like main or HEAD, or the hash of a commit or a tree, optionally followed by ^<n> or ~<n>.
+    path		Only show changes of these paths and everything under them.
+
+Options:
+	--staged	Compare the index instead of the working tree, --cached is the same.
+	--stat		Show how many lines changed in each file instead of the changes.
+	--numstat	Like --stat, but with the number of added and deleted lines in a format for scripts.
+	--name-only	Only show the names of changed files.
+	--name-status	Only show the names and the status of changed files, see diff-tree.
+	-U		The number of unchanged lines shown around each change, defaults to 3.
+	--color		When to highlight the output: always, never or auto, which only highlights for a terminal.
+			Defaults to the color.diff config, or auto when it is not set.
+	--diff-algorithm	How lines are matched: myers, patience or histogram.
+			Defaults to the diff.algorithm config, or myers when it is not set.
+	--word-diff	Show changed words within lines, as [-deleted-] and {+added+} or with --word-diff=color only with colors.
+	-w		Ignore whitespace when comparing lines.
+	-b		Ignore changes in the amount of whitespace and whitespace at the end of lines.
+	--ignore-blank-lines	Ignore changes which only add or delete blank lines.
+	-M		Detect renamed files which are at least this similar, e.g. -M50%. Renames are detected with
+			50% by default, unless the diff.renames config is false.
+	-C		Like -M, but also detect files which are copies of changed files.
+	--no-renames	Show renamed files as a deleted and an added file.
+
+Note:
+	- Untracked files are not shown, add them to the index to see them.
+	- When "--" is left out, arguments which are not revisions but exist in the working tree are paths.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formats := 0
+		for _, f := range []bool{diffStat, diffNumStat, diffNameOnly, diffNameStatus} {
+			if f {
+				formats++
+			}
+		}
+		if formats > 1 {
+			return errors.New("only one of --stat, --numstat, --name-only and --name-status can be used")
+		}
+
+		revs, paths, err := splitRevisionArgs(args, cmd.ArgsLenAtDash())
+		if err != nil {
+			return err
+		}
+
+		if len(revs) == 1 {
+			if from, to, ok := strings.Cut(revs[0], ".."); ok {
+				revs = []string{cmp.Or(from, refs.HEAD), cmp.Or(to, refs.HEAD)}
+			}
+		}
+
+		old, new, err := diffSides(revs, diffStaged)
+		if err != nil {
+			return err
+		}
+
+		changes, err := diff.Trees(old.tree, new.tree, diff.TreeOptions{Recursive: true, Paths: paths})
+		if err != nil {
+			return err
+		}
+
+		renames, err := renameOptions()
+		if err != nil {
+			return err
+		}
+		if renames != nil {
+			if changes, err = diff.DetectRenames(changes, *renames, old.load, new.load); err != nil {
+				return err
+			}
+		}
+
+		opts, err := diffOptions()
+		if err != nil {
+			return err
+		}
+
+		w := bufio.NewWriter(os.Stdout)
+		if err = writeChanges(w, changes, old, new, opts); err != nil {
+			return err
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffStaged, "staged", false, "Compare the index to a revision, HEAD by default.")
+	diffCmd.Flags().BoolVar(&diffStaged, "cached", false, "Same as --staged.")
+	diffCmd.Flags().BoolVar(&diffStat, "stat", false, "Show how many lines changed in each file.")
+	diffCmd.Flags().BoolVar(&diffNumStat, "numstat", false, "Show the number of added and deleted lines of each file.")
+	diffCmd.Flags().BoolVar(&diffNameOnly, "name-only", false, "Only show the names of changed files.")
+	diffCmd.Flags().BoolVar(&diffNameStatus, "name-status", false, "Only show the names and the status of changed files.")
+	addDiffFlags(diffCmd)
+	addRenameFlags(diffCmd)
+	RootCmd.AddCommand(diffCmd)
+}
+
+// addDiffFlags adds the flags diffOptions reads to cmd.
+func addDiffFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&diffUnified, "unified", "U", diff.DefaultContext, "Number of unchanged lines shown around each change.")
+	cmd.Flags().StringVar(&diffColor, "color", "", "When to highlight the output: always, never or auto.")
+	cmd.Flags().Lookup("color").NoOptDefVal = "always"
+	cmd.Flags().StringVar(&diffAlgorithm, "diff-algorithm", "", "How lines are matched: myers, patience or histogram.")
+	cmd.Flags().StringVar(&diffWordDiff, "word-diff", "", "Show changed words within lines: plain or color.")
+	cmd.Flags().Lookup("word-diff").NoOptDefVal = "plain"
+	cmd.Flags().BoolVarP(&ignoreAllSpace, "ignore-all-space", "w", false, "Ignore whitespace when comparing lines.")
+	cmd.Flags().BoolVarP(&ignoreSpace, "ignore-space-change", "b", false, "Ignore changes in the amount of whitespace.")
+	cmd.Flags().BoolVar(&ignoreBlank, "ignore-blank-lines", false, "Ignore changes which only add or delete blank lines.")
+}
+
+// diffOptions returns the diff.Options set by the flags addDiffFlags adds,
+// falling back to the color.diff and diff.algorithm configs.
+func diffOptions() (diff.Options, error) {
+	opts := diff.DefaultOptions()
+	opts.Context = diffUnified
+	opts.IgnoreAllSpace = ignoreAllSpace
+	opts.IgnoreSpaceChange = ignoreSpace
+	opts.IgnoreBlankLines = ignoreBlank
+
+	var err error
+	if opts.Color, err = useColor(diffColor, "color.diff"); err != nil {
+		return diff.Options{}, err
+	}
+
+	algorithm := diffAlgorithm
+	if algorithm == "" {
+		v, ok, err := config.Get("diff.algorithm")
+		if err != nil {
+			return diff.Options{}, err
+		}
+
+		algorithm = "myers"
+		if ok {
+			algorithm = v
+		}
+	}
+	if opts.Algorithm, err = diff.ParseAlgorithm(algorithm); err != nil {
+		return diff.Options{}, err
+	}
+
+	switch diffWordDiff {
+	case "":
+	case "plain":
+		opts.WordDiff = diff.WordDiffPlain
+	case "color":
+		opts.WordDiff = diff.WordDiffColor
+		opts.Color = true
+	default:
+		return diff.Options{}, fmt.Errorf("invalid --word-diff value %q, it should be plain or color", diffWordDiff)
+	}
+
+	return opts, nil
+}
+
+// addRenameFlags adds the flags renameOptions reads to cmd.
+func addRenameFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&findRenames, "find-renames", "M", "", "Detect renames which are at least this similar, e.g. 50%.")
+	cmd.Flags().StringVarP(&findCopies, "find-copies", "C", "", "Detect renames and copies which are at least this similar, e.g. 50%.")
+	cmd.Flags().BoolVar(&noRenames, "no-renames", false, "Do not detect renames.")
+}
+
+// renameOptions returns how renames are detected according to the flags
+// addRenameFlags adds and the diff.renames config, which is true, false or
+// copies. It returns nil when renames should not be detected.
+func renameOptions() (*diff.RenameOptions, error) {
+	if noRenames {
+		return nil, nil
+	}
+
+	opts := diff.DefaultRenameOptions()
+	var err error
+	switch {
+	case findCopies != "":
+		opts.Copies = true
+		opts.Threshold, err = parseSimilarity(findCopies)
+	case findRenames != "":
+		opts.Threshold, err = parseSimilarity(findRenames)
+	default:
+		v, ok, err := config.Get("diff.renames")
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !ok || v == "true":
+		case v == "copies":
+			opts.Copies = true
+		case v == "false":
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("invalid diff.renames value %q, it should be true, false or copies", v)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &opts, nil
+}
+
+// parseSimilarity parses a similarity in percent like 50%, the '%' is optional.
+func parseSimilarity(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "%"))
+	if err != nil || n < 0 || n > 100 {
+		return 0, fmt.Errorf("invalid similarity %q, it should be a percentage between 0%% and 100%%", s)
+	}
+
+	return n, nil
+}
+
+// diffSide is one side of a comparison made by diff.
+type diffSide struct {
+	// tree holds the files of the side, it is nil when there are none.
+	tree *structures.Tree
+	// load returns the content of a file of the side, it is diff.LoadWorkingTree
+	// for the working tree, whose Blobs are not stored.
+	load diff.ContentLoader
+}
+
+// content returns the content of the file name with mode and hash on s.
+// It returns nil when hash is empty, which means there is no such file.
+func (s diffSide) content(name string, mode structures.EntryMode, hash string) ([]byte, error) {
+	if hash == "" {
+		return nil, nil
+	}
+
+	return s.load(name, mode, hash)
+}
+
+// splitRevisionArgs splits args into revisions and paths. dash is the number
+// of args before "--", or -1 when there is none. Without "--", the args from
+// the first one which is not a revision but exists in the working tree are
+// paths. Ranges like "A..B" and exclusions like "^A" are taken as revisions.
+func splitRevisionArgs(args []string, dash int) (revs []string, paths []string, err error) {
+	if dash >= 0 {
+		revs, paths = args[:dash], args[dash:]
+	} else {
+		for i, a := range args {
+			if _, err := resolveRevision(strings.TrimPrefix(a, "^")); err != nil && !strings.Contains(a, "..") {
+				if _, statErr := os.Lstat(a); statErr != nil {
+					return nil, nil, err
+				}
+
+				revs, paths = args[:i], args[i:]
+				break
+			}
+			revs = args[:i+1]
+		}
+	}
+
+	for i, p := range paths {
+		paths[i] = filepath.ToSlash(filepath.Clean(p))
+	}
+
+	return revs, paths, nil
+}
+
+// diffSides returns the old and the new side diff compares for revs.
+func diffSides(revs []string, staged bool) (diffSide, diffSide, error) {
+	if len(revs) > 2 {
+		return diffSide{}, diffSide{}, errors.New("at most two revisions can be compared")
+	}
+
+	if len(revs) == 2 {
+		if staged {
+			return diffSide{}, diffSide{}, errors.New("--staged compares the index to a single revision")
+		}
+
+		old, err := fetchRevisionTree(revs[0])
+		if err != nil {
+			return diffSide{}, diffSide{}, err
+		}
+
+		new, err := fetchRevisionTree(revs[1])
+		if err != nil {
+			return diffSide{}, diffSide{}, err
+		}
+
+		return diffSide{tree: &old, load: diff.LoadBlob}, diffSide{tree: &new, load: diff.LoadBlob}, nil
+	}
+
+	i, err := track.FetchIndex()
+	if err != nil && !errors.Is(err, track.ErrIndexNotFound) {
+		return diffSide{}, diffSide{}, err
+	}
+
+	index, err := i.Tree()
+	if err != nil {
+		return diffSide{}, diffSide{}, err
+	}
+
+	old := diffSide{load: diff.LoadBlob}
+	switch {
+	case len(revs) == 1:
+		t, err := fetchRevisionTree(revs[0])
+		if err != nil {
+			return diffSide{}, diffSide{}, err
+		}
+		old.tree = &t
+	case staged:
+		t, err := fetchRevisionTree(refs.HEAD)
+		if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+			return diffSide{}, diffSide{}, err
+		}
+		// Before the first commit everything in the index is new.
+		if err == nil {
+			old.tree = &t
+		}
+	default:
+		old.tree = &index
+	}
+
+	if staged {
+		return old, diffSide{tree: &index, load: diff.LoadBlob}, nil
+	}
+
+	wt, err := i.WorkingTree()
+	if err != nil {
+		return diffSide{}, diffSide{}, err
+	}
+
+	return old, diffSide{tree: &wt, load: diff.LoadWorkingTree}, nil
+}
+
+// writeChanges writes changes between old and new to w in the format the
+// flags of diff select.
+func writeChanges(w io.Writer, changes []diff.Change, old diffSide, new diffSide, opts diff.Options) error {
+	if diffNameOnly || diffNameStatus {
+		for _, c := range changes {
+			if diffNameOnly {
+				fmt.Fprintln(w, c.Path)
+				continue
+			}
+
+			fmt.Fprintln(w, formatNameStatus(c))
+		}
+
+		return nil
+	}
+
+	var stats []diff.FileStat
+	for _, c := range changes {
+		a, err := old.content(c.SourcePath(), c.OldMode, c.OldHash)
+		if err != nil {
+			return err
+		}
+
+		b, err := new.content(c.Path, c.NewMode, c.NewHash)
+		if err != nil {
+			return err
+		}
+
+		if diffStat || diffNumStat {
+			stats = append(stats, diff.NewFileStat(c.CompactName(), a, b, opts))
+			continue
+		}
+
+		if err = diff.WritePatch(w, c, a, b, opts); err != nil {
+			return err
+		}
+	}
+
+	if diffNumStat {
+		return diff.WriteNumStat(w, stats)
+	}
+	if diffStat {
+		return diff.WriteStat(w, stats, opts)
+	}
+
+	return nil
+}
+
+// formatNameStatus formats c as a "<status> TAB <path>" line, renamed and
+// copied paths have their old path before the new one.
+func formatNameStatus(c diff.Change) string {
+	if c.OldPath != "" {
+		return fmt.Sprintf("%v\t%v\t%v", c.Status(), c.OldPath, c.Path)
+	}
+
+	return fmt.Sprintf("%v\t%v", c.Status(), c.Path)
+}
+
+// useColor decides whether output is highlighted from when, which is always,
+// never or auto. When when is empty, the config key is used and auto when it
+// is not set either. auto highlights when the standard output is a terminal.
+func useColor(when string, key string) (bool, error) {
+	if when == "" {
+		v, ok, err := config.Get(key)
+		if err != nil {
+			return false, err
+		}
+
+		when = "auto"
+		if ok {
+			when = v
+		}
+	}
+
+	switch when {
+	case "always", "true":
+		return true, nil
+	case "never", "false":
+		return false, nil
+	case "auto":
+		s, err := os.Stdout.Stat()
+		if err != nil {
+			return false, nil
+		}
+
+		return s.Mode()&os.ModeCharDevice != 0, nil
+	}
+
+	return false, fmt.Errorf("invalid color value %q, it should be always, never or auto", when)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"armanVersionControl/diff"
+	"armanVersionControl/structures"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var diffBlobCmd = &cobra.Command{
+	Use:   "diff-blob [-U n] old new",
+	Short: "Show the changes between two blobs.",
+	Long: `Compares the content of two blobs line by line and prints the changes in the unified diff format.
+
+Arguments:
+    old		The hash of the blob to compare from.
+    new		The hash of the blob to compare to.
+
+Options:
+	-U		The number of unchanged lines shown around each change, defaults to 3.
+	--color		When to highlight the output: always, never or auto, which only highlights for a terminal.
+			Defaults to the color.diff config, or auto when it is not set.
+	--diff-algorithm	How lines are matched: myers, patience or histogram.
+			Defaults to the diff.algorithm config, or myers when it is not set.
+	--word-diff	Show changed words within lines, as [-deleted-] and {+added+} or with --word-diff=color only with colors.
+	-w		Ignore whitespace when comparing lines.
+	-b		Ignore changes in the amount of whitespace and whitespace at the end of lines.
+	--ignore-blank-lines	Ignore changes which only add or delete blank lines.
+
+Note:
+	- Nothing is printed when the blobs have the same content.
+	- Blobs whose content has a NUL byte near the start are binary, only whether they differ is printed for them.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var blobs [2]structures.Blob
+		var hashes [2]string
+		for i, h := range args {
+			o, err := fetchObjectOfType(h, structures.IsBlobB, "blob")
+			if err != nil {
+				return err
+			}
+
+			if blobs[i], err = structures.NewBlobFromB(o.Content); err != nil {
+				return err
+			}
+			hashes[i] = o.Hash
+		}
+
+		opts, err := diffOptions()
+		if err != nil {
+			return err
+		}
+
+		return diff.Unified(os.Stdout, "a/"+hashes[0], "b/"+hashes[1], blobs[0].Content, blobs[1].Content, opts)
+	},
+}
+
+func init() {
+	addDiffFlags(diffBlobCmd)
+	RootCmd.AddCommand(diffBlobCmd)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"armanVersionControl/diff"
+	"armanVersionControl/structures"
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var (
+	diffTreeRecursive  bool
+	diffTreeNameOnly   bool
+	diffTreeNameStatus bool
+)
+
+var diffTreeCmd = &cobra.Command{
+	Use:   "diff-tree [-r] [--name-only | --name-status] tree-ish tree-ish [path...]",
+	Short: "Compare the content and mode of paths in two trees.",
+	Long: `Compares two trees and lists the paths which differ, one per line in the following format:
+
+	:<old mode> SP <new mode> SP <old hash> SP <new hash> SP <status> TAB <path>
+
+The status is A for added, D for deleted, M for modified and T for paths which changed between a file and a symbolic link.
+The mode and the hash are all zeros on the side where the path does not exist.
+
+Arguments:
+    tree-ish		A ref or the hash of a tree or a commit, in case of a commit its tree is compared.
+    path		Only compare these paths and everything under them.
+
+Notes:
+	- Without -r, changed subdirectories are listed as a whole and are not recursed into.
+	- Subdirectories with the same hash in both trees are not looked into.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if diffTreeNameOnly && diffTreeNameStatus {
+			return fmt.Errorf("--name-only and --name-status can not be used together")
+		}
+
+		var trees [2]structures.Tree
+		for i, h := range args[:2] {
+			t, err := fetchRevisionTree(h)
+			if err != nil {
+				return err
+			}
+			trees[i] = t
+		}
+
+		changes, err := diff.Trees(&trees[0], &trees[1], diff.TreeOptions{Recursive: diffTreeRecursive, Paths: args[2:]})
+		if err != nil {
+			return err
+		}
+
+		for _, c := range changes {
+			switch {
+			case diffTreeNameOnly:
+				fmt.Println(c.Path)
+			case diffTreeNameStatus:
+				fmt.Println(formatNameStatus(c))
+			default:
+				fmt.Println(formatRawChange(c))
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	diffTreeCmd.Flags().BoolVarP(&diffTreeRecursive, "recursive", "r", false, "Recurse into subdirectories.")
+	diffTreeCmd.Flags().BoolVar(&diffTreeNameOnly, "name-only", false, "Only list the names of changed paths.")
+	diffTreeCmd.Flags().BoolVar(&diffTreeNameStatus, "name-status", false, "Only list the status and the names of changed paths.")
+	RootCmd.AddCommand(diffTreeCmd)
+}
+
+// formatRawChange formats c as a ":<old mode> <new mode> <old hash> <new hash> <status>\t<path>" line.
+func formatRawChange(c diff.Change) string {
+	oldHash, newHash := c.OldHash, c.NewHash
+	if oldHash == "" {
+		oldHash = strings.Repeat("0", len(newHash))
+	}
+	if newHash == "" {
+		newHash = strings.Repeat("0", len(oldHash))
+	}
+
+	return fmt.Sprintf(":%v %v %v %v %v", c.OldMode, c.NewMode, oldHash, newHash, formatNameStatus(c))
+}
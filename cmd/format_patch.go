@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"armanVersionControl/diff"
+	"armanVersionControl/history"
+	"armanVersionControl/mailbox"
+	"armanVersionControl/structures"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxPatchNameLen is the length of the subject in the names of the files
+	// format-patch writes at most.
+	maxPatchNameLen = 52
+)
+
+var (
+	formatPatchOutput string
+	formatPatchStdout bool
+)
+
+var formatPatchCmd = &cobra.Command{
+	Use:   "format-patch [-o dir] [--stdout] (since | A..B)",
+	Short: "Write commits as patches in mailbox files.",
+	Long: `Writes each commit of a range as an email in the mailbox format, which am applies, and prints the names of the files.
+The files are named after the number of the commit in the range and its subject, e.g. 0001-fix-typo.patch.
+Each email has the author, the date and the message of the commit, the statistics of its changes and its diff.
+
+Arguments:
+    since		The commits which are reachable from HEAD but not from since, the same as "since..HEAD".
+    A..B		The commits which are reachable from B but not from A.
+
+Options:
+	-o		The directory to write the files into, which is created when needed. Defaults to the current directory.
+	--stdout	Write all emails to the standard output instead of files.
+	-M		Detect renamed files which are at least this similar, e.g. -M50%. Renames are detected with
+			50% by default, unless the diff.renames config is false.
+	-C		Like -M, but also detect files which are copies of changed files.
+	--no-renames	Show renamed files as a deleted and an added file.
+
+Note:
+	- Commits are written from the oldest to the newest, merge commits are left out.
+	- Changes of binary files are only mentioned, they can not be applied from the emails.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rev := args[0]
+		if !strings.Contains(rev, "..") {
+			rev += ".."
+		}
+
+		include, exclude, err := logRange([]string{rev})
+		if err != nil {
+			return err
+		}
+
+		renames, err := renameOptions()
+		if err != nil {
+			return err
+		}
+
+		var commits []structures.Commit
+		err = history.WalkRange(include, exclude, func(c structures.Commit) error {
+			if len(c.ParentHashes) <= 1 {
+				commits = append(commits, c)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		slices.Reverse(commits)
+
+		if !formatPatchStdout && formatPatchOutput != "" {
+			if err = os.MkdirAll(formatPatchOutput, 0o777); err != nil {
+				return err
+			}
+		}
+
+		for i, c := range commits {
+			m, err := commitMailboxMessage(c, renames)
+			if err != nil {
+				return err
+			}
+
+			if formatPatchStdout {
+				if err = mailbox.Write(os.Stdout, m, i+1, len(commits)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			name := filepath.Join(formatPatchOutput, fmt.Sprintf("%04d-%v.patch", i+1, patchFileName(m.Subject)))
+			if err = writeMailboxFile(name, m, i+1, len(commits)); err != nil {
+				return err
+			}
+			fmt.Println(name)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	formatPatchCmd.Flags().StringVarP(&formatPatchOutput, "output-directory", "o", "", "The directory to write the files into.")
+	formatPatchCmd.Flags().BoolVar(&formatPatchStdout, "stdout", false, "Write all emails to the standard output.")
+	addRenameFlags(formatPatchCmd)
+	RootCmd.AddCommand(formatPatchCmd)
+}
+
+// commitMailboxMessage returns c as a mailbox.Message, whose patch is the diff
+// of c and its parent.
+func commitMailboxMessage(c structures.Commit, renames *diff.RenameOptions) (mailbox.Message, error) {
+	t, err := c.FetchTree()
+	if err != nil {
+		return mailbox.Message{}, err
+	}
+
+	var pt *structures.Tree
+	if len(c.ParentHashes) > 0 {
+		tree, err := structures.FetchTreeish(c.ParentHashes[0])
+		if err != nil {
+			return mailbox.Message{}, err
+		}
+		pt = &tree
+	}
+
+	changes, err := diff.Trees(pt, &t, diff.TreeOptions{Recursive: true})
+	if err != nil {
+		return mailbox.Message{}, err
+	}
+	if renames != nil {
+		if changes, err = diff.DetectRenames(changes, *renames, diff.LoadBlob, diff.LoadBlob); err != nil {
+			return mailbox.Message{}, err
+		}
+	}
+
+	side := diffSide{load: diff.LoadBlob}
+	opts := diff.DefaultOptions()
+	var patch strings.Builder
+	var stats []diff.FileStat
+	for _, ch := range changes {
+		a, err := side.content(ch.SourcePath(), ch.OldMode, ch.OldHash)
+		if err != nil {
+			return mailbox.Message{}, err
+		}
+
+		b, err := side.content(ch.Path, ch.NewMode, ch.NewHash)
+		if err != nil {
+			return mailbox.Message{}, err
+		}
+
+		stats = append(stats, diff.NewFileStat(ch.CompactName(), a, b, opts))
+		if err = diff.WritePatch(&patch, ch, a, b, opts); err != nil {
+			return mailbox.Message{}, err
+		}
+	}
+
+	var sb strings.Builder
+	if err = diff.WriteStat(&sb, stats, opts); err != nil {
+		return mailbox.Message{}, err
+	}
+	sb.WriteString("\n" + patch.String())
+
+	subject, body := mailbox.SplitMessage(c.Message)
+	return mailbox.Message{
+		Hash:        c.Hash,
+		Author:      c.Author,
+		AuthorEmail: c.AuthorEmail,
+		Date:        c.AuthorDate,
+		Subject:     subject,
+		Body:        body,
+		Patch:       sb.String(),
+	}, nil
+}
+
+// patchFileName turns subject into a file name, with runs of anything but
+// ASCII letters, digits, dots and underscores replaced by a dash.
+func patchFileName(subject string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range subject {
+		if r == '.' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		dash = true
+	}
+
+	name := strings.TrimRight(sb.String()[:min(sb.Len(), maxPatchNameLen)], ".-")
+	if name == "" {
+		return "patch"
+	}
+
+	return name
+}
+
+// writeMailboxFile writes m to the file name, see mailbox.Write.
+func writeMailboxFile(name string, m mailbox.Message, n int, total int) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err = mailbox.Write(f, m, n, total); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"armanVersionControl/fsck"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var (
+	fsckFull             bool
+	fsckConnectivityOnly bool
+	fsckUnreachable      bool
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck [--full] [--connectivity-only] [--unreachable]",
+	Short: "Verify the integrity of the object database and the index.",
+	Long: `Verifies every object in the object database and the index. Objects are rehashed and compared with their names,
+decoded, and every tree entry and commit tree and parent is checked to point at an existing object of the right type.
+
+Problems are printed to the standard error, and the exit status is non-zero when there is any.
+Objects which are not reachable from HEAD, any ref, the index or any reflog and are not referenced by any other object
+are printed as dangling.
+
+Options:
+	--full			Rehash blobs as well as trees and commits. This is the default, use --full=false to only verify the header of blobs.
+	--connectivity-only	Only check that referenced objects exist and have the right type. Nothing is rehashed and blobs are not read.
+	--unreachable		Print every unreachable object instead of only the dangling ones.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		res, err := fsck.Check(fsck.Options{Full: fsckFull, ConnectivityOnly: fsckConnectivityOnly})
+		if err != nil {
+			return err
+		}
+
+		for _, p := range res.Problems {
+			fmt.Fprintln(os.Stderr, p)
+		}
+
+		if fsckUnreachable {
+			for _, o := range res.Unreachable {
+				fmt.Printf("unreachable %v %v\n", o.Kind, o.Hash)
+			}
+		} else {
+			for _, o := range res.Dangling {
+				fmt.Printf("dangling %v %v\n", o.Kind, o.Hash)
+			}
+		}
+
+		if len(res.Problems) != 0 {
+			return silentExit(cmd, 1)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	fsckCmd.Flags().BoolVar(&fsckFull, "full", true, "Rehash blobs as well as trees and commits.")
+	fsckCmd.Flags().BoolVar(&fsckConnectivityOnly, "connectivity-only", false, "Only check connectivity, without rehashing objects.")
+	fsckCmd.Flags().BoolVar(&fsckUnreachable, "unreachable", false, "Print all unreachable objects, not only the dangling ones.")
+	RootCmd.AddCommand(fsckCmd)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"armanVersionControl/config"
+	"armanVersionControl/gc"
+	"fmt"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var (
+	gcPrune  string
+	gcDryRun bool
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc [--prune=<expiry>] [--dry-run]",
+	Short: "Remove unreachable objects from the object database.",
+	Long: `Removes the objects which are not reachable from HEAD, any ref, the index or any reflog and are older than the expiry.
+
+Options:
+	--prune		How old unreachable objects should be to be removed, e.g. 2w, 14d, 36h, now or never.
+			Defaults to the gc.pruneExpire config, or 2w when it is not set.
+	--dry-run	Print the objects which would be removed without removing them.
+
+Note:
+	- Recently written objects are kept because a running command might be about to reference them.
+	- Nothing is removed when a reachable object is missing or corrupt, run fsck to find out why.
+	- All objects are loose objects in avc, so there is nothing to repack.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expiry := gcPrune
+		if expiry == "" {
+			v, ok, err := config.Get("gc.pruneExpire")
+			if err != nil {
+				return err
+			}
+
+			expiry = gc.DefaultPruneExpire
+			if ok {
+				expiry = v
+			}
+		}
+
+		before, err := gc.ParseExpire(expiry, time.Now())
+		if err != nil {
+			return err
+		}
+
+		pruned, err := gc.Prune(gc.Options{ExpireBefore: before, DryRun: gcDryRun})
+		if gcDryRun {
+			for _, h := range pruned {
+				fmt.Printf("would remove %v\n", h)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if !gcDryRun {
+			fmt.Printf("%v unreachable objects removed successfully.\n", len(pruned))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	gcCmd.Flags().StringVar(&gcPrune, "prune", "", "Remove unreachable objects older than this expiry.")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Print the objects which would be removed without removing them.")
+	RootCmd.AddCommand(gcCmd)
+}
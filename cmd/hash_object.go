@@ -3,29 +3,66 @@ package cmd
 import (
 	"armanVersionControl/storage"
 	"armanVersionControl/structures"
+	"bufio"
 	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 )
 
 var (
-	filePath string
-	content  string
-	write    bool
+	filePath   string
+	content    string
+	write      bool
+	objectType string
+	fromStdin  bool
+	stdinPaths bool
 )
 
 var hashObjectCmd = &cobra.Command{
-	Use:   "hash-object [-w | --write] { {--file-path | -f} | {--content | -c} }",
+	Use:   "hash-object [-w | --write] [-t | --type type] { {--file-path | -f} | {--content | -c} | --stdin | --stdin-paths }",
 	Short: "Computes the object ID value for a specified file path or provided content.",
 	Long: `Computes and reports the object ID value for a specified file path or provided content and optionally writes the resulting object into the object database.
 If the provided path is a directory path, will generate a tree for the directory and each directory and each file will be a blob in that tree.
 
+Options:
+	-t		The type of the object to create, either blob, tree or commit. The content is validated to be in the
+			format of that type, e.g. the output of 'cat-file --plain' of a tree is a valid tree content.
+			Defaults to blob, or tree when the file path is a directory.
+	--stdin		Read the content from the standard input.
+	--stdin-paths	Read file paths from the standard input, one per line, and print the object ID of each of them.
+
 Note:
+	- The printed object ID is always the ID the object gets in the object database, whether -w is provided or not.
 	- If a file or directory is added to object database more than once, the previous hash and object will be used.
 	- Files or directories that start with a '.' AKA the hidden files and directories are ignored.`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		sources := 0
+		for _, f := range []bool{filePath != "", content != "", fromStdin, stdinPaths} {
+			if f {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return errors.New("exactly one of --file-path, --content, --stdin and --stdin-paths should be provided")
+		}
+
+		if stdinPaths {
+			sc := bufio.NewScanner(os.Stdin)
+			for sc.Scan() {
+				s, err := computeHashOfPath(sc.Text())
+				if err != nil {
+					return err
+				}
+
+				fmt.Println(s)
+			}
+
+			return sc.Err()
+		}
+
 		s, err := computeHashAndWriteIfFlag()
 		if err != nil {
 			return err
@@ -40,6 +77,9 @@ func init() {
 	hashObjectCmd.Flags().StringVarP(&filePath, "file-path", "f", "", "Hash content of file located at the given path.")
 	hashObjectCmd.Flags().StringVarP(&content, "content", "c", "", "Hash the provided content from initCmd line input.")
 	hashObjectCmd.Flags().BoolVarP(&write, "write", "w", false, "When specified will actually store the resulting object into object database.")
+	hashObjectCmd.Flags().StringVarP(&objectType, "type", "t", "", "Type of the object to create, either blob, tree or commit.")
+	hashObjectCmd.Flags().BoolVar(&fromStdin, "stdin", false, "Hash the content read from the standard input.")
+	hashObjectCmd.Flags().BoolVar(&stdinPaths, "stdin-paths", false, "Hash the files whose paths are read from the standard input.")
 
 	RootCmd.AddCommand(hashObjectCmd)
 }
@@ -47,24 +87,39 @@ func init() {
 // TODO maybe move these functions to another go file?
 func computeHashAndWriteIfFlag() (string, error) {
 	if content != "" {
-		if write {
-			return structures.Blob{Content: []byte(content)}.StoreBlob()
+		return computeHashOfPayload([]byte(content))
+	}
+
+	if fromStdin {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
 		}
 
-		return storage.ComputeHash([]byte(content)), nil
+		return computeHashOfPayload(b)
 	}
 
-	if filePath == "" {
+	return computeHashOfPath(filePath)
+}
+
+// computeHashOfPath computes the object ID of the file or directory at fp
+// and stores it when write is true.
+func computeHashOfPath(fp string) (string, error) {
+	if fp == "" {
 		return "", errors.New("filePath can not be empty")
 	}
 
-	s, err := os.Stat(filePath)
+	s, err := os.Stat(fp)
 	if err != nil {
 		return "", err
 	}
 
 	if s.IsDir() {
-		t, err := computeTree(filePath)
+		if objectType != "" && objectType != "tree" {
+			return "", fmt.Errorf("'%v' is a directory, it can not be hashed as a %v", fp, objectType)
+		}
+
+		t, err := computeTree(fp)
 		if err != nil {
 			return "", err
 		}
@@ -73,28 +128,38 @@ func computeHashAndWriteIfFlag() (string, error) {
 			return t.StoreTree()
 		}
 
-		b, err := t.FileRepresent()
-		if err != nil {
-			return "", err
-		}
+		return t.ComputeHash()
+	}
 
-		return storage.ComputeHash(b), nil
+	b, err := computeBlob(fp)
+	if err != nil {
+		return "", err
 	}
 
-	if !s.Mode().IsRegular() {
-		return "", fmt.Errorf("expected a regular file but got %+v", s)
+	return computeHashOfPayload(b.Content)
+}
+
+// computeHashOfPayload computes the object ID of payload as an object of
+// objectType and stores it when write is true.
+func computeHashOfPayload(payload []byte) (string, error) {
+	kind := structures.ObjectBlob
+	if objectType != "" {
+		var err error
+		if kind, err = structures.ParseObjectKind(objectType); err != nil {
+			return "", err
+		}
 	}
 
-	b, err := computeBlob(filePath)
+	b, err := structures.Encode(kind, payload)
 	if err != nil {
 		return "", err
 	}
 
-	if write {
-		return b.StoreBlob()
+	if !write {
+		return storage.ComputeHash(b)
 	}
 
-	return storage.ComputeHash(b.FileRepresent()), err
+	return storage.Store(b)
 }
 
 func computeBlob(fp string) (structures.Blob, error) {
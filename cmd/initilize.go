@@ -1,18 +1,37 @@
 package cmd
 
 import (
+	"armanVersionControl/hashing"
+	"armanVersionControl/refs"
 	"armanVersionControl/storage"
 	"fmt"
 	"github.com/spf13/cobra"
 )
 
+var (
+	objectFormat string
+)
+
 var initCmd = &cobra.Command{
-	Use:   "init",
+	Use:   "init [--object-format=(sha1 | sha256)]",
 	Short: "Creates an empty avc repository.",
-	Long:  "Creates an empty Arman version control repository",
-	Args:  cobra.NoArgs,
+	Long: `Creates an empty Arman version control repository
+
+Options:
+	--object-format	The hash algorithm objects are identified with, either sha1 or sha256. Defaults to sha1.
+			It can not be changed after the repository is created.`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := storage.Init(); err != nil {
+		a, err := hashing.ParseAlgorithm(objectFormat)
+		if err != nil {
+			return err
+		}
+
+		if err := storage.Init(a); err != nil {
+			return err
+		}
+
+		if err := refs.WriteSymbolic(refs.HEAD, refs.DefaultBranch); err != nil {
 			return err
 		}
 
@@ -23,5 +42,6 @@ var initCmd = &cobra.Command{
 }
 
 func init() {
+	initCmd.Flags().StringVar(&objectFormat, "object-format", hashing.Default.Name(), "The hash algorithm of objects, either sha1 or sha256.")
 	RootCmd.AddCommand(initCmd)
 }
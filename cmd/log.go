@@ -1,30 +1,236 @@
 package cmd
 
 import (
-	"armanVersionControl/storage"
+	"armanVersionControl/diff"
+	"armanVersionControl/history"
+	"armanVersionControl/refs"
+	"armanVersionControl/structures"
+	"bufio"
+	"cmp"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// logDateLayout is the layout of dates in log, which is the one git uses.
+	logDateLayout = "Mon Jan 2 15:04:05 2006 -0700"
+)
+
+var (
+	logMaxCount   int
+	logOneLine    bool
+	logNameStatus bool
 )
 
 var logCmd = &cobra.Command{
-	Use:   "log",
-	Short: "Currently prints all objects stored in object database.",
-	Long:  "Currently prints all objects stored in object database.",
-	Args:  cobra.NoArgs,
+	Use:   "log [-n count] [--oneline] [--name-status] [revision...] [-- path...]",
+	Short: "Show the commit history.",
+	Long: `Shows the commits reachable from the revisions, newest first, with their author, date and message.
+
+Arguments:
+    revision		The commits to start from, HEAD by default. "^A" leaves out the commits reachable from A,
+			and "A..B" is the same as "^A B": the commits reachable from B but not from A.
+    path		Only show commits which changed these paths or anything under them.
+
+Options:
+	-n		Show at most this many commits.
+	--oneline	Show each commit in a single line with its abbreviated hash and the first line of its message.
+	--name-status	Show the names and the status of the files each commit changed, see diff-tree.
+	-M		Detect renamed files which are at least this similar, e.g. -M50%. Renames are detected with
+			50% by default, unless the diff.renames config is false.
+	-C		Like -M, but also detect files which are copies of changed files.
+	--no-renames	Show renamed files as a deleted and an added file.
+
+Note:
+	- Changed files are not shown for merge commits.
+	- With paths, a merge commit is only shown when it differs from all of its parents in these paths.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		all, err := storage.FetchAllObjectNames()
+		revs, paths, err := splitRevisionArgs(args, cmd.ArgsLenAtDash())
 		if err != nil {
 			return err
 		}
 
-		for _, o := range all {
-			fmt.Println(o)
+		include, exclude, err := logRange(revs)
+		if err != nil {
+			return err
 		}
 
-		return nil
+		renames, err := renameOptions()
+		if err != nil {
+			return err
+		}
+
+		w := bufio.NewWriter(os.Stdout)
+		shown := 0
+		err = history.WalkRange(include, exclude, func(c structures.Commit) error {
+			if logMaxCount >= 0 && shown >= logMaxCount {
+				return history.ErrStopWalk
+			}
+
+			changes, show, err := commitChanges(c, paths, renames)
+			if err != nil || !show {
+				return err
+			}
+
+			if shown > 0 && !logOneLine {
+				fmt.Fprintln(w)
+			}
+			shown++
+
+			writeLogEntry(w, c, changes)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return w.Flush()
 	},
 }
 
 func init() {
+	logCmd.Flags().IntVarP(&logMaxCount, "max-count", "n", -1, "Show at most this many commits.")
+	logCmd.Flags().BoolVar(&logOneLine, "oneline", false, "Show each commit in a single line.")
+	logCmd.Flags().BoolVar(&logNameStatus, "name-status", false, "Show the names and the status of changed files.")
+	addRenameFlags(logCmd)
 	RootCmd.AddCommand(logCmd)
 }
+
+// logRange resolves revs to the hashes of the commits log starts from and
+// the ones whose history is left out. Without revs, log starts from HEAD.
+func logRange(revs []string) (include []string, exclude []string, err error) {
+	if len(revs) == 0 {
+		revs = []string{refs.HEAD}
+	}
+
+	add := func(rev string, excluded bool) error {
+		h, err := resolveRevision(rev)
+		if err != nil {
+			if rev == refs.HEAD && errors.Is(err, refs.ErrRefNotFound) {
+				return errors.New("there are no commits yet")
+			}
+
+			return err
+		}
+
+		if excluded {
+			exclude = append(exclude, h)
+		} else {
+			include = append(include, h)
+		}
+
+		return nil
+	}
+
+	for _, rev := range revs {
+		if from, to, ok := strings.Cut(rev, ".."); ok {
+			if err = add(cmp.Or(from, refs.HEAD), true); err != nil {
+				return nil, nil, err
+			}
+			err = add(cmp.Or(to, refs.HEAD), false)
+		} else if r, ok := strings.CutPrefix(rev, "^"); ok {
+			err = add(r, true)
+		} else {
+			err = add(rev, false)
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return include, exclude, nil
+}
+
+// commitChanges returns the changes c made compared to its first parent, only
+// when they are shown, and whether c is shown at all. With paths, c is only
+// shown when it changed them compared to every parent.
+func commitChanges(c structures.Commit, paths []string, renames *diff.RenameOptions) ([]diff.Change, bool, error) {
+	if len(paths) == 0 && (!logNameStatus || len(c.ParentHashes) > 1) {
+		return nil, true, nil
+	}
+
+	t, err := c.FetchTree()
+	if err != nil {
+		return nil, false, err
+	}
+
+	parents := c.ParentHashes
+	if len(parents) == 0 {
+		parents = []string{""}
+	}
+
+	var first []diff.Change
+	for i, p := range parents {
+		var pt *structures.Tree
+		if p != "" {
+			tree, err := structures.FetchTreeish(p)
+			if err != nil {
+				return nil, false, err
+			}
+			pt = &tree
+		}
+
+		changes, err := diff.Trees(pt, &t, diff.TreeOptions{Recursive: true, Paths: paths})
+		if err != nil {
+			return nil, false, err
+		}
+		if len(changes) == 0 {
+			return nil, false, nil
+		}
+
+		if i == 0 {
+			first = changes
+		}
+	}
+
+	if !logNameStatus || len(c.ParentHashes) > 1 {
+		return nil, true, nil
+	}
+
+	if renames != nil {
+		if first, err = diff.DetectRenames(first, *renames, diff.LoadBlob, diff.LoadBlob); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return first, true, nil
+}
+
+// writeLogEntry writes c and the changes it made to w.
+func writeLogEntry(w io.Writer, c structures.Commit, changes []diff.Change) {
+	message := strings.TrimRight(c.Message, "\n")
+	if logOneLine {
+		subject, _, _ := strings.Cut(message, "\n")
+		fmt.Fprintf(w, "%v %v\n", c.Hash[:min(len(c.Hash), 7)], subject)
+	} else {
+		fmt.Fprintf(w, "commit %v\n", c.Hash)
+		if len(c.ParentHashes) > 1 {
+			var abbrevs []string
+			for _, p := range c.ParentHashes {
+				abbrevs = append(abbrevs, p[:min(len(p), 7)])
+			}
+			fmt.Fprintf(w, "Merge: %v\n", strings.Join(abbrevs, " "))
+		}
+		fmt.Fprintf(w, "Author: %v <%v>\n", c.Author, c.AuthorEmail)
+		fmt.Fprintf(w, "Date:   %v\n\n", c.AuthorDate.Format(logDateLayout))
+		for _, l := range strings.Split(message, "\n") {
+			fmt.Fprintf(w, "    %v\n", l)
+		}
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	if !logOneLine {
+		fmt.Fprintln(w)
+	}
+	for _, ch := range changes {
+		fmt.Fprintln(w, formatNameStatus(ch))
+	}
+}
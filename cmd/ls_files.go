@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"armanVersionControl/track"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lsFilesStage    bool
+	lsFilesDeleted  bool
+	lsFilesModified bool
+	lsFilesOthers   bool
+	lsFilesNulTerm  bool
+)
+
+var lsFilesCmd = &cobra.Command{
+	Use:   "ls-files [--stage] [--deleted] [--modified] [--others] [-z]",
+	Short: "List the files in the index and the working tree.",
+	Long: `Lists the files in the index, one per line. When --deleted, --modified or --others are provided, only those files are listed.
+
+Notes:
+	- --stage prints the mode, hash and stage of each index entry in the "<mode> SP <hash> SP <stage> TAB <name>" format.
+	  The stage is 0, or 1, 2 and 3 for the base, ours and theirs versions of files with conflicts.
+	- --modified includes deleted files as well.
+	- --others lists the files in the working tree which are not in the index.
+	- -z terminates each line with NUL instead of a new line, so names with new lines can be parsed by scripts.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		index, err := track.FetchIndex()
+		if err != nil && !errors.Is(err, track.ErrIndexNotFound) {
+			return err
+		}
+
+		term := "\n"
+		if lsFilesNulTerm {
+			term = "\x00"
+		}
+
+		cached := !lsFilesDeleted && !lsFilesModified && !lsFilesOthers
+		for _, ie := range index.Entries {
+			show := cached
+			if !show && lsFilesDeleted {
+				if show, err = ie.IsDeleted(); err != nil {
+					return err
+				}
+			}
+			if !show && lsFilesModified {
+				if show, err = ie.IsModified(); err != nil {
+					return err
+				}
+			}
+			if !show {
+				continue
+			}
+
+			if lsFilesStage {
+				fmt.Printf("%v %v %d\t%v%v", ie.Mode, ie.EntryHash, ie.Stage, ie.Name, term)
+				continue
+			}
+
+			fmt.Print(ie.Name, term)
+		}
+
+		if !lsFilesOthers {
+			return nil
+		}
+
+		others, err := track.Untracked(index)
+		if err != nil {
+			return err
+		}
+
+		for _, o := range others {
+			fmt.Print(o, term)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	lsFilesCmd.Flags().BoolVarP(&lsFilesStage, "stage", "s", false, "Show mode, hash and stage of each entry.")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesDeleted, "deleted", "d", false, "Show deleted files.")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesModified, "modified", "m", false, "Show modified files.")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesOthers, "others", "o", false, "Show files which are not in the index.")
+	lsFilesCmd.Flags().BoolVarP(&lsFilesNulTerm, "zero", "z", false, "Terminate lines with NUL instead of new line.")
+	RootCmd.AddCommand(lsFilesCmd)
+}
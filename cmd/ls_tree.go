@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"armanVersionControl/structures"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io/fs"
+	"strings"
+)
+
+var (
+	lsTreeRecursive bool
+	lsTreeShowTrees bool
+	lsTreeNameOnly  bool
+)
+
+var lsTreeCmd = &cobra.Command{
+	Use:   "ls-tree [-r] [-t] [--name-only] tree-ish [path]",
+	Short: "List the contents of a tree object.",
+	Long: `Lists the entries of a tree, one per line in the following format, which mktree accepts as input:
+
+	<mode> SP <type> SP <hash> TAB <name>
+
+Arguments:
+    tree-ish		A tree or a commit, by hash, prefix of one or revision like main or HEAD~1. In case of a commit
+			its tree is listed.
+    path		Only list this path. When path ends with '/', the contents of that directory are listed instead.
+
+Notes:
+	- Without -r, subdirectories are listed as tree entries and are not recursed into.
+	- With -r, only files are listed unless -t is provided too.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := fetchRevisionTree(args[0])
+		if err != nil {
+			return err
+		}
+
+		p := ""
+		if len(args) == 2 {
+			p = args[1]
+		}
+		listChildren := p == "" || strings.HasSuffix(p, "/")
+		p = strings.Trim(p, "/")
+
+		print := func(name string, te *structures.TreeEntry) {
+			if lsTreeNameOnly {
+				fmt.Println(name)
+				return
+			}
+
+			fmt.Println(formatTreeEntry(name, te))
+		}
+
+		return t.Walk(func(name string, te *structures.TreeEntry) error {
+			isTree := te.Kind == structures.KindTree
+
+			// Directories on the way to p are only recursed into.
+			if p != "" && strings.HasPrefix(p, name+"/") {
+				return nil
+			}
+
+			inside := p == "" || name == p || strings.HasPrefix(name, p+"/")
+			if !inside {
+				if isTree {
+					return fs.SkipDir
+				}
+
+				return nil
+			}
+
+			if lsTreeRecursive {
+				if !isTree || lsTreeShowTrees {
+					print(name, te)
+				}
+
+				return nil
+			}
+
+			if name == p && isTree && listChildren {
+				return nil
+			}
+
+			print(name, te)
+			if isTree {
+				return fs.SkipDir
+			}
+
+			return nil
+		})
+	},
+}
+
+func init() {
+	lsTreeCmd.Flags().BoolVarP(&lsTreeRecursive, "recursive", "r", false, "Recurse into subdirectories.")
+	lsTreeCmd.Flags().BoolVarP(&lsTreeShowTrees, "trees", "t", false, "Show tree entries even when recursing into them.")
+	lsTreeCmd.Flags().BoolVar(&lsTreeNameOnly, "name-only", false, "Only list the names of entries.")
+	RootCmd.AddCommand(lsTreeCmd)
+}
+
+// formatTreeEntry formats te named name as a "<mode> SP <type> SP <hash> TAB <name>" line.
+func formatTreeEntry(name string, te *structures.TreeEntry) string {
+	return fmt.Sprintf("%v %v %v\t%v", te.Mode, entryTypeName(te.Kind), te.EntryHash, name)
+}
+
+// entryTypeName returns the object type name of kind used in tree listings.
+func entryTypeName(kind structures.EntryKind) string {
+	for n, k := range entryTypes {
+		if k == kind {
+			return n
+		}
+	}
+
+	panic("A new unexpected kind detected.")
+}
@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"armanVersionControl/config"
+	"armanVersionControl/diff"
+	"armanVersionControl/history"
+	"armanVersionControl/refs"
+	"armanVersionControl/structures"
+	"armanVersionControl/track"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	mergeNoFF    bool
+	mergeFFOnly  bool
+	mergeMessage string
+	mergeAbort   bool
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge [--no-ff | --ff-only] [-m message] branch | --abort",
+	Short: "Merge the history of a branch into HEAD.",
+	Long: `Joins the history of the branch, or any commit, with HEAD. When HEAD is an ancestor of the branch, HEAD is
+fast-forwarded to it. Otherwise the changes both made since their merge base, the newest commit both have in
+their history, are merged into a commit with HEAD and the branch as parents. The index and the working tree are
+updated to the result.
+
+Arguments:
+    branch		The branch or commit to merge.
+
+Options:
+	--no-ff		Create a merge commit even when HEAD could be fast-forwarded.
+	--ff-only	Only fast-forward, fail when a merge commit would be needed.
+	-m		The message of the merge commit, defaults to "Merge branch '<branch>'".
+	--abort		Give up the merge in progress, the index and the files it changed are reset to HEAD.
+
+Note:
+	- Files only one side changed are taken from it. Text files both changed are merged line by line, and lines both
+	  changed differently are conflicts. They are written to the working tree like this:
+		<<<<<<< HEAD
+		the lines of HEAD
+		||||||| merge base
+		the lines of the merge base
+		=======
+		the lines of the branch
+		>>>>>>> branch
+	  Binary files and files one side deleted and the other changed are conflicts as well, the version of HEAD, or
+	  of the branch when HEAD deleted it, is written to the working tree then.
+	- With conflicts, nothing is committed. The index has the base, ours and theirs versions of each file with
+	  conflicts, see status and checkout. Fix the files, add them, and commit the merge with commit, or give it up
+	  with merge --abort.
+	- The index should have no changes which are not committed, and the files the merge changes should not have changes
+	  in the working tree.
+	- Histories without a common commit are not merged. When there are several merge bases, the newest one is used.`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if mergeAbort && len(args) > 0 {
+			return errors.New("--abort does not take a branch")
+		}
+		if !mergeAbort && len(args) == 0 {
+			return errors.New("the branch to merge is required")
+		}
+		if mergeNoFF && mergeFFOnly {
+			return errors.New("--no-ff and --ff-only can not be used together")
+		}
+
+		// The arguments are valid, so the usage does not help with any
+		// error from here on, like conflicts.
+		cmd.SilenceUsage = true
+
+		if mergeAbort {
+			return abortMerge()
+		}
+
+		if _, _, ok, err := refs.ReadMergeHead(); err != nil || ok {
+			if err != nil {
+				return err
+			}
+
+			return errors.New("a merge is in progress, commit it or give it up with merge --abort")
+		}
+
+		theirs, err := resolveRevision(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err = history.FetchCommit(theirs); err != nil {
+			return err
+		}
+
+		if err = checkIndexCommitted(); err != nil {
+			return err
+		}
+
+		head, err := refs.Resolve(refs.HEAD)
+		if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+			return err
+		}
+		if head == "" {
+			return fastForward(args[0], head, theirs)
+		}
+
+		bases, err := history.MergeBases(head, theirs)
+		if err != nil {
+			return err
+		}
+		if len(bases) == 0 {
+			return fmt.Errorf("HEAD and %v have no common history, refusing to merge them", args[0])
+		}
+		if len(bases) > 1 {
+			fmt.Fprintf(os.Stderr, "warning: HEAD and %v have %v merge bases, only %v is used, so changes both sides merged differently before may conflict\n",
+				args[0], len(bases), bases[0])
+		}
+
+		if slices.Contains(bases, theirs) {
+			fmt.Println("Already up to date.")
+			return nil
+		}
+		if slices.Contains(bases, head) && !mergeNoFF {
+			return fastForward(args[0], head, theirs)
+		}
+		if mergeFFOnly {
+			return errors.New("HEAD can not be fast-forwarded, a merge commit is needed")
+		}
+
+		message := mergeMessage
+		if message == "" {
+			message = mergeCommitMessage(args[0])
+		}
+		if !strings.HasSuffix(message, "\n") {
+			message += "\n"
+		}
+
+		return threeWayMergeCommit(args[0], head, theirs, bases[0], message)
+	},
+}
+
+func init() {
+	mergeCmd.Flags().BoolVar(&mergeNoFF, "no-ff", false, "Create a merge commit even when HEAD could be fast-forwarded.")
+	mergeCmd.Flags().BoolVar(&mergeFFOnly, "ff-only", false, "Only fast-forward.")
+	mergeCmd.Flags().StringVarP(&mergeMessage, "message", "m", "", "The message of the merge commit.")
+	mergeCmd.Flags().BoolVar(&mergeAbort, "abort", false, "Give up the merge in progress.")
+	RootCmd.AddCommand(mergeCmd)
+}
+
+// mergeCommitMessage returns the default message of the commit which merges
+// rev.
+func mergeCommitMessage(rev string) string {
+	name, err := refs.Expand(rev)
+	if err == nil {
+		if branch, ok := strings.CutPrefix(name, "refs/heads/"); ok {
+			return fmt.Sprintf("Merge branch '%v'", branch)
+		}
+	}
+
+	return fmt.Sprintf("Merge commit '%v'", rev)
+}
+
+// abortMerge resets the index and the working tree to HEAD and removes
+// MERGE_HEAD.
+func abortMerge() error {
+	if _, _, ok, err := refs.ReadMergeHead(); err != nil || !ok {
+		if err != nil {
+			return err
+		}
+
+		return errors.New("there is no merge to abort")
+	}
+
+	head, err := refs.Resolve(refs.HEAD)
+	if err != nil {
+		return err
+	}
+
+	t, err := fetchCommitTree(head)
+	if err != nil {
+		return err
+	}
+
+	if err = track.AbortMerge(t); err != nil {
+		return err
+	}
+
+	return refs.RemoveMergeHead()
+}
+
+// fetchCommitTree returns the Tree of the commit with hash, or nil when hash
+// is empty.
+func fetchCommitTree(hash string) (*structures.Tree, error) {
+	if hash == "" {
+		return nil, nil
+	}
+
+	t, err := structures.FetchTreeish(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// fastForward moves HEAD from head, which may be empty, to its descendant
+// theirs and checks out its tree.
+func fastForward(rev string, head string, theirs string) error {
+	ours, err := fetchCommitTree(head)
+	if err != nil {
+		return err
+	}
+
+	t, err := fetchCommitTree(theirs)
+	if err != nil {
+		return err
+	}
+
+	// Merging theirs into itself takes every file from it.
+	m, err := track.MergeTrees(ours, ours, t, diff.MergeLabels{})
+	if err != nil {
+		return err
+	}
+
+	if err = track.CheckoutMerge(m); err != nil {
+		return err
+	}
+
+	if head != "" {
+		fmt.Printf("Updating %v..%v\n", head, theirs)
+	}
+	fmt.Println("Fast-forward")
+
+	return refs.Update(refs.HEAD, theirs, head, fmt.Sprintf("merge %v: Fast-forward", rev))
+}
+
+// threeWayMergeCommit merges theirs into head using base as the merge base and
+// commits the result with message. When there are conflicts, they are written
+// to the working tree and MERGE_HEAD is recorded instead.
+func threeWayMergeCommit(rev string, head string, theirs string, base string, message string) error {
+	// The identities are checked first, so nothing is changed without them.
+	author, err := config.Author()
+	if err != nil {
+		return err
+	}
+
+	commiter, err := config.Commiter()
+	if err != nil {
+		return err
+	}
+
+	trees := make([]*structures.Tree, 3)
+	for i, h := range []string{base, head, theirs} {
+		if trees[i], err = fetchCommitTree(h); err != nil {
+			return err
+		}
+	}
+
+	labels := diff.MergeLabels{Ours: refs.HEAD, Base: "merge base", Theirs: rev}
+	m, err := track.MergeTrees(trees[0], trees[1], trees[2], labels)
+	if err != nil {
+		return err
+	}
+
+	if err = track.CheckoutMerge(m); err != nil {
+		return err
+	}
+
+	if len(m.Conflicts) > 0 {
+		for _, c := range m.Conflicts {
+			fmt.Printf("CONFLICT (%v): Merge conflict in %v\n", c.Reason, c.Path)
+		}
+
+		if err = refs.WriteMergeHead(theirs, message); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("%w, fix them, add the files and commit the result", track.ErrMergeConflicts)
+	}
+
+	tree, err := track.WriteTree()
+	if err != nil {
+		return err
+	}
+
+	c := structures.New(tree, []string{head, theirs}, author.Name, author.Email, author.Date,
+		commiter.Name, commiter.Email, commiter.Date, message)
+	h, err := c.StoreCommit()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Merge made by the three-way strategy.")
+	return refs.Update(refs.HEAD, h, head, fmt.Sprintf("merge %v: Merge made by the three-way strategy.", rev))
+}
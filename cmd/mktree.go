@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// entryTypes maps the object type names used in tree listings to EntryKind.
+var entryTypes = map[string]structures.EntryKind{
+	"tree": structures.KindTree,
+	"blob": structures.KindBlob,
+}
+
+var allowMissing bool
+
+var mktreeCmd = &cobra.Command{
+	Use:   "mktree [--missing]",
+	Short: "Build a tree object from a text listing.",
+	Long: `Reads a tree listing from the standard input, stores it as a tree object in the object database and prints its hash.
+Each line of the listing describes one entry in the following format, which is the same format ls-tree prints:
+
+	<mode> SP <type> SP <hash> TAB <name>
+
+Notes:
+	- Entries are sorted in the canonical order, so the order of lines does not change the resulting hash.
+	- Names can not be empty, "." or "..", and can not contain '/' or NUL. Duplicate names are rejected.
+	- Every hash must point to an existing object of the given type unless --missing is provided.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := readTreeListing()
+		if err != nil {
+			return err
+		}
+
+		b, err := t.FileRepresent()
+		if err != nil {
+			return err
+		}
+
+		h, err := storage.Store(b)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(h)
+		return nil
+	},
+}
+
+func init() {
+	mktreeCmd.Flags().BoolVar(&allowMissing, "missing", false, "Allow hashes of objects that do not exist in the object database.")
+	RootCmd.AddCommand(mktreeCmd)
+}
+
+// readTreeListing parses the tree listing from standard input.
+func readTreeListing() (structures.Tree, error) {
+	t := structures.Tree{}
+
+	sc := bufio.NewScanner(os.Stdin)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+
+		te, err := parseTreeListingLine(line)
+		if err != nil {
+			return structures.Tree{}, fmt.Errorf("line %v: %w", n, err)
+		}
+
+		t.Entries = append(t.Entries, te)
+	}
+
+	return t, sc.Err()
+}
+
+// parseTreeListingLine parses a single "<mode> SP <type> SP <hash> TAB <name>" line.
+func parseTreeListingLine(line string) (*structures.TreeEntry, error) {
+	meta, name, ok := strings.Cut(line, "\t")
+	if !ok {
+		return nil, errors.New("expected a tab before the entry name")
+	}
+
+	fields := strings.Split(meta, " ")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("expected '<mode> <type> <hash>' but got %q", meta)
+	}
+
+	m, err := strconv.ParseUint(fields[0], 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mode %q", fields[0])
+	}
+	mode := structures.EntryMode(m)
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("unsupported mode %v", mode)
+	}
+
+	kind, ok := entryTypes[fields[1]]
+	if !ok || kind != mode.Kind() {
+		return nil, fmt.Errorf("type %q does not match mode %v", fields[1], mode)
+	}
+
+	if err = structures.ValidateEntryName(name); err != nil {
+		return nil, err
+	}
+
+	hash := fields[2]
+	if allowMissing {
+		// Missing objects can not be looked up by a prefix of their hash.
+		if err = storage.ValidateHash(hash, false); err != nil {
+			return nil, err
+		}
+	} else {
+		o, err := storage.FetchByHash(hash)
+		if err != nil {
+			return nil, fmt.Errorf("object %v: %w", hash, err)
+		}
+
+		if (kind == structures.KindTree && !structures.IsTreeB(o.Content)) ||
+			(kind == structures.KindBlob && !structures.IsBlobB(o.Content)) {
+			return nil, fmt.Errorf("object %v is not a %v", hash, fields[1])
+		}
+
+		hash = o.Hash
+	}
+
+	return &structures.TreeEntry{Kind: kind, Mode: mode, EntryHash: hash, Name: name}, nil
+}
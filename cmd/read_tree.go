@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"armanVersionControl/track"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	readTreePrefix string
+	readTreeMerge  bool
+)
+
+var readTreeCmd = &cobra.Command{
+	Use:   "read-tree tree-ish [-m | --merge] [--prefix dir]",
+	Short: "Read a tree into the index.",
+	Long: `Reads the tree into the index. The working tree is not changed, use checkout-index to write the index to the working tree.
+
+Arguments:
+    tree-ish		A tree or a commit, by hash, prefix of one or revision like main or HEAD~1. In case of a commit
+			its tree is read.
+
+Notes:
+	- By default the index is replaced with the content of the tree.
+	- With --merge, entries of the tree are added to the index, replacing entries with the same path and keeping the rest.
+	- With --prefix, the tree is read as the content of the dir directory and the rest of the index is kept.
+	  The index must not have any entry under dir already.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := fetchRevisionTree(args[0])
+		if err != nil {
+			return err
+		}
+
+		if err = track.ReadTree(t, readTreePrefix, readTreeMerge); err != nil {
+			return err
+		}
+
+		fmt.Printf("%v read into index successfully.\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	readTreeCmd.Flags().StringVar(&readTreePrefix, "prefix", "", "Read the tree into the index under this directory.")
+	readTreeCmd.Flags().BoolVarP(&readTreeMerge, "merge", "m", false, "Merge the tree into the index instead of replacing it.")
+	RootCmd.AddCommand(readTreeCmd)
+}
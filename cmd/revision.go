@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"armanVersionControl/refs"
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	errUnknownRevision = errors.New("unknown revision")
+)
+
+// resolveRevision returns the hash of the object rev names. rev is either a
+// ref, which can be short like main for refs/heads/main, or a hash or a prefix
+// of one. It can be followed by any number of ^<n>, which selects the n-th
+// parent, and ~<n>, which follows the first parent n times. n defaults to 1.
+func resolveRevision(rev string) (string, error) {
+	base, suffix := rev, ""
+	if i := strings.IndexAny(rev, "^~"); i >= 0 {
+		base, suffix = rev[:i], rev[i:]
+	}
+
+	hash, err := resolveRevisionBase(base)
+	if err != nil {
+		return "", err
+	}
+
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+
+		digits := len(suffix) - len(strings.TrimLeft(suffix, "0123456789"))
+		n := 1
+		if digits > 0 {
+			if n, err = strconv.Atoi(suffix[:digits]); err != nil {
+				return "", fmt.Errorf("%w: %v", errUnknownRevision, rev)
+			}
+			suffix = suffix[digits:]
+		}
+
+		if op == '^' {
+			if n == 0 {
+				continue
+			}
+
+			if hash, err = nthParent(hash, n, rev); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		for range n {
+			if hash, err = nthParent(hash, 1, rev); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hash, nil
+}
+
+// resolveRevisionBase resolves rev without any ^ or ~ suffix. Refs take
+// precedence over hashes.
+func resolveRevisionBase(rev string) (string, error) {
+	if rev == "" {
+		return "", fmt.Errorf("%w: %q", errUnknownRevision, rev)
+	}
+
+	name, err := refs.Expand(rev)
+	if err == nil {
+		return refs.Resolve(name)
+	}
+	if !errors.Is(err, refs.ErrRefNotFound) {
+		return "", err
+	}
+
+	hash, _, err := structures.FetchInfo(rev)
+	if errors.Is(err, storage.ErrObjectNotFound) || errors.Is(err, storage.ErrInvalidHash) || errors.Is(err, storage.ErrHashIsShort) {
+		return "", fmt.Errorf("%w: %v", errUnknownRevision, rev)
+	}
+
+	return hash, err
+}
+
+// nthParent returns the hash of the n-th parent of the commit with hash, rev
+// is the revision being resolved and is only used in errors.
+func nthParent(hash string, n int, rev string) (string, error) {
+	o, err := fetchObjectOfType(hash, structures.IsCommitB, "commit")
+	if err != nil {
+		return "", err
+	}
+
+	c, err := structures.NewCommitFromObject(o)
+	if err != nil {
+		return "", err
+	}
+
+	if n > len(c.ParentHashes) {
+		return "", fmt.Errorf("%w: %v, commit %v has %v parents", errUnknownRevision, rev, hash, len(c.ParentHashes))
+	}
+
+	return c.ParentHashes[n-1], nil
+}
+
+// fetchRevisionTree returns the Tree of the tree or commit rev names.
+func fetchRevisionTree(rev string) (structures.Tree, error) {
+	hash, err := resolveRevision(rev)
+	if err != nil {
+		return structures.Tree{}, err
+	}
+
+	return structures.FetchTreeish(hash)
+}
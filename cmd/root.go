@@ -1,11 +1,31 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
 	"os"
 )
 
+// exitCodeError makes Execute exit with code without printing anything,
+// for commands whose result is their exit code.
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %v", e.code)
+}
+
+// silentExit returns an exitCodeError for cmd and stops cobra from printing
+// the error and the usage of cmd.
+func silentExit(cmd *cobra.Command, code int) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+
+	return &exitCodeError{code: code}
+}
+
 var RootCmd = &cobra.Command{
 	Use:     "avc",
 	Aliases: []string{"kvc"},
@@ -20,6 +40,11 @@ var RootCmd = &cobra.Command{
 
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
+		var ece *exitCodeError
+		if errors.As(err, &ece) {
+			os.Exit(ece.code)
+		}
+
 		fmt.Fprintf(os.Stderr, "An error occured: '%s'\n", err)
 		os.Exit(1)
 	}
@@ -1,28 +1,235 @@
 package cmd
 
 import (
+	"armanVersionControl/diff"
+	"armanVersionControl/refs"
 	"armanVersionControl/track"
+	"bufio"
+	"errors"
+	"fmt"
 	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	statusShort bool
 )
 
-// TODO update Short and Long.
-// TODO check how git status shows doc and show comment like that
-// TODO Add Status in track to give slice of tracked and untracked files in the repo
-// TODO add comment for which files has been updated or created new?
 var statusCmd = &cobra.Command{
-	Use:   "status",
-	Short: "Will add the provided path to the index.",
-	Long: `This command updates the current index with the content found in the provided path, to prepare and stage content for the next commit.
-The index holds a snapshot of the current content of the working tree.`,
+	Use:   "status [-s]",
+	Short: "Show the state of the working tree and the index.",
+	Long: `Shows the current branch and three groups of files:
+
+	Changes to be committed		Files which differ between the index and HEAD, the next commit records these.
+	Unmerged paths			Files with conflicts of a merge, which have to be resolved and added.
+	Changes not staged for commit	Tracked files which differ between the working tree and the index.
+	Untracked files			Files in the working tree which are not in the index.
+
+Options:
+	-s	Show one line per file in the format "XY <path>", where X is the status of the file in the index,
+		Y its status in the working tree and "??" marks untracked files. See diff-tree for the letters.
+		Unmerged files are marked with which side added (A), deleted (D) or changed (U) them, e.g. "UU" when
+		both changed them and "DU" when HEAD deleted them.
+	-M	Detect renamed files which are at least this similar, e.g. -M50%. Renames are detected with
+		50% by default, unless the diff.renames config is false.
+	-C	Like -M, but also detect files which are copies of changed files.
+	--no-renames	Show renamed files as a deleted and an added file.
+
+Note:
+	- Renames are only detected among the changes to be committed. A file moved in the working tree
+	  is a deleted file and an untracked file until both are added to the index.`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := track.Add(name); err != nil {
+		renames, err := renameOptions()
+		if err != nil {
+			return err
+		}
+
+		s, err := track.FetchStatus(renames)
+		if err != nil {
+			return err
+		}
+
+		w := bufio.NewWriter(os.Stdout)
+		if statusShort {
+			writeShortStatus(w, s)
+		} else if err = writeLongStatus(w, s); err != nil {
 			return err
 		}
-		return nil
+
+		return w.Flush()
 	},
 }
 
 func init() {
+	statusCmd.Flags().BoolVarP(&statusShort, "short", "s", false, "Show one line per file.")
+	addRenameFlags(statusCmd)
 	RootCmd.AddCommand(statusCmd)
 }
+
+// statusLabels are the labels of changes in the long format of status.
+var statusLabels = map[diff.ChangeKind]string{
+	diff.ChangeAdded:       "new file:",
+	diff.ChangeDeleted:     "deleted:",
+	diff.ChangeModified:    "modified:",
+	diff.ChangeTypeChanged: "typechange:",
+	diff.ChangeRenamed:     "renamed:",
+	diff.ChangeCopied:      "copied:",
+}
+
+// unmergedStatus returns the label of c in the long format of status and its
+// letters in the short format.
+func unmergedStatus(c track.MergeConflict) (string, string) {
+	switch {
+	case c.Ours != nil && c.Theirs != nil && c.Base != nil:
+		return "both modified:", "UU"
+	case c.Ours != nil && c.Theirs != nil:
+		return "both added:", "AA"
+	case c.Theirs != nil && c.Base != nil:
+		return "deleted by us:", "DU"
+	case c.Ours != nil && c.Base != nil:
+		return "deleted by them:", "UD"
+	case c.Ours != nil:
+		return "added by us:", "AU"
+	case c.Theirs != nil:
+		return "added by them:", "UA"
+	default:
+		return "both deleted:", "DD"
+	}
+}
+
+// writeLongStatus writes s to w in the long format of status.
+func writeLongStatus(w io.Writer, s track.Status) error {
+	target, symbolic, err := refs.ReadSymbolic(refs.HEAD)
+	if err != nil {
+		return err
+	}
+
+	head, err := refs.Resolve(refs.HEAD)
+	if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+		return err
+	}
+
+	if symbolic {
+		fmt.Fprintf(w, "On branch %v\n", strings.TrimPrefix(target, "refs/heads/"))
+	} else {
+		fmt.Fprintf(w, "HEAD detached at %v\n", head[:min(len(head), 7)])
+	}
+	if head == "" {
+		fmt.Fprint(w, "\nNo commits yet\n")
+	}
+
+	_, _, merging, err := refs.ReadMergeHead()
+	if err != nil {
+		return err
+	}
+	if len(s.Unmerged) > 0 {
+		fmt.Fprint(w, "\nYou have unmerged paths, fix the conflicts, add the files and commit the result.\n")
+	} else if merging {
+		fmt.Fprint(w, "\nAll conflicts fixed but you are still merging, commit the result.\n")
+	}
+
+	writeGroup := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+
+		fmt.Fprintf(w, "\n%v:\n", title)
+		for _, l := range lines {
+			fmt.Fprintf(w, "\t%v\n", l)
+		}
+	}
+
+	formatChanges := func(changes []diff.Change) []string {
+		var lines []string
+		for _, c := range changes {
+			name := c.Path
+			if c.OldPath != "" {
+				name = fmt.Sprintf("%v -> %v", c.OldPath, c.Path)
+			}
+
+			lines = append(lines, fmt.Sprintf("%-12v%v", statusLabels[c.Kind], name))
+		}
+
+		return lines
+	}
+
+	var unmerged []string
+	for _, c := range s.Unmerged {
+		label, _ := unmergedStatus(c)
+		unmerged = append(unmerged, fmt.Sprintf("%-17v%v", label, c.Path))
+	}
+
+	writeGroup("Changes to be committed", formatChanges(s.Staged))
+	writeGroup("Unmerged paths", unmerged)
+	writeGroup("Changes not staged for commit", formatChanges(s.Unstaged))
+	writeGroup("Untracked files", s.Untracked)
+	fmt.Fprintln(w)
+
+	switch {
+	case len(s.Staged) > 0:
+	case len(s.Unstaged) > 0 || len(s.Unmerged) > 0:
+		fmt.Fprintln(w, "no changes added to commit")
+	case len(s.Untracked) > 0:
+		fmt.Fprintln(w, "nothing added to commit but untracked files present")
+	default:
+		fmt.Fprintln(w, "nothing to commit, working tree clean")
+	}
+
+	return nil
+}
+
+// writeShortStatus writes s to w in the short format of status, sorted by path.
+func writeShortStatus(w io.Writer, s track.Status) {
+	type entry struct {
+		staged   string
+		unstaged string
+		oldPath  string
+	}
+
+	entries := make(map[string]*entry)
+	get := func(path string) *entry {
+		e, ok := entries[path]
+		if !ok {
+			e = &entry{staged: " ", unstaged: " "}
+			entries[path] = e
+		}
+
+		return e
+	}
+
+	for _, c := range s.Staged {
+		e := get(c.Path)
+		e.staged, e.oldPath = c.Kind.String(), c.OldPath
+	}
+	for _, c := range s.Unstaged {
+		get(c.Path).unstaged = c.Kind.String()
+	}
+	for _, c := range s.Unmerged {
+		_, letters := unmergedStatus(c)
+		e := get(c.Path)
+		e.staged, e.unstaged = letters[:1], letters[1:]
+	}
+	for _, name := range s.Untracked {
+		e := get(name)
+		e.staged, e.unstaged = "?", "?"
+	}
+
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+
+	for _, p := range paths {
+		e := entries[p]
+		if e.oldPath != "" {
+			p = fmt.Sprintf("%v -> %v", e.oldPath, p)
+		}
+
+		fmt.Fprintf(w, "%v%v %v\n", e.staged, e.unstaged, p)
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"armanVersionControl/refs"
+	"armanVersionControl/structures"
+	"errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	updateRefMessage string
+	updateRefDelete  bool
+)
+
+var updateRefCmd = &cobra.Command{
+	Use:   "update-ref [-m message] (ref hash [old-hash] | -d ref)",
+	Short: "Update the object a ref points to.",
+	Long: `Makes ref point to the object with hash and records the change in the reflog of ref.
+When ref is a symbolic ref such as HEAD, the ref it points to is updated.
+
+Arguments:
+	ref		The full name of the ref, e.g. HEAD or refs/heads/main.
+	hash		The hash of the object, a prefix of it is accepted as well.
+	old-hash	When provided, ref is only updated if it currently points to old-hash.
+
+Options:
+	-m	The message recorded in the reflog.
+	-d	Delete ref and its reflog.`,
+	Args: cobra.RangeArgs(1, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if updateRefDelete {
+			if len(args) != 1 {
+				return errors.New("only the ref should be provided with -d")
+			}
+
+			return refs.Delete(args[0])
+		}
+
+		if len(args) < 2 {
+			return errors.New("ref and hash are required")
+		}
+
+		hash, _, err := structures.FetchInfo(args[1])
+		if err != nil {
+			return err
+		}
+
+		oldHash := ""
+		if len(args) == 3 {
+			oldHash = args[2]
+		}
+
+		return refs.Update(args[0], hash, oldHash, updateRefMessage)
+	},
+}
+
+func init() {
+	updateRefCmd.Flags().StringVarP(&updateRefMessage, "message", "m", "", "The message recorded in the reflog.")
+	updateRefCmd.Flags().BoolVarP(&updateRefDelete, "delete", "d", false, "Delete the ref.")
+	RootCmd.AddCommand(updateRefCmd)
+}
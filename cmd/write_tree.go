@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"armanVersionControl/track"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var writeTreeCmd = &cobra.Command{
+	Use:   "write-tree",
+	Short: "Create a tree object from the current index.",
+	Long: `Creates tree objects from the current index, one for each directory, stores them in the object database and prints the hash of the root tree.
+
+Note:
+	- The index must not be empty.
+	- Writing the same index twice produces the same hash and reuses the stored objects.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		h, err := track.WriteTree()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(h)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(writeTreeCmd)
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"armanVersionControl/storage"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+var (
+	configFileName             = path.Join(storage.MainDir, "config")
+	filePerm       os.FileMode = 0770
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid config key, it should look like section.name")
+)
+
+// entry represents a single key and value in the config file.
+type entry struct {
+	key   string
+	value string
+}
+
+// Get returns the value of key from the config file of the avc repository.
+// ok is false when key is not set.
+func Get(key string) (value string, ok bool, err error) {
+	entries, err := read()
+	if err != nil {
+		return "", false, err
+	}
+
+	i := slices.IndexFunc(entries, func(e entry) bool {
+		return e.key == key
+	})
+	if i < 0 {
+		return "", false, nil
+	}
+
+	return entries[i].value, true, nil
+}
+
+// Set stores value for key in the config file of the avc repository,
+// replacing the previous value if there is any.
+func Set(key string, value string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	entries, err := read()
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(entries, func(e entry) bool {
+		return e.key == key
+	})
+	if i < 0 {
+		entries = append(entries, entry{key: key, value: value})
+	} else {
+		entries[i].value = value
+	}
+
+	return write(entries)
+}
+
+// validateKey checks whether key has a section and a name, e.g. user.name.
+func validateKey(key string) error {
+	section, name, ok := strings.Cut(key, ".")
+	if !ok || section == "" || name == "" || strings.ContainsAny(key, " =\t\n") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	return nil
+}
+
+// read parses the config file. Each line of the file is a "key = value" pair,
+// empty lines and lines starting with '#' are ignored.
+func read() ([]entry, error) {
+	ok, err := storage.ExistsMainDir()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, storage.ErrRepoNotInitialized
+	}
+
+	rf, err := os.ReadFile(configFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var entries []entry
+	sc := bufio.NewScanner(bytes.NewReader(rf))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid config file, line %v should be a 'key = value' pair", n)
+		}
+
+		entries = append(entries, entry{key: strings.TrimSpace(k), value: strings.TrimSpace(v)})
+	}
+
+	return entries, sc.Err()
+}
+
+// write persists entries to the config file.
+func write(entries []entry) error {
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(fmt.Sprintf("%v = %v\n", e.key, e.value))
+	}
+
+	return os.WriteFile(configFileName, []byte(sb.String()), filePerm)
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var (
+	ErrIdentityUnknown = errors.New("identity unknown, set it with 'avc config user.name <name>' and 'avc config user.email <email>'")
+)
+
+// Identity represents the person who authored or commited a change.
+type Identity struct {
+	// Name is the name of the person.
+	Name string
+	// Email is the email of the person.
+	Email string
+	// Date is when the change was authored or commited.
+	Date time.Time
+}
+
+// Author returns the Identity to use as the author of a new commit.
+// AVC_AUTHOR_NAME, AVC_AUTHOR_EMAIL and AVC_AUTHOR_DATE environment variables
+// take precedence over user.name, user.email and the current time.
+func Author() (Identity, error) {
+	return identity("AVC_AUTHOR")
+}
+
+// Commiter returns the Identity to use as the commiter of a new commit.
+// AVC_COMMITER_NAME, AVC_COMMITER_EMAIL and AVC_COMMITER_DATE environment
+// variables take precedence over user.name, user.email and the current time.
+func Commiter() (Identity, error) {
+	return identity("AVC_COMMITER")
+}
+
+// identity builds an Identity from the environment variables starting with
+// envPrefix, falling back to the config file.
+func identity(envPrefix string) (Identity, error) {
+	lookup := func(env string, key string) (string, error) {
+		if v, ok := os.LookupEnv(env); ok && v != "" {
+			return v, nil
+		}
+
+		v, ok, err := Get(key)
+		if err != nil {
+			return "", err
+		}
+		if !ok || v == "" {
+			return "", ErrIdentityUnknown
+		}
+
+		return v, nil
+	}
+
+	name, err := lookup(envPrefix+"_NAME", "user.name")
+	if err != nil {
+		return Identity{}, err
+	}
+
+	email, err := lookup(envPrefix+"_EMAIL", "user.email")
+	if err != nil {
+		return Identity{}, err
+	}
+
+	// Dates are stored with a precision of one second.
+	date := time.Now().Truncate(time.Second)
+	if v, ok := os.LookupEnv(envPrefix + "_DATE"); ok && v != "" {
+		date, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return Identity{}, fmt.Errorf("%v_DATE should be in RFC3339 format: %w", envPrefix, err)
+		}
+	}
+
+	return Identity{Name: name, Email: email, Date: date}, nil
+}
@@ -0,0 +1,184 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxFuzz is how many context lines at each end of a hunk Apply ignores
+	// at most, when the hunk does not apply with all of them.
+	MaxFuzz = 2
+)
+
+var (
+	ErrHunkFailed = errors.New("hunk failed")
+)
+
+// HunkResult is where Apply applied a hunk.
+type HunkResult struct {
+	// Line is the 1-based line of the old content the hunk was applied at,
+	// or was expected at when it failed.
+	Line int
+	// Offset is how many lines the hunk was moved from where it expected to
+	// be, because lines were added or deleted before it.
+	Offset int
+	// Fuzz is the number of context lines at each end of the hunk which were
+	// ignored, because they did not match.
+	Fuzz int
+	// Failed is set when the hunk did not apply.
+	Failed bool
+}
+
+// String describes r like patch does, e.g. "succeeded at 12 (offset 2 lines)".
+func (r HunkResult) String() string {
+	if r.Failed {
+		return fmt.Sprintf("FAILED at %v", r.Line)
+	}
+
+	s := fmt.Sprintf("succeeded at %v", r.Line)
+	if r.Fuzz > 0 {
+		s += fmt.Sprintf(" with fuzz %v", r.Fuzz)
+	}
+	if r.Offset != 0 {
+		s += fmt.Sprintf(" (offset %v)", plural(r.Offset, "line", "lines"))
+	}
+
+	return s
+}
+
+// Apply applies hunks to content and returns the patched content. Each hunk is
+// looked for where it expects to be first, and then further and further
+// away, never before the previous hunk. When it is not found, up to MaxFuzz
+// context lines are ignored at each end. All hunks are tried, and when any of
+// them fails, ErrHunkFailed is returned together with the results.
+func Apply(content []byte, hunks []Hunk) ([]byte, []HunkResult, error) {
+	lines := SplitLines(content)
+
+	var output []string
+	var results []HunkResult
+	failed := false
+	// next is the first line the next hunk may start at, and offset is how
+	// far the previous hunk was moved, which the next one is likely moved too.
+	next, offset := 0, 0
+	for _, h := range hunks {
+		expected := h.OldStart - 1
+		if h.OldLines == 0 {
+			// A hunk without old lines is inserted after line OldStart.
+			expected = h.OldStart
+		}
+
+		r := HunkResult{Line: expected + offset + 1, Failed: true}
+		for fuzz := 0; fuzz <= MaxFuzz && r.Failed; fuzz++ {
+			pre, post, lead, ok := h.images(fuzz)
+			if !ok {
+				break
+			}
+
+			// Without fuzz, a hunk with less context at its start than at
+			// its end has to be at the start, and the other way around.
+			start := expected + lead
+			atStart := fuzz == 0 && h.context(false) < h.context(true)
+			atEnd := fuzz == 0 && h.context(true) < h.context(false)
+
+			pos, found := findLines(lines, pre, start+offset, next, atStart, atEnd)
+			if !found {
+				continue
+			}
+
+			r = HunkResult{Line: pos - lead + 1, Offset: pos - start, Fuzz: fuzz}
+			output = append(output, lines[next:pos]...)
+			output = append(output, post...)
+			next, offset = pos+len(pre), pos-start
+		}
+
+		if r.Failed {
+			failed = true
+		}
+		results = append(results, r)
+	}
+
+	if failed {
+		return nil, results, ErrHunkFailed
+	}
+
+	output = append(output, lines[next:]...)
+	return []byte(strings.Join(output, "")), results, nil
+}
+
+// context returns the number of context lines at the end of h when end is
+// set, and at its start otherwise.
+func (h Hunk) context(end bool) int {
+	n := 0
+	for i := range h.Lines {
+		l := h.Lines[i]
+		if end {
+			l = h.Lines[len(h.Lines)-1-i]
+		}
+		if l.Kind != OpEqual {
+			break
+		}
+		n++
+	}
+
+	return n
+}
+
+// images returns the lines h expects and the ones it replaces them with, both
+// without fuzz context lines at each end, and how many lines were left out at
+// the start. It reports false when h does not have that many context lines.
+func (h Hunk) images(fuzz int) (pre []string, post []string, lead int, ok bool) {
+	lead, trail := min(fuzz, h.context(false)), min(fuzz, h.context(true))
+	if fuzz > 0 && lead < fuzz && trail < fuzz {
+		return nil, nil, 0, false
+	}
+	if lead+trail >= len(h.Lines) {
+		return nil, nil, 0, false
+	}
+
+	for _, l := range h.Lines[lead : len(h.Lines)-trail] {
+		if l.Kind != OpInsert {
+			pre = append(pre, l.Text)
+		}
+		if l.Kind != OpDelete {
+			post = append(post, l.Text)
+		}
+	}
+
+	return pre, post, lead, true
+}
+
+// findLines finds pre in lines, starting at start, or the closest line to it,
+// and alternating between later and earlier lines, but never before from. With atStart, pre has to be
+// at the first line, and with atEnd it has to end at the last line.
+func findLines(lines []string, pre []string, start int, from int, atStart bool, atEnd bool) (int, bool) {
+	matches := func(pos int) bool {
+		if pos < from || pos+len(pre) > len(lines) {
+			return false
+		}
+		if (atStart && pos != 0) || (atEnd && pos+len(pre) != len(lines)) {
+			return false
+		}
+
+		for i, l := range pre {
+			if lines[pos+i] != l {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	start = min(max(start, 0), len(lines))
+	for d := 0; start-d >= from || start+d <= len(lines); d++ {
+		if matches(start + d) {
+			return start + d, true
+		}
+		if d > 0 && matches(start-d) {
+			return start - d, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,149 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseHunks returns the hunks of a patch of a single file, given without its
+// --- and +++ lines.
+func parseHunks(t *testing.T, hunks string) []Hunk {
+	t.Helper()
+
+	patches, err := ParsePatch(strings.NewReader("--- a/f\n+++ b/f\n" + hunks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("got %v patches, want 1", len(patches))
+	}
+
+	return patches[0].Hunks
+}
+
+// numbers returns the lines "01" to n, each with a new line.
+func numbers(n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "%02d\n", i)
+	}
+
+	return sb.String()
+}
+
+func TestApply(t *testing.T) {
+	// The hunk changes line 05 and has three context lines at each end.
+	hunk := "@@ -2,7 +2,7 @@\n 02\n 03\n 04\n-05\n+XX\n 06\n 07\n 08\n"
+	content := numbers(10)
+	patched := strings.Replace(content, "05\n", "XX\n", 1)
+
+	tests := []struct {
+		name    string
+		content string
+		hunks   string
+		want    string
+		results []HunkResult
+	}{
+		{"in place", content, hunk, patched, []HunkResult{{Line: 2}}},
+		{
+			"lines added before", "a\nb\n" + content, hunk, "a\nb\n" + patched,
+			[]HunkResult{{Line: 4, Offset: 2}},
+		},
+		{
+			"lines deleted before", strings.TrimPrefix(content, "01\n"), hunk, strings.TrimPrefix(patched, "01\n"),
+			[]HunkResult{{Line: 1, Offset: -1}},
+		},
+		{
+			"first context line changed", strings.Replace(content, "02\n", "ZZ\n", 1), hunk,
+			strings.Replace(patched, "02\n", "ZZ\n", 1),
+			[]HunkResult{{Line: 2, Fuzz: 1}},
+		},
+		{
+			"two context lines changed at each end",
+			strings.NewReplacer("02\n", "ZZ\n", "03\n", "ZZ\n", "07\n", "ZZ\n", "08\n", "ZZ\n").Replace(content), hunk,
+			strings.NewReplacer("02\n", "ZZ\n", "03\n", "ZZ\n", "07\n", "ZZ\n", "08\n", "ZZ\n").Replace(patched),
+			[]HunkResult{{Line: 2, Fuzz: 2}},
+		},
+		{
+			"fuzz and offset", "a\n" + strings.Replace(content, "08\n", "ZZ\n", 1), hunk,
+			"a\n" + strings.Replace(patched, "08\n", "ZZ\n", 1),
+			[]HunkResult{{Line: 3, Offset: 1, Fuzz: 1}},
+		},
+		{
+			"later hunks are moved too", "a\nb\n" + content,
+			"@@ -4,3 +4,3 @@\n 04\n-05\n+XX\n 06\n@@ -9,2 +9,2 @@\n 09\n-10\n+YY\n",
+			"a\nb\n" + strings.Replace(patched, "10\n", "YY\n", 1),
+			[]HunkResult{{Line: 6, Offset: 2}, {Line: 11, Offset: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		got, results, err := Apply([]byte(tt.content), parseHunks(t, tt.hunks))
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%v: got\n%v\nwant\n%v", tt.name, string(got), tt.want)
+		}
+		if !reflect.DeepEqual(results, tt.results) {
+			t.Errorf("%v: got results %+v, want %+v", tt.name, results, tt.results)
+		}
+	}
+}
+
+func TestApplyFails(t *testing.T) {
+	hunk := "@@ -2,7 +2,7 @@\n 02\n 03\n 04\n-05\n+XX\n 06\n 07\n 08\n"
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"changed line differs", strings.Replace(numbers(10), "05\n", "ZZ\n", 1)},
+		{
+			// With fuzz 2, the remaining context line at the start still
+			// has to match.
+			"three context lines changed", strings.NewReplacer("02\n", "ZZ\n", "03\n", "ZZ\n", "04\n", "ZZ\n").Replace(numbers(10)),
+		},
+		{"empty content", ""},
+	}
+
+	for _, tt := range tests {
+		got, results, err := Apply([]byte(tt.content), parseHunks(t, hunk))
+		if !errors.Is(err, ErrHunkFailed) {
+			t.Errorf("%v: got error %v, want %v", tt.name, err, ErrHunkFailed)
+			continue
+		}
+		if got != nil || len(results) != 1 || !results[0].Failed {
+			t.Errorf("%v: got %q and results %+v, want a failed hunk", tt.name, got, results)
+		}
+	}
+
+	// All hunks are tried, so the result tells which ones failed.
+	_, results, err := Apply([]byte(numbers(20)), parseHunks(t, "@@ -2 +2 @@\n-XX\n+YY\n@@ -12 +12 @@\n-12\n+YY\n"))
+	want := []HunkResult{{Line: 2, Failed: true}, {Line: 12}}
+	if !errors.Is(err, ErrHunkFailed) || !reflect.DeepEqual(results, want) {
+		t.Errorf("got results %+v and error %v, want %+v", results, err, want)
+	}
+}
+
+func TestHunkResultString(t *testing.T) {
+	tests := []struct {
+		r    HunkResult
+		want string
+	}{
+		{HunkResult{Line: 3}, "succeeded at 3"},
+		{HunkResult{Line: 3, Offset: 1}, "succeeded at 3 (offset 1 line)"},
+		{HunkResult{Line: 3, Offset: -2, Fuzz: 1}, "succeeded at 3 with fuzz 1 (offset -2 lines)"},
+		{HunkResult{Line: 3, Failed: true}, "FAILED at 3"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.r.String(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package diff
+
+import (
+	"bufio"
+)
+
+// ANSI escape sequences of the colors git uses for diffs.
+const (
+	colorReset = "\x1b[m"
+	// colorMeta is used for the lines which describe a file.
+	colorMeta = "\x1b[1m"
+	// colorFrag is used for hunk headers.
+	colorFrag = "\x1b[36m"
+	colorOld  = "\x1b[31m"
+	colorNew  = "\x1b[32m"
+)
+
+// paint returns s highlighted with color when opts.Color is set.
+func (opts Options) paint(color string, s string) string {
+	if !opts.Color || color == "" || s == "" {
+		return s
+	}
+
+	return color + s + colorReset
+}
+
+// writeLine writes line highlighted with color, followed by a new line.
+func (opts Options) writeLine(bw *bufio.Writer, color string, line string) {
+	bw.WriteString(opts.paint(color, line))
+	bw.WriteByte('\n')
+}
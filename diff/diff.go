@@ -0,0 +1,196 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultContext is the number of unchanged lines shown around changes.
+	DefaultContext = 3
+	// binaryCheckLen is how many bytes are looked at to detect binary content.
+	binaryCheckLen = 8000
+)
+
+// OpKind is the kind of an edit operation.
+type OpKind int
+
+const (
+	OpEqual OpKind = iota
+	OpDelete
+	OpInsert
+)
+
+// Op is a single line of an edit script which turns a into b.
+type Op struct {
+	Kind OpKind
+	// A is the index of the line in a, it is -1 for inserted lines.
+	A int
+	// B is the index of the line in b, it is -1 for deleted lines.
+	B int
+}
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown diff algorithm")
+)
+
+// Algorithm is an algorithm which computes the edit script of two contents.
+type Algorithm int
+
+const (
+	// AlgorithmMyers finds the shortest edit script, see Myers.
+	AlgorithmMyers Algorithm = iota
+	// AlgorithmPatience matches unique lines first, see Patience.
+	AlgorithmPatience
+	// AlgorithmHistogram matches rare lines first, see Histogram.
+	AlgorithmHistogram
+)
+
+var algorithmNames = []string{"myers", "patience", "histogram"}
+
+func (a Algorithm) String() string {
+	return algorithmNames[a]
+}
+
+// ParseAlgorithm returns the Algorithm with name, "default" is AlgorithmMyers.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	if name == "default" {
+		return AlgorithmMyers, nil
+	}
+
+	for i, n := range algorithmNames {
+		if n == name {
+			return Algorithm(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q, it should be one of %v", ErrUnknownAlgorithm, name, strings.Join(algorithmNames, ", "))
+}
+
+// diff returns the edit script of a and b computed with algorithm a.
+func (a Algorithm) diff(x []string, y []string) []Op {
+	switch a {
+	case AlgorithmPatience:
+		return Patience(x, y)
+	case AlgorithmHistogram:
+		return Histogram(x, y)
+	}
+
+	return Myers(x, y)
+}
+
+// WordDiffMode is how changes are shown within lines.
+type WordDiffMode int
+
+const (
+	// WordDiffNone shows whole lines as deleted and added.
+	WordDiffNone WordDiffMode = iota
+	// WordDiffPlain shows changed words inside lines as [-deleted-] and {+added+}.
+	WordDiffPlain
+	// WordDiffColor shows changed words inside lines only with colors.
+	WordDiffColor
+)
+
+// Options controls how content is compared and how the difference is shown.
+type Options struct {
+	// Context is the number of unchanged lines shown around changes.
+	Context int
+	// Color highlights the output with ANSI escape sequences.
+	Color bool
+	// Algorithm computes the edit script of lines.
+	Algorithm Algorithm
+	// IgnoreAllSpace compares lines without any of their whitespace.
+	IgnoreAllSpace bool
+	// IgnoreSpaceChange compares lines with each run of whitespace as a
+	// single space and without whitespace at their end.
+	IgnoreSpaceChange bool
+	// IgnoreBlankLines does not show changes which only add or delete
+	// lines without anything but whitespace, unless they are close to other
+	// changes.
+	IgnoreBlankLines bool
+	// WordDiff shows the changes within lines instead of whole lines.
+	WordDiff WordDiffMode
+}
+
+// DefaultOptions returns the Options diff uses unless told otherwise.
+func DefaultOptions() Options {
+	return Options{Context: DefaultContext}
+}
+
+// SplitLines splits content into lines. Each line keeps its trailing new line,
+// so the last line has none when content does not end with a new line.
+func SplitLines(content []byte) []string {
+	var lines []string
+	for len(content) > 0 {
+		i := bytes.IndexByte(content, '\n')
+		if i < 0 {
+			lines = append(lines, string(content))
+			break
+		}
+
+		lines = append(lines, string(content[:i+1]))
+		content = content[i+1:]
+	}
+
+	return lines
+}
+
+// IsBinary reports whether content should be treated as binary rather than
+// text, which is the case when its beginning contains a NUL byte.
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binaryCheckLen)], 0) >= 0
+}
+
+// Lines compares the lines of a and b and returns the edit script which turns
+// a into b, computed with opts.Algorithm. Lines which only differ in the
+// whitespace opts ignores are equal.
+func Lines(a []string, b []string, opts Options) []Op {
+	if !opts.IgnoreAllSpace && !opts.IgnoreSpaceChange {
+		return opts.Algorithm.diff(a, b)
+	}
+
+	return opts.Algorithm.diff(opts.normalizeLines(a), opts.normalizeLines(b))
+}
+
+// normalizeLines returns lines with the whitespace opts ignores removed.
+func (opts Options) normalizeLines(lines []string) []string {
+	output := make([]string, len(lines))
+	for i, l := range lines {
+		if opts.IgnoreAllSpace {
+			output[i] = strings.Map(func(r rune) rune {
+				if unicode.IsSpace(r) {
+					return -1
+				}
+
+				return r
+			}, l)
+			continue
+		}
+
+		var sb strings.Builder
+		space := false
+		for _, r := range strings.TrimRightFunc(l, unicode.IsSpace) {
+			if unicode.IsSpace(r) {
+				space = true
+				continue
+			}
+
+			if space {
+				sb.WriteByte(' ')
+				space = false
+			}
+			sb.WriteRune(r)
+		}
+		output[i] = sb.String()
+	}
+
+	return output
+}
+
+// isBlank reports whether line has nothing but whitespace.
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
@@ -0,0 +1,93 @@
+package diff
+
+const (
+	// maxHistogramChain is how often a line may appear in the old content to
+	// be used as an anchor by Histogram. Regions with only more common lines
+	// are compared with Myers.
+	maxHistogramChain = 64
+)
+
+// Histogram returns an edit script which turns a into b, computed with the
+// histogram diff algorithm of JGit, which git uses as well. It extends the
+// idea of Patience to lines which are not unique: the longest run of equal
+// lines around the line of b which appears the least often in a is matched,
+// and the lines before and after it are compared the same way.
+func Histogram(a []string, b []string) []Op {
+	ia, ib := internLines(a, b)
+	return appendHistogram(make([]Op, 0, max(len(a), len(b))), ia, ib, 0, 0)
+}
+
+// appendHistogram appends the edit script of a and b computed by Histogram
+// to ops, see appendMyers.
+func appendHistogram(ops []Op, a []int, b []int, aStart int, bStart int) []Op {
+	ops, a, b, aStart, bStart, suffix := trimCommon(ops, a, b, aStart, bStart)
+
+	r, ok := lowestRegion(a, b)
+	if !ok {
+		ops = appendMyers(ops, a, b, aStart, bStart)
+		return appendEqual(ops, aStart+len(a), bStart+len(b), suffix)
+	}
+
+	ops = appendHistogram(ops, a[:r.a], b[:r.b], aStart, bStart)
+	ops = appendEqual(ops, aStart+r.a, bStart+r.b, r.n)
+	ops = appendHistogram(ops, a[r.a+r.n:], b[r.b+r.n:], aStart+r.a+r.n, bStart+r.b+r.n)
+
+	return appendEqual(ops, aStart+len(a), bStart+len(b), suffix)
+}
+
+// region is a run of n equal lines, starting at line a of the old and line b
+// of the new content.
+type region struct {
+	a int
+	b int
+	n int
+}
+
+// lowestRegion finds the run of equal lines of a and b whose least common line
+// appears the least often in a, preferring longer runs when that is the same.
+// It reports false when every line b shares with a appears too often in a.
+func lowestRegion(a []int, b []int) (region, bool) {
+	positions := make(map[int][]int)
+	for i, l := range a {
+		positions[l] = append(positions[l], i)
+	}
+
+	var best region
+	bestCount := maxHistogramChain + 1
+	for j := 0; j < len(b); {
+		next := j + 1
+		for _, i := range positions[b[j]] {
+			if len(positions[b[j]]) > maxHistogramChain {
+				break
+			}
+
+			// Extend the match in both directions and find the least common
+			// line in it.
+			s, t := i, j
+			for s > 0 && t > 0 && a[s-1] == b[t-1] {
+				s--
+				t--
+			}
+			e, f := i+1, j+1
+			for e < len(a) && f < len(b) && a[e] == b[f] {
+				e++
+				f++
+			}
+
+			count := len(positions[b[j]])
+			for k := s; k < e; k++ {
+				count = min(count, len(positions[a[k]]))
+			}
+
+			if count < bestCount || (count == bestCount && e-s > best.n) {
+				best, bestCount = region{a: s, b: t, n: e - s}, count
+			}
+			// Lines inside this run can only find the same run again.
+			next = max(next, f)
+		}
+
+		j = next
+	}
+
+	return best, best.n > 0
+}
@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	// conflictMarkerLen is the length of the markers around conflicts.
+	conflictMarkerLen = 7
+)
+
+// MergeLabels are written after the conflict markers of each side.
+type MergeLabels struct {
+	Ours   string
+	Base   string
+	Theirs string
+}
+
+// Merge merges the changes ours and theirs made to base line by line, like
+// diff3 does, and returns the result and the number of conflicts in it.
+// Lines which only one side changed, or both changed the same way, are taken
+// as they are. Where both changed the same lines differently, the result has
+// the lines of each side between conflict markers:
+//
+//	<<<<<<< ours
+//	the lines of ours
+//	||||||| base
+//	the lines of base
+//	=======
+//	the lines of theirs
+//	>>>>>>> theirs
+func Merge(base []byte, ours []byte, theirs []byte, labels MergeLabels, opts Options) ([]byte, int) {
+	o, a, b := SplitLines(base), SplitLines(ours), SplitLines(theirs)
+
+	// matchA and matchB map the lines of base to the equal lines of ours and
+	// theirs, or -1 where a side changed them.
+	match := func(other []string) []int {
+		m := make([]int, len(o))
+		for i := range m {
+			m[i] = -1
+		}
+		for _, op := range Lines(o, other, opts) {
+			if op.Kind == OpEqual {
+				m[op.A] = op.B
+			}
+		}
+
+		return m
+	}
+	matchA, matchB := match(a), match(b)
+
+	var sb strings.Builder
+	conflicts := 0
+	// i, j and k are the next lines of base, ours and theirs.
+	i, j, k := 0, 0, 0
+	for i < len(o) || j < len(a) || k < len(b) {
+		if i < len(o) && matchA[i] == j && matchB[i] == k {
+			sb.WriteString(a[j])
+			i, j, k = i+1, j+1, k+1
+			continue
+		}
+
+		// The chunk which changed on either side ends at the next line of
+		// base which neither side changed, or at the end.
+		end, endA, endB := len(o), len(a), len(b)
+		for n := i; n < len(o); n++ {
+			if matchA[n] >= j && matchB[n] >= k {
+				end, endA, endB = n, matchA[n], matchB[n]
+				break
+			}
+		}
+
+		oc, ac, bc := o[i:end], a[j:endA], b[k:endB]
+		switch {
+		case slices.Equal(oc, ac):
+			sb.WriteString(strings.Join(bc, ""))
+		case slices.Equal(oc, bc) || slices.Equal(ac, bc):
+			sb.WriteString(strings.Join(ac, ""))
+		default:
+			conflicts++
+			writeMarker(&sb, "<", labels.Ours)
+			sb.WriteString(strings.Join(ac, ""))
+			writeMarker(&sb, "|", labels.Base)
+			sb.WriteString(strings.Join(oc, ""))
+			writeMarker(&sb, "=", "")
+			sb.WriteString(strings.Join(bc, ""))
+			writeMarker(&sb, ">", labels.Theirs)
+		}
+
+		i, j, k = end, endA, endB
+	}
+
+	return []byte(sb.String()), conflicts
+}
+
+// writeMarker writes a conflict marker of c followed by label to sb, on a
+// line of its own. A new line is added first when the lines before the marker
+// do not end with one.
+func writeMarker(sb *strings.Builder, c string, label string) {
+	if s := sb.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		sb.WriteByte('\n')
+	}
+
+	sb.WriteString(strings.Repeat(c, conflictMarkerLen))
+	if label != "" {
+		sb.WriteString(" " + label)
+	}
+	sb.WriteByte('\n')
+}
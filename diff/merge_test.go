@@ -0,0 +1,63 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	labels := MergeLabels{Ours: "ours", Base: "base", Theirs: "theirs"}
+
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{"nothing changed", "a\nb\n", "a\nb\n", "a\nb\n", "a\nb\n", 0},
+		{"only ours changed", "a\nb\nc\n", "a\nX\nc\n", "a\nb\nc\n", "a\nX\nc\n", 0},
+		{"only theirs changed", "a\nb\nc\n", "a\nb\nc\n", "a\nb\nY\n", "a\nb\nY\n", 0},
+		{"both changed the same way", "a\nb\nc\n", "a\nX\nc\n", "a\nX\nc\n", "a\nX\nc\n", 0},
+		{"different lines changed", "a\nb\nc\nd\ne\n", "X\nb\nc\nd\ne\n", "a\nb\nc\nd\nY\n", "X\nb\nc\nd\nY\n", 0},
+		{"deleted and changed lines", "a\nb\nc\nd\ne\n", "a\nc\nd\ne\n", "a\nb\nc\nd\nY\n", "a\nc\nd\nY\n", 0},
+		{"both added at the end", "a\n", "a\nb\n", "a\nb\n", "a\nb\n", 0},
+		{
+			"same line changed differently", "a\nb\nc\n", "a\nX\nc\n", "a\nY\nc\n",
+			"a\n<<<<<<< ours\nX\n||||||| base\nb\n=======\nY\n>>>>>>> theirs\nc\n", 1,
+		},
+		{
+			"changed and deleted", "a\nb\nc\n", "a\nX\nc\n", "a\nc\n",
+			"a\n<<<<<<< ours\nX\n||||||| base\nb\n=======\n>>>>>>> theirs\nc\n", 1,
+		},
+		{
+			"added differently", "", "a\n", "b\n",
+			"<<<<<<< ours\na\n||||||| base\n=======\nb\n>>>>>>> theirs\n", 1,
+		},
+		{
+			"two conflicts", "a\nb\nc\nd\ne\n", "X\nb\nc\nd\nX\n", "Y\nb\nc\nd\nY\n",
+			"<<<<<<< ours\nX\n||||||| base\na\n=======\nY\n>>>>>>> theirs\nb\nc\nd\n" +
+				"<<<<<<< ours\nX\n||||||| base\ne\n=======\nY\n>>>>>>> theirs\n", 2,
+		},
+		{
+			// The markers are always on lines of their own.
+			"no new line at the end", "a\nb", "a\nX", "a\nY",
+			"a\n<<<<<<< ours\nX\n||||||| base\nb\n=======\nY\n>>>>>>> theirs\n", 1,
+		},
+	}
+
+	for _, tt := range tests {
+		got, conflicts := Merge([]byte(tt.base), []byte(tt.ours), []byte(tt.theirs), labels, DefaultOptions())
+		if string(got) != tt.want || conflicts != tt.conflicts {
+			t.Errorf("%v: got %v conflicts in\n%v\nwant %v conflicts in\n%v", tt.name, conflicts, string(got), tt.conflicts, tt.want)
+		}
+	}
+}
+
+func TestMergeWithoutLabels(t *testing.T) {
+	got, conflicts := Merge([]byte("a\n"), []byte("b\n"), []byte("c\n"), MergeLabels{}, DefaultOptions())
+
+	if want := "<<<<<<<\nb\n|||||||\na\n=======\nc\n>>>>>>>\n"; string(got) != want || conflicts != 1 {
+		t.Errorf("got %v conflicts in %q, want 1 in %q", conflicts, got, want)
+	}
+}
@@ -0,0 +1,154 @@
+package diff
+
+// Myers returns the shortest edit script which turns a into b, computed with
+// the O(ND) algorithm of Eugene W. Myers, "An O(ND) Difference Algorithm and
+// Its Variations". Lines which are the same at the start and the end of a and
+// b are matched before the search, they are common in real changes and would
+// otherwise make the search longer.
+func Myers(a []string, b []string) []Op {
+	// Lines are compared as integers, comparing strings in the search is slow.
+	ia, ib := internLines(a, b)
+	return appendMyers(make([]Op, 0, max(len(a), len(b))), ia, ib, 0, 0)
+}
+
+// appendMyers appends the edit script of a and b computed by Myers to ops.
+// a and b start at line aStart and bStart of the whole content, which the
+// indexes of the ops are relative to. They are split at their middle snake
+// and both halves are diffed recursively, like in the linear space variation
+// of the algorithm.
+func appendMyers(ops []Op, a []int, b []int, aStart int, bStart int) []Op {
+	ops, a, b, aStart, bStart, suffix := trimCommon(ops, a, b, aStart, bStart)
+
+	switch {
+	case len(a) == 0:
+		for i := range b {
+			ops = append(ops, Op{Kind: OpInsert, A: -1, B: bStart + i})
+		}
+	case len(b) == 0:
+		for i := range a {
+			ops = append(ops, Op{Kind: OpDelete, A: aStart + i, B: -1})
+		}
+	default:
+		// Without common lines at the ends, there are at least two edits,
+		// so both sides of the middle snake are smaller than a and b.
+		x, y, u, v := middleSnake(a, b)
+		ops = appendMyers(ops, a[:x], b[:y], aStart, bStart)
+		ops = appendEqual(ops, aStart+x, bStart+y, u-x)
+		ops = appendMyers(ops, a[u:], b[v:], aStart+u, bStart+v)
+	}
+
+	return appendEqual(ops, aStart+len(a), bStart+len(b), suffix)
+}
+
+// trimCommon appends the lines a and b start with to ops and returns the
+// rest of a and b, where they start and how many lines they end with, which
+// have to be appended after their edit script.
+func trimCommon(ops []Op, a []int, b []int, aStart int, bStart int) ([]Op, []int, []int, int, int, int) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops = appendEqual(ops, aStart, bStart, prefix)
+	return ops, a[prefix : len(a)-suffix], b[prefix : len(b)-suffix], aStart + prefix, bStart + prefix, suffix
+}
+
+// appendEqual appends n equal lines starting at line aStart and bStart to ops.
+func appendEqual(ops []Op, aStart int, bStart int, n int) []Op {
+	for i := range n {
+		ops = append(ops, Op{Kind: OpEqual, A: aStart + i, B: bStart + i})
+	}
+
+	return ops
+}
+
+// internLines maps every distinct line of a and b to an integer.
+func internLines(a []string, b []string) ([]int, []int) {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		output := make([]int, len(lines))
+		for i, l := range lines {
+			id, ok := ids[l]
+			if !ok {
+				id = len(ids)
+				ids[l] = id
+			}
+			output[i] = id
+		}
+
+		return output
+	}
+
+	return intern(a), intern(b)
+}
+
+// middleSnake returns where the middle snake of the shortest edit script of a
+// and b starts, x and y, and ends, u and v. The greedy search of the Myers
+// algorithm runs from the start and from the end at the same time, until the
+// paths overlap after about half of the edits. Only the furthest reaching
+// paths are kept, so the memory needed is linear, unlike when the whole path
+// is traced.
+func middleSnake(a []int, b []int) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+
+	// forward[k] holds the furthest x reached on diagonal k = x - y from the
+	// start, backward[k] the same from the end, where a and b are reversed.
+	forward := make([]int, 2*maxD+3)
+	backward := make([]int, 2*maxD+3)
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			// Move down (insert) from diagonal k+1 or right (delete) from k-1,
+			// whichever reached further.
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+
+			// The diagonal delta - k from the end is diagonal k from the start.
+			if back := delta - k; odd && back >= -(d-1) && back <= d-1 && u+backward[offset+back] >= n {
+				return x, y, u, v
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var rx int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				rx = backward[offset+k+1]
+			} else {
+				rx = backward[offset+k-1] + 1
+			}
+
+			ry := rx - k
+			ru, rv := rx, ry
+			for ru < n && rv < m && a[n-1-ru] == b[m-1-rv] {
+				ru++
+				rv++
+			}
+			backward[offset+k] = ru
+
+			if front := delta - k; !odd && front >= -d && front <= d && forward[offset+front]+ru >= n {
+				return n - ru, m - rv, n - rx, m - ry
+			}
+		}
+	}
+
+	panic("the paths from the start and the end of the edit script did not meet")
+}
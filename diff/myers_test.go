@@ -0,0 +1,149 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// letters returns a line for each letter of s, which keeps the tables short.
+func letters(s string) []string {
+	var lines []string
+	for _, r := range s {
+		lines = append(lines, string(r)+"\n")
+	}
+
+	return lines
+}
+
+// checkScript fails the test when ops is not an edit script which turns a
+// into b, and returns the number of deleted and inserted lines.
+func checkScript(t *testing.T, name string, a []string, b []string, ops []Op) int {
+	t.Helper()
+
+	i, j, edits := 0, 0, 0
+	for _, op := range ops {
+		switch op.Kind {
+		case OpEqual:
+			if op.A != i || op.B != j || a[i] != b[j] {
+				t.Fatalf("%v: equal op %+v at line %v of a and %v of b does not match", name, op, i, j)
+			}
+			i, j = i+1, j+1
+		case OpDelete:
+			if op.A != i || op.B != -1 {
+				t.Fatalf("%v: delete op %+v at line %v of a", name, op, i)
+			}
+			i, edits = i+1, edits+1
+		case OpInsert:
+			if op.A != -1 || op.B != j {
+				t.Fatalf("%v: insert op %+v at line %v of b", name, op, j)
+			}
+			j, edits = j+1, edits+1
+		}
+	}
+
+	if i != len(a) || j != len(b) {
+		t.Fatalf("%v: script ends at line %v of %v in a and %v of %v in b", name, i, len(a), j, len(b))
+	}
+
+	return edits
+}
+
+// minEdits returns the length of the shortest edit script of a and b, computed
+// from their longest common subsequence.
+func minEdits(a []string, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+var scriptTests = []struct {
+	name string
+	a    string
+	b    string
+}{
+	{"both empty", "", ""},
+	{"insert all", "", "abc"},
+	{"delete all", "abc", ""},
+	{"identical", "abcdef", "abcdef"},
+	{"replace all", "abc", "xyz"},
+	{"insert in the middle", "abef", "abcdef"},
+	{"delete at the ends", "xabcx", "abc"},
+	{"paper example", "abcabba", "cbabac"},
+	{"repeated lines", "aaaaab", "baaaaa"},
+	{"swap", "ab", "ba"},
+}
+
+func TestAlgorithmsReturnEditScripts(t *testing.T) {
+	for _, alg := range []Algorithm{AlgorithmMyers, AlgorithmPatience, AlgorithmHistogram} {
+		for _, tt := range scriptTests {
+			a, b := letters(tt.a), letters(tt.b)
+			name := alg.String() + ": " + tt.name
+			edits := checkScript(t, name, a, b, alg.diff(a, b))
+
+			if tt.a == tt.b && edits != 0 {
+				t.Errorf("%v: %v edits for identical content", name, edits)
+			}
+		}
+	}
+}
+
+func TestMyersFindsShortestEditScript(t *testing.T) {
+	for _, tt := range scriptTests {
+		a, b := letters(tt.a), letters(tt.b)
+		if got, want := checkScript(t, tt.name, a, b, Myers(a, b)), minEdits(a, b); got != want {
+			t.Errorf("%v: %v edits, want %v", tt.name, got, want)
+		}
+	}
+
+	// Random contents with few different lines have many equally long
+	// scripts, which exercises the middle snake of both parities.
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		var sb strings.Builder
+		for range r.Intn(40) {
+			sb.WriteByte("abc"[r.Intn(3)])
+		}
+
+		return letters(sb.String())
+	}
+	for range 2000 {
+		a, b := random(), random()
+		if got, want := checkScript(t, "random", a, b, Myers(a, b)), minEdits(a, b); got != want {
+			t.Fatalf("%q and %q: %v edits, want %v", a, b, got, want)
+		}
+	}
+}
+
+func TestPatienceAndHistogramKeepUniqueLinesTogether(t *testing.T) {
+	a := SplitLines([]byte("int a() {\n\treturn 1;\n}\n\nint c() {\n\treturn 3;\n}\n"))
+	b := SplitLines([]byte("int a() {\n\treturn 1;\n}\n\nint b() {\n\treturn 2;\n}\n\nint c() {\n\treturn 3;\n}\n"))
+
+	for _, alg := range []Algorithm{AlgorithmPatience, AlgorithmHistogram} {
+		ops := alg.diff(a, b)
+		if edits := checkScript(t, alg.String(), a, b, ops); edits != 4 {
+			t.Errorf("%v: %v edits, want the 4 inserted lines", alg, edits)
+		}
+
+		// The new function and its blank line are inserted as a whole,
+		// instead of sharing the closing brace of a or c.
+		for _, op := range ops {
+			if op.Kind == OpInsert && (op.B < 4 || op.B > 7) {
+				t.Errorf("%v: line %q of b is inserted, want lines 5 to 8", alg, b[op.B])
+			}
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package diff
+
+import (
+	"armanVersionControl/structures"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCorruptPatch = errors.New("corrupt patch")
+)
+
+// maxHunkNumber is the largest line number or count of lines in a hunk header.
+const maxHunkNumber = 1<<31 - 1
+
+// hunkHeaderRegexp matches the "@@ -<old start>,<old lines> +<new start>,<new lines> @@"
+// line of a hunk, see Hunk.Header.
+var hunkHeaderRegexp = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// FilePatch is the patch of a single file, as written by WritePatch.
+type FilePatch struct {
+	// Change describes the file before and after the patch. The hashes are
+	// the ones of the "index" line, so they are usually abbreviated, and
+	// they are empty when the patch has no such line. The modes are zero
+	// when the patch does not have them, which means the mode is unchanged.
+	Change
+	// Binary is set for patches of binary files, which have no hunks.
+	Binary bool
+	Hunks  []Hunk
+}
+
+// Reverse returns the patch which undoes p.
+func (p FilePatch) Reverse() (FilePatch, error) {
+	r := FilePatch{Binary: p.Binary}
+	r.Change = Change{
+		Kind:       p.Kind,
+		Path:       p.SourcePath(),
+		OldMode:    p.NewMode,
+		OldHash:    p.NewHash,
+		NewMode:    p.OldMode,
+		NewHash:    p.OldHash,
+		Similarity: p.Similarity,
+	}
+
+	switch p.Kind {
+	case ChangeAdded:
+		r.Kind = ChangeDeleted
+	case ChangeDeleted:
+		r.Kind = ChangeAdded
+	case ChangeRenamed:
+		r.OldPath = p.Path
+	case ChangeCopied:
+		return FilePatch{}, fmt.Errorf("the copy of '%v' to '%v' can not be reversed", p.OldPath, p.Path)
+	}
+
+	for _, h := range p.Hunks {
+		r.Hunks = append(r.Hunks, h.Reverse())
+	}
+
+	return r, nil
+}
+
+// Reverse returns the hunk which undoes h.
+func (h Hunk) Reverse() Hunk {
+	r := Hunk{OldStart: h.NewStart, OldLines: h.NewLines, NewStart: h.OldStart, NewLines: h.OldLines}
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case OpDelete:
+			l.Kind = OpInsert
+		case OpInsert:
+			l.Kind = OpDelete
+		}
+		r.Lines = append(r.Lines, l)
+	}
+
+	return r
+}
+
+// ParsePatch reads the patches of files in the unified format from r. Both
+// the format of WritePatch and plain unified diffs are understood, in which
+// case the first directory of each path is removed like for "a/" and "b/".
+// Anything before, between and after the patches, like the message of an
+// email, is skipped.
+func ParsePatch(r io.Reader) ([]FilePatch, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := patchParser{lines: SplitLines(content)}
+	var patches []FilePatch
+	for p.i < len(p.lines) {
+		l := p.lines[p.i]
+		switch {
+		case strings.HasPrefix(l, "diff --git "):
+			fp, err := p.parseGitPatch()
+			if err != nil {
+				return nil, err
+			}
+			patches = append(patches, fp)
+		case strings.HasPrefix(l, "--- ") && strings.HasPrefix(p.line(1), "+++ ") && strings.HasPrefix(p.line(2), "@@ "):
+			fp := FilePatch{Change: Change{Kind: ChangeModified}}
+			if err := p.parseNames(&fp); err != nil {
+				return nil, err
+			}
+			if err := p.parseHunks(&fp); err != nil {
+				return nil, err
+			}
+			patches = append(patches, fp)
+		default:
+			p.i++
+		}
+	}
+
+	return patches, nil
+}
+
+// patchParser holds the lines ParsePatch reads and the index of the next one.
+type patchParser struct {
+	lines []string
+	i     int
+}
+
+// line returns the line n lines after the next one, without its new line, or
+// an empty string after the end.
+func (p *patchParser) line(n int) string {
+	if p.i+n >= len(p.lines) {
+		return ""
+	}
+
+	return strings.TrimSuffix(p.lines[p.i+n], "\n")
+}
+
+// errorf returns an ErrCorruptPatch for the line n lines after the next one.
+func (p *patchParser) errorf(n int, format string, args ...any) error {
+	return fmt.Errorf("%w at line %v: %v", ErrCorruptPatch, p.i+n+1, fmt.Sprintf(format, args...))
+}
+
+// parseGitPatch parses a patch starting with a "diff --git" line and its
+// extended header lines.
+func (p *patchParser) parseGitPatch() (FilePatch, error) {
+	fp := FilePatch{Change: Change{Kind: ChangeModified}}
+
+	// The names in the "diff --git a/<old> b/<new>" line are only used when
+	// no other line has them. Without spaces in them, they are separated by
+	// the only space, otherwise both are assumed to be the same.
+	names := strings.TrimPrefix(p.line(0), "diff --git ")
+	oldName, newName, ok := strings.Cut(names, " ")
+	if strings.Count(names, " ") != 1 && len(names)%2 == 1 {
+		oldName, newName, ok = names[:len(names)/2], names[len(names)/2+1:], true
+	}
+	if !ok || !strings.HasPrefix(oldName, "a/") || !strings.HasPrefix(newName, "b/") {
+		return FilePatch{}, p.errorf(0, "invalid names %q", names)
+	}
+	oldPath, newPath := oldName[2:], newName[2:]
+	p.i++
+
+	parseMode := func(s string) (structures.EntryMode, error) {
+		m, err := strconv.ParseUint(s, 8, 32)
+		if err != nil || !structures.EntryMode(m).IsValid() || structures.EntryMode(m) == structures.ModeTree {
+			return 0, p.errorf(0, "invalid mode %q", s)
+		}
+
+		return structures.EntryMode(m), nil
+	}
+
+	var err error
+header:
+	for p.i < len(p.lines) {
+		l := p.line(0)
+		switch {
+		case strings.HasPrefix(l, "old mode "):
+			fp.OldMode, err = parseMode(strings.TrimPrefix(l, "old mode "))
+		case strings.HasPrefix(l, "new mode "):
+			fp.NewMode, err = parseMode(strings.TrimPrefix(l, "new mode "))
+		case strings.HasPrefix(l, "new file mode "):
+			fp.Kind = ChangeAdded
+			fp.NewMode, err = parseMode(strings.TrimPrefix(l, "new file mode "))
+		case strings.HasPrefix(l, "deleted file mode "):
+			fp.Kind = ChangeDeleted
+			fp.OldMode, err = parseMode(strings.TrimPrefix(l, "deleted file mode "))
+		case strings.HasPrefix(l, "rename from "):
+			fp.Kind, oldPath = ChangeRenamed, strings.TrimPrefix(l, "rename from ")
+		case strings.HasPrefix(l, "rename to "):
+			fp.Kind, newPath = ChangeRenamed, strings.TrimPrefix(l, "rename to ")
+		case strings.HasPrefix(l, "copy from "):
+			fp.Kind, oldPath = ChangeCopied, strings.TrimPrefix(l, "copy from ")
+		case strings.HasPrefix(l, "copy to "):
+			fp.Kind, newPath = ChangeCopied, strings.TrimPrefix(l, "copy to ")
+		case strings.HasPrefix(l, "similarity index "):
+			fp.Similarity, err = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(l, "similarity index "), "%"))
+			if err != nil {
+				err = p.errorf(0, "invalid similarity %q", l)
+			}
+		case strings.HasPrefix(l, "dissimilarity index "):
+		case strings.HasPrefix(l, "index "):
+			hashes, mode, hasMode := strings.Cut(strings.TrimPrefix(l, "index "), " ")
+			oldHash, newHash, ok := strings.Cut(hashes, "..")
+			if !ok {
+				return FilePatch{}, p.errorf(0, "invalid index line %q", l)
+			}
+			fp.OldHash, fp.NewHash = missingHash(oldHash), missingHash(newHash)
+
+			if hasMode {
+				if fp.OldMode, err = parseMode(mode); err == nil {
+					fp.NewMode = fp.OldMode
+				}
+			}
+		case strings.HasPrefix(l, "Binary files ") || l == "GIT binary patch":
+			fp.Binary = true
+		default:
+			break header
+		}
+
+		if err != nil {
+			return FilePatch{}, err
+		}
+		p.i++
+	}
+
+	switch fp.Kind {
+	case ChangeAdded:
+		fp.Path = newPath
+	case ChangeDeleted:
+		fp.Path = oldPath
+	case ChangeRenamed, ChangeCopied:
+		fp.Path, fp.OldPath = newPath, oldPath
+	default:
+		fp.Path = newPath
+	}
+
+	if strings.HasPrefix(p.line(0), "--- ") && strings.HasPrefix(p.line(1), "+++ ") {
+		// The names of the --- and +++ lines are the same as the ones above,
+		// but they are checked, because they are the only ones of plain
+		// unified diffs.
+		names := fp
+		if err := p.parseNames(&names); err != nil {
+			return FilePatch{}, err
+		}
+		if names.Kind != fp.Kind && fp.Kind != ChangeRenamed && fp.Kind != ChangeCopied {
+			return FilePatch{}, p.errorf(-2, "the --- and +++ lines do not match the diff --git line")
+		}
+
+		return fp, p.parseHunks(&fp)
+	}
+
+	return fp, nil
+}
+
+// missingHash returns an empty string for the zeros WritePatch writes as the
+// hash of a missing file, and hash otherwise.
+func missingHash(hash string) string {
+	if strings.Trim(hash, "0") == "" {
+		return ""
+	}
+
+	return hash
+}
+
+// parseNames parses the "--- <old name>" and "+++ <new name>" lines into fp.
+// A name of /dev/null makes fp an added or a deleted file.
+func (p *patchParser) parseNames(fp *FilePatch) error {
+	name := func(l string, prefix string) (string, error) {
+		n := strings.TrimPrefix(l, prefix)
+		// Some tools write the date of the file after a tab.
+		n, _, _ = strings.Cut(n, "\t")
+		if n == devNull {
+			return "", nil
+		}
+
+		_, n, ok := strings.Cut(n, "/")
+		if !ok || n == "" {
+			return "", p.errorf(0, "invalid name in %q", l)
+		}
+
+		return n, nil
+	}
+
+	oldPath, err := name(p.line(0), "--- ")
+	if err != nil {
+		return err
+	}
+	p.i++
+
+	newPath, err := name(p.line(0), "+++ ")
+	if err != nil {
+		return err
+	}
+	p.i++
+
+	switch {
+	case oldPath == "" && newPath == "":
+		return p.errorf(-1, "both names are %v", devNull)
+	case oldPath == "":
+		fp.Kind, fp.Path = ChangeAdded, newPath
+	case newPath == "":
+		fp.Kind, fp.Path = ChangeDeleted, oldPath
+	case fp.Kind == ChangeModified && oldPath != newPath:
+		fp.Kind, fp.Path, fp.OldPath = ChangeRenamed, newPath, oldPath
+	default:
+		fp.Path = newPath
+	}
+
+	return nil
+}
+
+// parseHunks parses the hunks following the --- and +++ lines into fp.
+func (p *patchParser) parseHunks(fp *FilePatch) error {
+	for strings.HasPrefix(p.line(0), "@@ ") {
+		m := hunkHeaderRegexp.FindStringSubmatch(p.line(0))
+		if m == nil {
+			return p.errorf(0, "invalid hunk header %q", p.line(0))
+		}
+
+		var h Hunk
+		for i, n := range []*int{&h.OldStart, &h.OldLines, &h.NewStart, &h.NewLines} {
+			if m[i+1] == "" {
+				*n = 1
+				continue
+			}
+
+			// Larger line numbers are implausible, and they could overflow
+			// when the hunk is moved.
+			v, err := strconv.Atoi(m[i+1])
+			if err != nil || v > maxHunkNumber {
+				return p.errorf(0, "invalid line number %v in hunk header %q", m[i+1], p.line(0))
+			}
+			*n = v
+		}
+		p.i++
+
+		oldLines, newLines := 0, 0
+		for oldLines < h.OldLines || newLines < h.NewLines {
+			if p.i >= len(p.lines) {
+				return p.errorf(0, "the hunk %q ends early", h.Header())
+			}
+
+			l := p.lines[p.i]
+			var kind OpKind
+			switch l[0] {
+			case ' ':
+				kind = OpEqual
+				oldLines++
+				newLines++
+			case '\n':
+				// An empty context line whose space was lost, e.g. by an
+				// email client.
+				kind, l = OpEqual, " \n"
+				oldLines++
+				newLines++
+			case '-':
+				kind = OpDelete
+				oldLines++
+			case '+':
+				kind = OpInsert
+				newLines++
+			case '\\':
+				p.i++
+				if err := noNewLine(&h); err != nil {
+					return p.errorf(-1, "%v", err)
+				}
+				continue
+			default:
+				return p.errorf(0, "unexpected line %q in the hunk %q", strings.TrimSuffix(l, "\n"), h.Header())
+			}
+
+			if oldLines > h.OldLines || newLines > h.NewLines {
+				return p.errorf(0, "the hunk %q has more lines than its header", h.Header())
+			}
+
+			h.Lines = append(h.Lines, Line{Kind: kind, Text: l[1:]})
+			p.i++
+		}
+
+		if strings.HasPrefix(p.line(0), "\\") {
+			if err := noNewLine(&h); err != nil {
+				return p.errorf(0, "%v", err)
+			}
+			p.i++
+		}
+
+		fp.Hunks = append(fp.Hunks, h)
+	}
+
+	return nil
+}
+
+// noNewLine removes the new line of the last line of h, which a
+// "\ No newline at end of file" line follows.
+func noNewLine(h *Hunk) error {
+	if len(h.Lines) == 0 {
+		return errors.New("no line before the missing new line marker")
+	}
+
+	l := &h.Lines[len(h.Lines)-1]
+	l.Text = strings.TrimSuffix(l.Text, "\n")
+	return nil
+}
@@ -0,0 +1,36 @@
+package diff
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePatchRejectsInvalidHunkHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"old start too large", "@@ -2147483648 +1 @@"},
+		{"new start too large", "@@ -1 +99999999999 @@"},
+		{"old lines too large", "@@ -1,2147483648 +1 @@"},
+		{"beyond int64", "@@ -1 +1,99999999999999999999 @@"},
+		{"no numbers", "@@ -a +b @@"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParsePatch(strings.NewReader("--- a/f\n+++ b/f\n" + tt.header + "\n-a\n+b\n"))
+		if !errors.Is(err, ErrCorruptPatch) {
+			t.Errorf("%v: got error %v, want %v", tt.name, err, ErrCorruptPatch)
+		}
+	}
+
+	// The largest line number is still accepted.
+	patches, err := ParsePatch(strings.NewReader("--- a/f\n+++ b/f\n@@ -2147483647 +2147483647 @@\n-a\n+b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := patches[0].Hunks[0]; h.OldStart != maxHunkNumber || h.NewStart != maxHunkNumber {
+		t.Errorf("got hunk %v, want both starts at %v", h.Header(), maxHunkNumber)
+	}
+}
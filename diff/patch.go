@@ -0,0 +1,89 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// abbrevLen is the number of characters hashes are abbreviated to.
+	abbrevLen = 7
+	// devNull is the name of the missing side of an added or deleted file.
+	devNull = "/dev/null"
+)
+
+// WritePatch writes the change c, whose old and new content are a and b, to w
+// in the unified format git uses. The hunks are preceded by a
+// "diff --git a/<path> b/<path>" line, lines describing added and deleted
+// files, renames, copies and mode changes, and an
+// "index <old hash>..<new hash>" line when the content changed.
+// A change of type is written as the deletion of the old file followed by the
+// addition of the new one.
+func WritePatch(w io.Writer, c Change, a []byte, b []byte, opts Options) error {
+	if c.Kind == ChangeTypeChanged {
+		deleted := Change{Kind: ChangeDeleted, Path: c.Path, OldMode: c.OldMode, OldHash: c.OldHash}
+		if err := WritePatch(w, deleted, a, nil, opts); err != nil {
+			return err
+		}
+
+		added := Change{Kind: ChangeAdded, Path: c.Path, NewMode: c.NewMode, NewHash: c.NewHash}
+		return WritePatch(w, added, nil, b, opts)
+	}
+
+	oldName, newName := "a/"+c.SourcePath(), "b/"+c.Path
+
+	var header []string
+	header = append(header, fmt.Sprintf("diff --git %v %v", oldName, newName))
+	index := fmt.Sprintf("index %v..%v", abbrev(c.OldHash), abbrev(c.NewHash))
+	switch c.Kind {
+	case ChangeAdded:
+		oldName = devNull
+		header = append(header, fmt.Sprintf("new file mode %v", c.NewMode), index)
+	case ChangeDeleted:
+		newName = devNull
+		header = append(header, fmt.Sprintf("deleted file mode %v", c.OldMode), index)
+	default:
+		if c.OldMode != c.NewMode {
+			header = append(header, fmt.Sprintf("old mode %v", c.OldMode), fmt.Sprintf("new mode %v", c.NewMode))
+		}
+
+		if c.Kind == ChangeRenamed || c.Kind == ChangeCopied {
+			verb := "rename"
+			if c.Kind == ChangeCopied {
+				verb = "copy"
+			}
+
+			header = append(header, fmt.Sprintf("similarity index %v%%", c.Similarity),
+				fmt.Sprintf("%v from %v", verb, c.OldPath), fmt.Sprintf("%v to %v", verb, c.Path))
+		}
+
+		if c.OldHash != c.NewHash {
+			if c.OldMode == c.NewMode {
+				index = fmt.Sprintf("%v %v", index, c.NewMode)
+			}
+			header = append(header, index)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, l := range header {
+		opts.writeLine(bw, colorMeta, l)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return Unified(w, oldName, newName, a, b, opts)
+}
+
+// abbrev shortens hash to abbrevLen characters. A missing hash is written as
+// zeros.
+func abbrev(hash string) string {
+	if hash == "" {
+		return strings.Repeat("0", abbrevLen)
+	}
+
+	return hash[:min(len(hash), abbrevLen)]
+}
@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"sort"
+)
+
+// Patience returns an edit script which turns a into b, computed with the
+// patience diff algorithm of Bram Cohen. Lines which appear exactly once in
+// both a and b are matched first, keeping the longest sequence of them which
+// is in the same order in both. The lines between these matches are compared
+// the same way, and with Myers when they have no such lines. This keeps
+// unique lines, like function signatures, together, at the cost of not always
+// finding the shortest edit script.
+func Patience(a []string, b []string) []Op {
+	ia, ib := internLines(a, b)
+	return appendPatience(make([]Op, 0, max(len(a), len(b))), ia, ib, 0, 0)
+}
+
+// appendPatience appends the edit script of a and b computed by Patience to
+// ops, see appendMyers.
+func appendPatience(ops []Op, a []int, b []int, aStart int, bStart int) []Op {
+	ops, a, b, aStart, bStart, suffix := trimCommon(ops, a, b, aStart, bStart)
+
+	matches := uniqueMatches(a, b)
+	if len(matches) == 0 {
+		ops = appendMyers(ops, a, b, aStart, bStart)
+		return appendEqual(ops, aStart+len(a), bStart+len(b), suffix)
+	}
+
+	i, j := 0, 0
+	for _, m := range matches {
+		ops = appendPatience(ops, a[i:m.a], b[j:m.b], aStart+i, bStart+j)
+		ops = appendEqual(ops, aStart+m.a, bStart+m.b, 1)
+		i, j = m.a+1, m.b+1
+	}
+	ops = appendPatience(ops, a[i:], b[j:], aStart+i, bStart+j)
+
+	return appendEqual(ops, aStart+len(a), bStart+len(b), suffix)
+}
+
+// match is a pair of equal lines, line a of the old and line b of the new content.
+type match struct {
+	a int
+	b int
+}
+
+// uniqueMatches returns the longest sequence of lines which appear exactly
+// once in both a and b and are in the same order in both, found by patience
+// sorting them by their position in b.
+func uniqueMatches(a []int, b []int) []match {
+	type count struct {
+		a, b int
+		// posA and posB are the last position of the line in a and b.
+		posA, posB int
+	}
+
+	counts := make(map[int]*count)
+	for i, l := range a {
+		c, ok := counts[l]
+		if !ok {
+			c = &count{}
+			counts[l] = c
+		}
+		c.a++
+		c.posA = i
+	}
+	for j, l := range b {
+		if c, ok := counts[l]; ok {
+			c.b++
+			c.posB = j
+		}
+	}
+
+	var unique []match
+	for i, l := range a {
+		if c := counts[l]; c.a == 1 && c.b == 1 {
+			unique = append(unique, match{a: i, b: c.posB})
+		}
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+
+	// tops[k] is the index in unique of the top card of pile k, prev links
+	// each card to the top of the previous pile when it was placed.
+	var tops []int
+	prev := make([]int, len(unique))
+	for i, m := range unique {
+		k := sort.Search(len(tops), func(k int) bool {
+			return unique[tops[k]].b > m.b
+		})
+
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tops[k-1]
+		}
+
+		if k == len(tops) {
+			tops = append(tops, i)
+		} else {
+			tops[k] = i
+		}
+	}
+
+	output := make([]match, len(tops))
+	for i, k := tops[len(tops)-1], len(tops)-1; k >= 0; i, k = prev[i], k-1 {
+		output[k] = unique[i]
+	}
+
+	return output
+}
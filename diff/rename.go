@@ -0,0 +1,341 @@
+package diff
+
+import (
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"bytes"
+	"cmp"
+	"path"
+	"slices"
+)
+
+const (
+	// DefaultRenameThreshold is the similarity in percent a deleted and an
+	// added file need to be detected as a rename.
+	DefaultRenameThreshold = 50
+	// renameLimit limits the number of deleted and added files which are
+	// compared to each other by content, because each pair is compared.
+	renameLimit = 1000
+	// maxChunkLen is the length of the longest chunk content is split into
+	// when files are compared by content.
+	maxChunkLen = 64
+)
+
+// ContentLoader returns the content of the file path with mode and hash on
+// one side of a comparison.
+type ContentLoader func(path string, mode structures.EntryMode, hash string) ([]byte, error)
+
+// LoadBlob is a ContentLoader for a side whose Blobs are in the object database.
+func LoadBlob(_ string, _ structures.EntryMode, hash string) ([]byte, error) {
+	o, err := storage.FetchByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := structures.NewBlobFromB(o.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Content, nil
+}
+
+// LoadWorkingTree is a ContentLoader for a side which is the working tree.
+func LoadWorkingTree(path string, mode structures.EntryMode, _ string) ([]byte, error) {
+	return structures.ReadEntryContent(path, mode)
+}
+
+// RenameOptions controls how DetectRenames pairs files.
+type RenameOptions struct {
+	// Threshold is the similarity in percent a pair of files needs.
+	Threshold int
+	// Copies also detects added files which are copies of modified files,
+	// or of deleted files which were already renamed.
+	Copies bool
+}
+
+// DefaultRenameOptions returns the RenameOptions used unless told otherwise.
+func DefaultRenameOptions() RenameOptions {
+	return RenameOptions{Threshold: DefaultRenameThreshold}
+}
+
+// renamePair is a candidate source and destination for a rename or a copy.
+type renamePair struct {
+	src        int
+	dst        int
+	similarity int
+}
+
+// DetectRenames finds the added files of changes which are renames or copies
+// of other files, and replaces them with a single ChangeRenamed or
+// ChangeCopied change. The deletion of a renamed file is left out.
+//
+// Files with the same hash are paired first without reading their content,
+// preferring files with the same name. The remaining files are compared by
+// content, which loadOld and loadNew return for the old and the new side, and
+// the most similar pairs are taken as long as they reach the threshold.
+// Similarity is the share of the content, split into lines of at most 64
+// bytes, which both files have in common. Empty files and pairs of a symbolic
+// link and a regular file are never paired.
+func DetectRenames(changes []Change, opts RenameOptions, loadOld ContentLoader, loadNew ContentLoader) ([]Change, error) {
+	var srcs, dsts []int
+	for i, c := range changes {
+		switch {
+		case c.Kind == ChangeAdded:
+			dsts = append(dsts, i)
+		case c.Kind == ChangeDeleted, c.Kind == ChangeModified && opts.Copies:
+			srcs = append(srcs, i)
+		}
+	}
+	if len(srcs) == 0 || len(dsts) == 0 {
+		return changes, nil
+	}
+
+	empty, err := structures.Blob{}.ComputeHash()
+	if err != nil {
+		return nil, err
+	}
+
+	r := renamer{changes: changes, opts: opts, emptyHash: empty, renamed: make(map[int]bool), matched: make(map[int]Change)}
+	r.exact(srcs, dsts)
+	if err := r.inexact(srcs, dsts, loadOld, loadNew); err != nil {
+		return nil, err
+	}
+
+	output := make([]Change, 0, len(changes))
+	for i, c := range changes {
+		if r.renamed[i] {
+			continue
+		}
+
+		if m, ok := r.matched[i]; ok {
+			c = m
+		}
+		output = append(output, c)
+	}
+
+	return output, nil
+}
+
+// renamer keeps the state of a single DetectRenames call.
+type renamer struct {
+	changes []Change
+	opts    RenameOptions
+	// emptyHash is the hash of an empty Blob.
+	emptyHash string
+	// renamed holds the sources which are renamed.
+	renamed map[int]bool
+	// matched holds the changes that replace the destinations.
+	matched map[int]Change
+}
+
+// exact pairs destinations and sources with the same hash.
+func (r *renamer) exact(srcs []int, dsts []int) {
+	byHash := make(map[string][]int)
+	for _, s := range srcs {
+		byHash[r.changes[s].OldHash] = append(byHash[r.changes[s].OldHash], s)
+	}
+
+	for _, d := range dsts {
+		candidates := slices.Clone(byHash[r.changes[d].NewHash])
+		slices.SortStableFunc(candidates, func(a, b int) int {
+			return cmp.Compare(r.rank(a, d), r.rank(b, d))
+		})
+
+		for _, s := range candidates {
+			if r.pair(s, d, 100) {
+				break
+			}
+		}
+	}
+}
+
+// rank orders the sources a destination d with the same hash is paired
+// with. Sources which can still be renamed come first, then the ones with
+// the same name as d.
+func (r *renamer) rank(s int, d int) int {
+	rank := 0
+	if !r.canRename(s) {
+		rank += 2
+	}
+	if !r.sameName(s, d) {
+		rank++
+	}
+
+	return rank
+}
+
+// sameName reports whether the source s and the destination d have the same
+// file name, possibly in different directories.
+func (r *renamer) sameName(s int, d int) bool {
+	return path.Base(r.changes[s].Path) == path.Base(r.changes[d].Path)
+}
+
+// inexact pairs the destinations and the sources which are not paired yet by
+// the similarity of their content.
+func (r *renamer) inexact(srcs []int, dsts []int, loadOld ContentLoader, loadNew ContentLoader) error {
+	srcs = slices.DeleteFunc(slices.Clone(srcs), func(s int) bool {
+		return !r.canRename(s) && !r.opts.Copies
+	})
+	dsts = slices.DeleteFunc(slices.Clone(dsts), func(d int) bool {
+		_, ok := r.matched[d]
+		return ok
+	})
+	if len(srcs) == 0 || len(dsts) == 0 || len(srcs)*len(dsts) > renameLimit*renameLimit {
+		return nil
+	}
+
+	load := func(indexes []int, old bool) ([]map[string]int, []int, error) {
+		chunks := make([]map[string]int, len(indexes))
+		sizes := make([]int, len(indexes))
+		for i, ci := range indexes {
+			c := r.changes[ci]
+			var content []byte
+			var err error
+			if old {
+				content, err = loadOld(c.Path, c.OldMode, c.OldHash)
+			} else {
+				content, err = loadNew(c.Path, c.NewMode, c.NewHash)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+
+			chunks[i], sizes[i] = countChunks(content), len(content)
+		}
+
+		return chunks, sizes, nil
+	}
+
+	srcChunks, srcSizes, err := load(srcs, true)
+	if err != nil {
+		return err
+	}
+
+	dstChunks, dstSizes, err := load(dsts, false)
+	if err != nil {
+		return err
+	}
+
+	var pairs []renamePair
+	for i, s := range srcs {
+		for j, d := range dsts {
+			if srcSizes[i] == 0 || dstSizes[j] == 0 || !r.compatible(s, d) {
+				continue
+			}
+
+			// The similarity can not reach the threshold when the sizes differ too much.
+			larger := max(srcSizes[i], dstSizes[j])
+			if min(srcSizes[i], dstSizes[j])*100 < r.opts.Threshold*larger {
+				continue
+			}
+
+			sim := commonChunks(srcChunks[i], dstChunks[j]) * 100 / larger
+			if sim >= r.opts.Threshold {
+				pairs = append(pairs, renamePair{src: s, dst: d, similarity: sim})
+			}
+		}
+	}
+
+	slices.SortStableFunc(pairs, func(a, b renamePair) int {
+		if c := cmp.Compare(b.similarity, a.similarity); c != 0 {
+			return c
+		}
+
+		// Prefer files with the same name when they are equally similar.
+		switch sa, sb := r.sameName(a.src, a.dst), r.sameName(b.src, b.dst); {
+		case sa && !sb:
+			return -1
+		case sb && !sa:
+			return 1
+		}
+
+		return 0
+	})
+
+	for _, p := range pairs {
+		if _, ok := r.matched[p.dst]; ok {
+			continue
+		}
+
+		r.pair(p.src, p.dst, p.similarity)
+	}
+
+	return nil
+}
+
+// canRename reports whether the source s can still be renamed, which is only
+// the case for deleted files which are not renamed already.
+func (r *renamer) canRename(s int) bool {
+	return r.changes[s].Kind == ChangeDeleted && !r.renamed[s]
+}
+
+// compatible reports whether the source s and the destination d can be
+// paired, which is not the case for a symbolic link and a regular file.
+func (r *renamer) compatible(s int, d int) bool {
+	return (r.changes[s].OldMode == structures.ModeSymlink) == (r.changes[d].NewMode == structures.ModeSymlink)
+}
+
+// pair records the destination d as a rename or a copy of the source s and
+// reports whether it did. Empty files are not paired.
+func (r *renamer) pair(s int, d int, similarity int) bool {
+	src, dst := r.changes[s], r.changes[d]
+	if !r.compatible(s, d) || src.OldHash == r.emptyHash {
+		return false
+	}
+
+	kind := ChangeRenamed
+	if !r.canRename(s) {
+		if !r.opts.Copies {
+			return false
+		}
+		kind = ChangeCopied
+	}
+
+	if kind == ChangeRenamed {
+		r.renamed[s] = true
+	}
+	r.matched[d] = Change{
+		Kind:       kind,
+		Path:       dst.Path,
+		OldPath:    src.Path,
+		OldMode:    src.OldMode,
+		OldHash:    src.OldHash,
+		NewMode:    dst.NewMode,
+		NewHash:    dst.NewHash,
+		Similarity: similarity,
+	}
+
+	return true
+}
+
+// countChunks splits content into lines, which are split further into chunks
+// of at most maxChunkLen bytes, and counts how often each chunk appears.
+func countChunks(content []byte) map[string]int {
+	counts := make(map[string]int)
+	for len(content) > 0 {
+		n := min(len(content), maxChunkLen)
+		if i := bytes.IndexByte(content[:n], '\n'); i >= 0 {
+			n = i + 1
+		}
+
+		counts[string(content[:n])]++
+		content = content[n:]
+	}
+
+	return counts
+}
+
+// commonChunks returns the number of bytes of the chunks a and b have in common.
+func commonChunks(a map[string]int, b map[string]int) int {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+
+	common := 0
+	for chunk, n := range a {
+		common += min(n, b[chunk]) * len(chunk)
+	}
+
+	return common
+}
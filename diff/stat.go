@@ -0,0 +1,141 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// statWidth is the width WriteStat keeps its lines in.
+	statWidth = 80
+)
+
+// FileStat is the number of lines added to and deleted from a changed file.
+type FileStat struct {
+	Path    string
+	Added   int
+	Deleted int
+	// Binary is set when either side is binary. Lines are not counted then,
+	// OldSize and NewSize hold the size of each side in bytes instead.
+	Binary  bool
+	OldSize int
+	NewSize int
+}
+
+// NewFileStat counts the lines added and deleted between a and b, the old
+// and the new content of the file path.
+func NewFileStat(path string, a []byte, b []byte, opts Options) FileStat {
+	s := FileStat{Path: path, OldSize: len(a), NewSize: len(b)}
+	if IsBinary(a) || IsBinary(b) {
+		s.Binary = true
+		return s
+	}
+
+	for _, op := range Lines(SplitLines(a), SplitLines(b), opts) {
+		switch op.Kind {
+		case OpDelete:
+			s.Deleted++
+		case OpInsert:
+			s.Added++
+		}
+	}
+
+	return s
+}
+
+// WriteNumStat writes a "<added> TAB <deleted> TAB <path>" line for each of
+// stats. The counts of binary files are written as '-'.
+func WriteNumStat(w io.Writer, stats []FileStat) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range stats {
+		if s.Binary {
+			fmt.Fprintf(bw, "-\t-\t%v\n", s.Path)
+			continue
+		}
+
+		fmt.Fprintf(bw, "%v\t%v\t%v\n", s.Added, s.Deleted, s.Path)
+	}
+
+	return bw.Flush()
+}
+
+// WriteStat writes a " <path> | <changes> <graph>" line for each of stats,
+// where the graph has a '+' for each added and a '-' for each deleted line,
+// scaled down when it does not fit. A summary of all files follows.
+func WriteStat(w io.Writer, stats []FileStat, opts Options) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	nameWidth, countWidth, maxChanges := 0, 0, 0
+	added, deleted := 0, 0
+	for _, s := range stats {
+		nameWidth = max(nameWidth, len(s.Path))
+		if s.Binary {
+			countWidth = max(countWidth, len("Bin"))
+			continue
+		}
+
+		changes := s.Added + s.Deleted
+		countWidth = max(countWidth, len(fmt.Sprint(changes)))
+		maxChanges = max(maxChanges, changes)
+		added += s.Added
+		deleted += s.Deleted
+	}
+
+	graphWidth := max(statWidth-nameWidth-countWidth-5, 10)
+	scale := func(n int) int {
+		if maxChanges <= graphWidth || n == 0 {
+			return n
+		}
+
+		return 1 + n*(graphWidth-1)/maxChanges
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, s := range stats {
+		fmt.Fprintf(bw, " %-*v | ", nameWidth, s.Path)
+		if s.Binary {
+			fmt.Fprintf(bw, "Bin %v -> %v bytes\n", s.OldSize, s.NewSize)
+			continue
+		}
+
+		total := scale(s.Added + s.Deleted)
+		plus := scale(s.Added)
+		minus := max(total-plus, 0)
+		if s.Deleted > 0 {
+			minus = max(minus, 1)
+		}
+
+		fmt.Fprintf(bw, "%*v", countWidth, s.Added+s.Deleted)
+		if plus+minus > 0 {
+			bw.WriteByte(' ')
+		}
+		bw.WriteString(opts.paint(colorNew, strings.Repeat("+", plus)))
+		bw.WriteString(opts.paint(colorOld, strings.Repeat("-", minus)))
+		bw.WriteByte('\n')
+	}
+
+	summary := fmt.Sprintf(" %v changed", plural(len(stats), "file", "files"))
+	if added > 0 || deleted == 0 {
+		summary += fmt.Sprintf(", %v(+)", plural(added, "insertion", "insertions"))
+	}
+	if deleted > 0 || added == 0 {
+		summary += fmt.Sprintf(", %v(-)", plural(deleted, "deletion", "deletions"))
+	}
+	bw.WriteString(summary)
+	bw.WriteByte('\n')
+
+	return bw.Flush()
+}
+
+// plural formats n followed by one or other, depending on n.
+func plural(n int, one string, other string) string {
+	if n == 1 {
+		return fmt.Sprintf("%v %v", n, one)
+	}
+
+	return fmt.Sprintf("%v %v", n, other)
+}
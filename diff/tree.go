@@ -0,0 +1,302 @@
+package diff
+
+import (
+	"armanVersionControl/structures"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChangeKind is the kind of change of a path between two trees.
+type ChangeKind int
+
+const (
+	// ChangeAdded is a path which only exists in the new tree.
+	ChangeAdded ChangeKind = iota
+	// ChangeDeleted is a path which only exists in the old tree.
+	ChangeDeleted
+	// ChangeModified is a path whose content or mode changed.
+	ChangeModified
+	// ChangeTypeChanged is a path which changed between a regular file
+	// and a symbolic link.
+	ChangeTypeChanged
+	// ChangeRenamed is a path which was moved, see DetectRenames.
+	ChangeRenamed
+	// ChangeCopied is a path which was added as a copy of another path,
+	// see DetectRenames.
+	ChangeCopied
+)
+
+// String returns the letter git uses for k in its listings.
+func (k ChangeKind) String() string {
+	return []string{"A", "D", "M", "T", "R", "C"}[k]
+}
+
+// Change is a single changed path between two trees.
+type Change struct {
+	Kind ChangeKind
+	// Path is the name of the entry relative to the root of the trees.
+	Path string
+	// OldMode and OldHash describe the entry in the old tree, they are
+	// zero for added paths.
+	OldMode structures.EntryMode
+	OldHash string
+	// NewMode and NewHash describe the entry in the new tree, they are
+	// zero for deleted paths.
+	NewMode structures.EntryMode
+	NewHash string
+	// OldPath is the path the entry had in the old tree, it is only set for
+	// renamed and copied paths.
+	OldPath string
+	// Similarity is how similar the old and the new content of a renamed or
+	// copied path are, in percent.
+	Similarity int
+}
+
+// Status returns the letter of c.Kind, followed by the similarity for renamed
+// and copied paths, e.g. R075, like git lists them.
+func (c Change) Status() string {
+	if c.Kind == ChangeRenamed || c.Kind == ChangeCopied {
+		return fmt.Sprintf("%v%03d", c.Kind, c.Similarity)
+	}
+
+	return c.Kind.String()
+}
+
+// SourcePath returns the path of c in the old tree.
+func (c Change) SourcePath() string {
+	if c.OldPath != "" {
+		return c.OldPath
+	}
+
+	return c.Path
+}
+
+// CompactName returns the path of c, for renamed and copied paths in the
+// form "<old> => <new>", where the directories both paths start and end with
+// are only written once, e.g. "src/{old => new}/main.go".
+func (c Change) CompactName() string {
+	if c.OldPath == "" {
+		return c.Path
+	}
+
+	old, new := c.OldPath, c.Path
+	prefix := 0
+	for i := 0; i < min(len(old), len(new)) && old[i] == new[i]; i++ {
+		if old[i] == '/' {
+			prefix = i + 1
+		}
+	}
+
+	suffix := 0
+	for i := 1; i <= min(len(old), len(new))-prefix && old[len(old)-i] == new[len(new)-i]; i++ {
+		if old[len(old)-i] == '/' {
+			suffix = i
+		}
+	}
+
+	if prefix == 0 && suffix == 0 {
+		return fmt.Sprintf("%v => %v", old, new)
+	}
+
+	return fmt.Sprintf("%v{%v => %v}%v", old[:prefix], old[prefix:len(old)-suffix], new[prefix:len(new)-suffix], old[len(old)-suffix:])
+}
+
+// TreeOptions controls which changes Trees reports.
+type TreeOptions struct {
+	// Recursive reports the files inside changed subdirectories, instead of
+	// the subdirectories themselves.
+	Recursive bool
+	// Paths limits the changes to these paths and everything under them.
+	// Every path is compared when Paths is empty.
+	Paths []string
+}
+
+// Trees compares the old and the new tree and returns their changes in the
+// canonical order of the trees. A nil tree is treated as an empty one, so
+// everything in the other tree is added or deleted.
+//
+// The trees are walked in parallel and subtrees with the same hash on both
+// sides are skipped without being fetched, so the EntryHash of every entry
+// has to be filled in, which is the case for stored trees and after
+// Tree.ComputeHash. A file replaced by a directory, or the other way around,
+// is reported as the deletion of one and the addition of the other.
+func Trees(old *structures.Tree, new *structures.Tree, opts TreeOptions) ([]Change, error) {
+	d := treeDiff{recursive: opts.Recursive}
+	for _, p := range opts.Paths {
+		p = strings.Trim(p, "/")
+		if p == "" || p == "." {
+			d.paths = nil
+			break
+		}
+
+		d.paths = append(d.paths, p)
+	}
+
+	if err := d.trees("", old, new); err != nil {
+		return nil, err
+	}
+
+	return d.changes, nil
+}
+
+// treeDiff keeps the state of a single Trees call.
+type treeDiff struct {
+	recursive bool
+	paths     []string
+	changes   []Change
+}
+
+// trees compares the entries of old and new, which are the subtrees at the
+// directory prefix. Entries with the same name are compared with each other.
+func (d *treeDiff) trees(prefix string, old *structures.Tree, new *structures.Tree) error {
+	a, b := sortedEntries(old), sortedEntries(new)
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		var oe, ne *structures.TreeEntry
+		switch {
+		case j == len(b) || (i < len(a) && a[i].Name < b[j].Name):
+			oe = a[i]
+			i++
+		case i == len(a) || b[j].Name < a[i].Name:
+			ne = b[j]
+			j++
+		default:
+			oe, ne = a[i], b[j]
+			i++
+			j++
+		}
+
+		if err := d.entries(prefix, oe, ne); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entries compares oe and ne, the entries with the same name in the old and
+// the new tree. Either of them is nil when the name only exists on one side.
+func (d *treeDiff) entries(prefix string, oe *structures.TreeEntry, ne *structures.TreeEntry) error {
+	if oe != nil && ne != nil && oe.Kind != ne.Kind {
+		if err := d.entries(prefix, oe, nil); err != nil {
+			return err
+		}
+
+		return d.entries(prefix, nil, ne)
+	}
+
+	if oe != nil && ne != nil && oe.EntryHash != "" && oe.EntryHash == ne.EntryHash && entryMode(oe) == entryMode(ne) {
+		return nil
+	}
+
+	c := Change{Kind: ChangeModified}
+	te := ne
+	if oe != nil {
+		c.OldMode, c.OldHash = entryMode(oe), oe.EntryHash
+	} else {
+		c.Kind = ChangeAdded
+	}
+	if ne != nil {
+		c.NewMode, c.NewHash = entryMode(ne), ne.EntryHash
+	} else {
+		c.Kind = ChangeDeleted
+		te = oe
+	}
+	c.Path = prefix + te.Name
+
+	matched, onPath := d.match(c.Path)
+	if te.Kind != structures.KindTree {
+		if !matched {
+			return nil
+		}
+
+		if c.Kind == ChangeModified && (c.OldMode == structures.ModeSymlink) != (c.NewMode == structures.ModeSymlink) {
+			c.Kind = ChangeTypeChanged
+		}
+
+		d.changes = append(d.changes, c)
+		return nil
+	}
+
+	if !matched && !onPath {
+		return nil
+	}
+
+	// Without recursion, subdirectories are only entered to reach a path
+	// which is inside them.
+	if matched && !d.recursive {
+		d.changes = append(d.changes, c)
+		return nil
+	}
+
+	ot, err := fetchSubtree(oe)
+	if err != nil {
+		return err
+	}
+
+	nt, err := fetchSubtree(ne)
+	if err != nil {
+		return err
+	}
+
+	return d.trees(c.Path+"/", ot, nt)
+}
+
+// fetchSubtree returns the Tree of the subdirectory te, or nil when te is nil.
+func fetchSubtree(te *structures.TreeEntry) (*structures.Tree, error) {
+	if te == nil {
+		return nil, nil
+	}
+
+	t, err := te.FetchTree()
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// match reports whether name is one of the paths d is limited to or inside
+// one of them, and whether any of those paths is inside name.
+func (d *treeDiff) match(name string) (matched bool, onPath bool) {
+	if len(d.paths) == 0 {
+		return true, false
+	}
+
+	for _, p := range d.paths {
+		if name == p || strings.HasPrefix(name, p+"/") {
+			matched = true
+		}
+		if strings.HasPrefix(p, name+"/") {
+			onPath = true
+		}
+	}
+
+	return matched, onPath
+}
+
+// sortedEntries returns the entries of t in the canonical order, without
+// reordering t itself. It returns nil for a nil t.
+func sortedEntries(t *structures.Tree) []*structures.TreeEntry {
+	if t == nil {
+		return nil
+	}
+
+	return slices.SortedFunc(slices.Values(t.Entries), func(a, b *structures.TreeEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// entryMode returns the mode of te, falling back to the default mode of its
+// kind for entries that were created without a mode.
+func entryMode(te *structures.TreeEntry) structures.EntryMode {
+	if te.Mode != 0 {
+		return te.Mode
+	}
+
+	if te.Kind == structures.KindTree {
+		return structures.ModeTree
+	}
+
+	return structures.ModeRegular
+}
@@ -0,0 +1,210 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// noNewLineMarker follows a line which does not end with a new line.
+	noNewLineMarker = "\\ No newline at end of file\n"
+)
+
+// Line is a line of a Hunk.
+type Line struct {
+	Kind OpKind
+	// Text is the content of the line, including its new line if it has one.
+	Text string
+}
+
+// Hunk is a group of changed lines together with the unchanged lines
+// around them.
+type Hunk struct {
+	// OldStart and NewStart are the 1-based line numbers where the hunk
+	// starts in the old and the new content. When the hunk has no lines
+	// on a side, they are the number of the line before the hunk.
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// Header returns the "@@ -<old start>,<old lines> +<new start>,<new lines> @@"
+// line of h, without a new line. Counts of 1 are omitted.
+func (h Hunk) Header() string {
+	r := func(start int, lines int) string {
+		if lines == 1 {
+			return fmt.Sprint(start)
+		}
+
+		return fmt.Sprintf("%v,%v", start, lines)
+	}
+
+	return fmt.Sprintf("@@ -%v +%v @@", r(h.OldStart, h.OldLines), r(h.NewStart, h.NewLines))
+}
+
+// Hunks groups the edit script ops of a into b into hunks, with context
+// unchanged lines around each change. Changes which are closer than twice
+// the context are put in the same hunk. It returns nil when a and b are equal.
+func Hunks(ops []Op, a []string, b []string, context int) []Hunk {
+	return hunks(ops, a, b, context, nil)
+}
+
+// hunks implements Hunks. Changes for which ignore returns true do not make a
+// hunk on their own, but are shown when they are part of a hunk.
+func hunks(ops []Op, a []string, b []string, context int, ignore func(op Op) bool) []Hunk {
+	context = max(context, 0)
+	isChange := func(op Op) bool {
+		return op.Kind != OpEqual && (ignore == nil || !ignore(op))
+	}
+
+	var hunks []Hunk
+	for i := 0; i < len(ops); {
+		if !isChange(ops[i]) {
+			i++
+			continue
+		}
+
+		// Find the end of this hunk, which is the first change followed by
+		// more than 2*context unchanged lines or by the end.
+		end := i
+		for j := i; j < len(ops); j++ {
+			if isChange(ops[j]) {
+				end = j + 1
+				continue
+			}
+			if j-end >= 2*context {
+				break
+			}
+		}
+
+		start := max(i-context, 0)
+		stop := min(end+context, len(ops))
+		hunks = append(hunks, newHunk(ops[start:stop], a, b, ops[:start]))
+		i = stop
+	}
+
+	return hunks
+}
+
+// newHunk creates a Hunk from ops, before are the ops preceding it which
+// tell where the hunk starts.
+func newHunk(ops []Op, a []string, b []string, before []Op) Hunk {
+	var h Hunk
+	for _, op := range before {
+		if op.A >= 0 {
+			h.OldStart++
+		}
+		if op.B >= 0 {
+			h.NewStart++
+		}
+	}
+
+	for _, op := range ops {
+		switch op.Kind {
+		case OpEqual:
+			h.OldLines++
+			h.NewLines++
+			// Equal lines may differ in what Options ignores, the new one is
+			// shown like git does.
+			h.Lines = append(h.Lines, Line{Kind: OpEqual, Text: b[op.B]})
+		case OpDelete:
+			h.OldLines++
+			h.Lines = append(h.Lines, Line{Kind: OpDelete, Text: a[op.A]})
+		case OpInsert:
+			h.NewLines++
+			h.Lines = append(h.Lines, Line{Kind: OpInsert, Text: b[op.B]})
+		}
+	}
+
+	// Line numbers are 1-based, unless there are no lines on that side.
+	if h.OldLines > 0 {
+		h.OldStart++
+	}
+	if h.NewLines > 0 {
+		h.NewStart++
+	}
+
+	return h
+}
+
+// Compare compares a and b line by line and returns the hunks of their
+// difference, or nil when they are equal apart from what opts ignores.
+func Compare(a []byte, b []byte, opts Options) []Hunk {
+	la, lb := SplitLines(a), SplitLines(b)
+
+	var ignore func(op Op) bool
+	if opts.IgnoreBlankLines {
+		ignore = func(op Op) bool {
+			if op.Kind == OpDelete {
+				return isBlank(la[op.A])
+			}
+
+			return isBlank(lb[op.B])
+		}
+	}
+
+	return hunks(Lines(la, lb, opts), la, lb, opts.Context, ignore)
+}
+
+// WriteHunks writes hunks to w in the unified format. Each hunk starts with
+// its header, followed by its lines prefixed with ' ', '-' or '+'.
+func WriteHunks(w io.Writer, hunks []Hunk) error {
+	return writeHunks(w, hunks, Options{})
+}
+
+// writeHunks is WriteHunks, highlighting the output when opts.Color is set.
+func writeHunks(w io.Writer, hunks []Hunk, opts Options) error {
+	bw := bufio.NewWriter(w)
+	prefixes := map[OpKind]string{OpEqual: " ", OpDelete: "-", OpInsert: "+"}
+	colors := map[OpKind]string{OpEqual: "", OpDelete: colorOld, OpInsert: colorNew}
+	for _, h := range hunks {
+		opts.writeLine(bw, colorFrag, h.Header())
+
+		for _, l := range h.Lines {
+			text, hasNewLine := strings.CutSuffix(l.Text, "\n")
+			opts.writeLine(bw, colors[l.Kind], prefixes[l.Kind]+text)
+			if !hasNewLine {
+				bw.WriteString(noNewLineMarker)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Unified writes the difference of a and b to w in the unified format, with
+// "--- <oldName>" and "+++ <newName>" lines before the hunks. Nothing is
+// written when a and b are equal. When either of them is binary, only a
+// "Binary files <oldName> and <newName> differ" line is written.
+func Unified(w io.Writer, oldName string, newName string, a []byte, b []byte, opts Options) error {
+	if IsBinary(a) || IsBinary(b) {
+		if string(a) == string(b) {
+			return nil
+		}
+
+		_, err := fmt.Fprintf(w, "Binary files %v and %v differ\n", oldName, newName)
+		return err
+	}
+
+	hunks := Compare(a, b, opts)
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	bw := bufio.NewWriter(w)
+	opts.writeLine(bw, colorMeta, "--- "+oldName)
+	opts.writeLine(bw, colorMeta, "+++ "+newName)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	if opts.WordDiff != WordDiffNone {
+		return writeWordHunks(w, hunks, opts)
+	}
+
+	return writeHunks(w, hunks, opts)
+}
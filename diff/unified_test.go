@@ -0,0 +1,100 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		context int
+		want    string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", 3, ""},
+		{"both empty", "", "", 3, ""},
+		{
+			"change with context", "a\nb\nc\nd\ne\nf\ng\n", "a\nb\nc\nX\ne\nf\ng\n", 3,
+			"@@ -1,7 +1,7 @@\n a\n b\n c\n-d\n+X\n e\n f\n g\n",
+		},
+		{
+			"context is cut at the ends", "a\nb\nc\nd\ne\nf\ng\nh\ni\n", "a\nb\nc\nd\nX\nf\ng\nh\ni\n", 1,
+			"@@ -4,3 +4,3 @@\n d\n-e\n+X\n f\n",
+		},
+		{"no context", "a\nb\nc\n", "a\nX\nc\n", 0, "@@ -2 +2 @@\n-b\n+X\n"},
+		{"insert without context", "a\nb\n", "a\nX\nb\n", 0, "@@ -1,0 +2 @@\n+X\n"},
+		{"from empty", "", "a\nb\n", 3, "@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"to empty", "a\nb\n", "", 3, "@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{
+			"no new line at the end", "a\nb", "a\nc", 3,
+			"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+		{"new line added at the end", "a", "a\n", 3, "@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+a\n"},
+		{
+			// Six unchanged lines are exactly twice the context, so the
+			// changes share a hunk.
+			"close changes are merged", "1\nA\n2\n3\n4\n5\n6\n7\nB\n8\n", "1\nX\n2\n3\n4\n5\n6\n7\nY\n8\n", 3,
+			"@@ -1,10 +1,10 @@\n 1\n-A\n+X\n 2\n 3\n 4\n 5\n 6\n 7\n-B\n+Y\n 8\n",
+		},
+		{
+			"distant changes are split", "1\nA\n2\n3\n4\n5\n6\n7\n8\nB\n9\n", "1\nX\n2\n3\n4\n5\n6\n7\n8\nY\n9\n", 3,
+			"@@ -1,5 +1,5 @@\n 1\n-A\n+X\n 2\n 3\n 4\n@@ -7,5 +7,5 @@\n 6\n 7\n 8\n-B\n+Y\n 9\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var sb strings.Builder
+		opts := DefaultOptions()
+		opts.Context = tt.context
+		if err := Unified(&sb, "a", "b", []byte(tt.a), []byte(tt.b), opts); err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+
+		want := tt.want
+		if want != "" {
+			want = "--- a\n+++ b\n" + want
+		}
+		if sb.String() != want {
+			t.Errorf("%v:\ngot\n%v\nwant\n%v", tt.name, sb.String(), want)
+		}
+	}
+}
+
+func TestUnifiedBinary(t *testing.T) {
+	var sb strings.Builder
+	if err := Unified(&sb, "a", "b", []byte("a\x00"), []byte("b\x00"), DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "Binary files a and b differ\n"; sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
+}
+
+func TestHunksRoundTripThroughParsePatch(t *testing.T) {
+	a := []byte("1\nA\n2\n3\n4\n5\n6\n7\n8\nB\n9")
+	b := []byte("1\nX\n2\n3\n4\n5\n6\n7\n8\nY\n9\n10")
+
+	var sb strings.Builder
+	if err := Unified(&sb, "a/f", "b/f", a, b, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	patches, err := ParsePatch(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("got %v patches, want 1", len(patches))
+	}
+
+	got, _, err := Apply(a, patches[0].Hunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(b) {
+		t.Errorf("applying the diff gives %q, want %q", got, b)
+	}
+}
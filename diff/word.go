@@ -0,0 +1,117 @@
+package diff
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// writeWordHunks writes hunks to w like writeHunks, but each group of deleted
+// and added lines is compared word by word and written as the new text, with
+// the deleted words as [-deleted-] and the added ones as {+added+}. With
+// WordDiffColor, the words are only highlighted, without the brackets.
+// Unchanged lines are written as they are, without a prefix.
+func writeWordHunks(w io.Writer, hunks []Hunk, opts Options) error {
+	bw := bufio.NewWriter(w)
+	for _, h := range hunks {
+		opts.writeLine(bw, colorFrag, h.Header())
+
+		for i := 0; i < len(h.Lines); {
+			if h.Lines[i].Kind == OpEqual {
+				writeText(bw, h.Lines[i].Text)
+				i++
+				continue
+			}
+
+			var old, new strings.Builder
+			for ; i < len(h.Lines) && h.Lines[i].Kind != OpEqual; i++ {
+				if h.Lines[i].Kind == OpDelete {
+					old.WriteString(h.Lines[i].Text)
+				} else {
+					new.WriteString(h.Lines[i].Text)
+				}
+			}
+
+			var sb strings.Builder
+			opts.writeWords(&sb, old.String(), new.String())
+			writeText(bw, sb.String())
+		}
+	}
+
+	return bw.Flush()
+}
+
+// writeText writes text to bw and makes sure it ends with a new line.
+func writeText(bw *bufio.Writer, text string) {
+	bw.WriteString(text)
+	if !strings.HasSuffix(text, "\n") {
+		bw.WriteByte('\n')
+	}
+}
+
+// writeWords compares the words of old and new and writes the new text with
+// the changed words marked to sb.
+func (opts Options) writeWords(sb *strings.Builder, old string, new string) {
+	a, b := splitWords(old), splitWords(new)
+	ops := opts.Algorithm.diff(a, b)
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == OpEqual {
+			sb.WriteString(b[ops[i].B])
+			i++
+			continue
+		}
+
+		var deleted, added strings.Builder
+		for ; i < len(ops) && ops[i].Kind != OpEqual; i++ {
+			if ops[i].Kind == OpDelete {
+				deleted.WriteString(a[ops[i].A])
+			} else {
+				added.WriteString(b[ops[i].B])
+			}
+		}
+
+		opts.markWords(sb, deleted.String(), "[-", "-]", colorOld)
+		opts.markWords(sb, added.String(), "{+", "+}", colorNew)
+	}
+}
+
+// markWords writes text to sb between open and close, or highlighted with
+// color for WordDiffColor. New lines are kept outside of the marks, so every
+// line of text is marked on its own.
+func (opts Options) markWords(sb *strings.Builder, text string, open string, close string, color string) {
+	for text != "" {
+		line, rest, hasNewLine := strings.Cut(text, "\n")
+		text = rest
+
+		if line != "" {
+			if opts.WordDiff == WordDiffColor {
+				sb.WriteString(color + line + colorReset)
+			} else {
+				sb.WriteString(opts.paint(color, open+line+close))
+			}
+		}
+		if hasNewLine {
+			sb.WriteByte('\n')
+		}
+	}
+}
+
+// splitWords splits text into words, which are runs of anything but
+// whitespace, and the runs of whitespace between them.
+func splitWords(text string) []string {
+	var words []string
+	start, space := 0, false
+	for i, r := range text {
+		if i > start && unicode.IsSpace(r) != space {
+			words = append(words, text[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+
+	return words
+}
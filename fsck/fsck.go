@@ -0,0 +1,257 @@
+package fsck
+
+import (
+	"armanVersionControl/reachability"
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"armanVersionControl/track"
+	"errors"
+	"fmt"
+)
+
+// Options controls how thoroughly Check verifies the object database.
+type Options struct {
+	// Full rehashes blobs as well as trees and commits. Blobs are usually
+	// much larger than the other objects, so without Full only their header
+	// is verified.
+	Full bool
+	// ConnectivityOnly only verifies that objects exist, have the kind they
+	// are referenced as and that their links are not broken. Objects are not
+	// rehashed and blobs are never read beyond their header. It overrides Full.
+	ConnectivityOnly bool
+}
+
+// Object is an object in the object database together with its kind.
+type Object struct {
+	Hash string
+	Kind structures.ObjectKind
+}
+
+// Problem is an inconsistency found by Check.
+type Problem struct {
+	// Hash is the object the problem was found in, it is empty
+	// when the problem is in the index or a ref.
+	Hash string
+	// Message describes the problem.
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Message
+}
+
+// Result is the outcome of Check.
+type Result struct {
+	// Problems are the inconsistencies found, Check found the object
+	// database and the index healthy when it is empty.
+	Problems []Problem
+	// Unreachable are objects which can not be reached from HEAD, any ref,
+	// the index or any reflog.
+	Unreachable []Object
+	// Dangling are unreachable objects which are not referenced by any
+	// other object, they are the starting points of unreachable history.
+	Dangling []Object
+}
+
+// link is a reference from one object to another.
+type link struct {
+	hash string
+	kind structures.ObjectKind
+	// name describes the link, e.g. the name of a tree entry.
+	name string
+}
+
+// node is an object of the object database that Check has read.
+type node struct {
+	kind  structures.ObjectKind
+	links []link
+}
+
+// Check verifies every object in the object database and the index. Each
+// object is rehashed and compared with its name, decoded and its links are
+// checked to point at existing objects of the right kind. Objects which are
+// not reachable from any of reachability.Roots are reported as unreachable.
+func Check(opts Options) (Result, error) {
+	names, err := storage.FetchAllObjectNames()
+	if err != nil {
+		return Result{}, err
+	}
+
+	var res Result
+	report := func(hash string, format string, a ...any) {
+		res.Problems = append(res.Problems, Problem{Hash: hash, Message: fmt.Sprintf(format, a...)})
+	}
+
+	nodes := make(map[string]*node, len(names))
+	for _, name := range names {
+		if err := storage.ValidateHash(name, false); err != nil {
+			report(name, "invalid object name %v", name)
+			continue
+		}
+
+		n, err := checkObject(name, opts)
+		if n != nil {
+			nodes[name] = n
+		}
+		if err != nil {
+			if n != nil {
+				report(name, "error in %v %v: %v", n.kind, name, err)
+			} else {
+				report(name, "error in object %v: %v", name, err)
+			}
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, name := range names {
+		n, ok := nodes[name]
+		if !ok {
+			continue
+		}
+
+		for _, l := range n.links {
+			referenced[l.hash] = true
+
+			target, ok := nodes[l.hash]
+			switch {
+			case !ok:
+				report(name, "broken link from %v %v to %v %v (%v)", n.kind, name, l.kind, l.hash, l.name)
+			case target.kind != l.kind:
+				report(name, "%v %v points to %v as a %v, but it is a %v (%v)", n.kind, name, l.hash, l.kind, target.kind, l.name)
+			}
+		}
+	}
+
+	validateIndex(nodes, report)
+
+	roots, err := reachability.Roots()
+	if err != nil {
+		// Without roots every object would be reported as unreachable.
+		report("", "can not compute reachability: %v", err)
+		return res, nil
+	}
+
+	var stack []string
+	for _, r := range roots {
+		if _, ok := nodes[r.Hash]; !ok {
+			report("", "%v points to missing object %v", r.Source, r.Hash)
+		}
+
+		stack = append(stack, r.Hash)
+	}
+
+	reachable := make(map[string]bool)
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[h] {
+			continue
+		}
+		reachable[h] = true
+
+		if n, ok := nodes[h]; ok {
+			for _, l := range n.links {
+				stack = append(stack, l.hash)
+			}
+		}
+	}
+
+	for _, name := range names {
+		n, ok := nodes[name]
+		if !ok || reachable[name] {
+			continue
+		}
+
+		o := Object{Hash: name, Kind: n.kind}
+		res.Unreachable = append(res.Unreachable, o)
+		if !referenced[name] {
+			res.Dangling = append(res.Dangling, o)
+		}
+	}
+
+	return res, nil
+}
+
+// checkObject reads and verifies the object with hash. The returned node is
+// nil when not even the kind of the object could be identified.
+func checkObject(hash string, opts Options) (*node, error) {
+	if opts.ConnectivityOnly {
+		_, info, err := structures.FetchInfo(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		n := &node{kind: info.Kind}
+		if info.Kind == structures.ObjectBlob {
+			return n, nil
+		}
+	}
+
+	o, err := storage.FetchByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := structures.Identify(o.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &node{kind: info.Kind}
+	if !opts.ConnectivityOnly && (opts.Full || info.Kind != structures.ObjectBlob) {
+		h, err := storage.ComputeHash(o.Content)
+		if err != nil {
+			return n, err
+		}
+		if h != hash {
+			return n, fmt.Errorf("hash mismatch, content hashes to %v", h)
+		}
+	}
+
+	obj, err := structures.Decode(o)
+	if err != nil {
+		return n, err
+	}
+
+	switch v := obj.(type) {
+	case structures.Tree:
+		for _, te := range v.Entries {
+			kind := structures.ObjectBlob
+			if te.Kind == structures.KindTree {
+				kind = structures.ObjectTree
+			}
+
+			n.links = append(n.links, link{hash: te.EntryHash, kind: kind, name: fmt.Sprintf("entry %q", te.Name)})
+		}
+	case structures.Commit:
+		n.links = append(n.links, link{hash: v.TreeHash, kind: structures.ObjectTree, name: "tree"})
+		for _, p := range v.ParentHashes {
+			n.links = append(n.links, link{hash: p, kind: structures.ObjectCommit, name: "parent"})
+		}
+	}
+
+	return n, nil
+}
+
+// validateIndex validates the index and checks that its entries point to
+// blobs. Missing blobs are reported along with the other missing roots.
+func validateIndex(nodes map[string]*node, report func(hash string, format string, a ...any)) {
+	index, err := track.FetchIndex()
+	if err != nil {
+		if !errors.Is(err, track.ErrIndexNotFound) {
+			report("", "error in index: %v", err)
+		}
+
+		return
+	}
+
+	if err = index.Validate(); err != nil {
+		report("", "error in index: %v", err)
+	}
+
+	for _, ie := range index.Entries {
+		if n, ok := nodes[ie.EntryHash]; ok && n.kind != structures.ObjectBlob {
+			report("", "index entry %q points to %v which is a %v", ie.Name, ie.EntryHash, n.kind)
+		}
+	}
+}
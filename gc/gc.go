@@ -0,0 +1,114 @@
+package gc
+
+import (
+	"armanVersionControl/reachability"
+	"armanVersionControl/storage"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPruneExpire is used when gc.pruneExpire is not set.
+	DefaultPruneExpire = "2w"
+)
+
+// Options controls which unreachable objects Prune removes.
+type Options struct {
+	// ExpireBefore is the grace period of unreachable objects, only the
+	// ones written before it are removed. Recently written objects might be
+	// about to be referenced by a command which is still running, e.g. the
+	// Blobs of an add whose index is not saved yet. The zero time removes nothing.
+	ExpireBefore time.Time
+	// DryRun only reports the objects which would be removed.
+	DryRun bool
+}
+
+// Prune removes the loose objects which are not reachable from any ref, the
+// index or any reflog and were written before opts.ExpireBefore. It returns
+// the hashes of the removed objects. Nothing is removed when a reachable
+// object is missing or corrupt, because then reachability can not be trusted.
+//
+// avc stores every object as a loose object and has no pack files yet, so
+// there is nothing to repack before pruning.
+func Prune(opts Options) ([]string, error) {
+	roots, err := reachability.Roots()
+	if err != nil {
+		return nil, err
+	}
+
+	reachable, err := reachability.Reachable(roots)
+	if err != nil {
+		return nil, fmt.Errorf("refusing to prune: %w", err)
+	}
+
+	names, err := storage.FetchAllObjectNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []string
+	for _, name := range names {
+		if reachable[name] {
+			continue
+		}
+
+		mtime, err := storage.ObjectModTime(name)
+		if err != nil {
+			return pruned, err
+		}
+		if !mtime.Before(opts.ExpireBefore) {
+			continue
+		}
+
+		if !opts.DryRun {
+			if err = storage.Remove(name); err != nil {
+				return pruned, err
+			}
+		}
+
+		pruned = append(pruned, name)
+	}
+
+	if !opts.DryRun {
+		// Leftovers of interrupted object writes expire like unreachable objects.
+		if _, err = storage.RemoveTempObjects(opts.ExpireBefore); err != nil {
+			return pruned, err
+		}
+	}
+
+	return pruned, nil
+}
+
+// ParseExpire parses an expiry, which is either "now", "never" or a duration
+// such as "2w", "14d" or "36h", and returns the time before which objects
+// are expired relative to now.
+func ParseExpire(s string, now time.Time) (time.Time, error) {
+	switch s {
+	case "now":
+		// Objects written in the current second are expired as well.
+		return now.Add(time.Second), nil
+	case "never":
+		return time.Time{}, nil
+	}
+
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			i, err := strconv.Atoi(n)
+			if err != nil || i < 0 {
+				return time.Time{}, fmt.Errorf("invalid expiry %q", s)
+			}
+
+			return now.Add(-time.Duration(i) * unit), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid expiry %q, it should be now, never or a duration such as 2w, 14d or 36h", s)
+	}
+
+	return now.Add(-d), nil
+}
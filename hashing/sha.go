@@ -1,10 +1,85 @@
 package hashing
 
-import "crypto/sha1"
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+)
 
-// Sha1 will generate a sha1 hash from b
-func Sha1(b []byte) []byte {
-	h := sha1.New()
+// Algorithm is a hash function that objects are identified with. A
+// repository uses a single Algorithm for all of its objects, because the
+// same content has a different hash with each Algorithm.
+type Algorithm struct {
+	name string
+	size int
+	new  func() hash.Hash
+}
+
+var (
+	// SHA1 detects crafted collisions, see newSHA1DC.
+	SHA1   = Algorithm{name: "sha1", size: sha1.Size, new: newSHA1DC}
+	SHA256 = Algorithm{name: "sha256", size: sha256.Size, new: sha256.New}
+
+	// Default is the Algorithm of repositories that did not choose one.
+	Default = SHA1
+
+	algorithms = []Algorithm{SHA1, SHA256}
+)
+
+// ParseAlgorithm returns the Algorithm with name, e.g. sha1 or sha256.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for _, a := range algorithms {
+		if a.name == name {
+			return a, nil
+		}
+	}
+
+	return Algorithm{}, fmt.Errorf("unknown hash algorithm %q, it should be sha1 or sha256", name)
+}
+
+// Name returns the name of a, e.g. sha1.
+func (a Algorithm) Name() string {
+	return a.name
+}
+
+func (a Algorithm) String() string {
+	return a.name
+}
+
+// Size returns the length of a hash in bytes.
+func (a Algorithm) Size() int {
+	return a.size
+}
+
+// HexLen returns the length of a hash in hexadecimal, which is how hashes
+// are printed and parsed.
+func (a Algorithm) HexLen() int {
+	return a.size * 2
+}
+
+// New returns a new hash.Hash computing a.
+func (a Algorithm) New() hash.Hash {
+	return a.new()
+}
+
+// Sum will generate a hash of b with a. It fails with ErrCollisionAttack when
+// b is part of a crafted collision of a, and the returned hash is then the one
+// a computes in that case, which differs from the colliding hash.
+func (a Algorithm) Sum(b []byte) ([]byte, error) {
+	h := a.new()
 	h.Write(b)
-	return h.Sum(nil)
+	sum := h.Sum(nil)
+
+	if cd, ok := h.(CollisionDetector); ok && cd.CollisionDetected() {
+		return sum, ErrCollisionAttack
+	}
+
+	return sum, nil
+}
+
+// Sha1 will generate a sha1 hash from b, with collision detection.
+func Sha1(b []byte) []byte {
+	sum, _ := SHA1.Sum(b)
+	return sum
 }
@@ -0,0 +1,352 @@
+package hashing
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/bits"
+)
+
+// This file implements SHA-1 with collision detection, in the same way as
+// sha1dc by Marc Stevens which git uses (https://github.com/cr-marcstevens/sha1collisiondetection).
+//
+// All known practical SHA-1 collision attacks, e.g. SHAttered, build near-collision
+// blocks from one of a small set of disturbance vectors. For each block and each
+// of these disturbance vectors, the message differences the attack would need are
+// applied to the block and the compression is recomputed from an intermediate
+// state. If this yields the same output as the real compression, the block is one
+// half of a collision crafted with that disturbance vector. Ordinary data never
+// triggers this, because that would itself be a SHA-1 collision.
+//
+// Like sha1dc, only the disturbance vectors whose unavoidable bit conditions are
+// met by the message are recompressed, see ubcCheck. This is still about ten
+// times slower than crypto/sha1, which uses assembly, see BenchmarkSHA1DC, so
+// repositories which can afford a new format should prefer SHA-256.
+
+var (
+	ErrCollisionAttack = errors.New("sha1 collision attack detected")
+)
+
+// CollisionDetector is implemented by the hash.Hash of an Algorithm which
+// detects crafted collisions.
+type CollisionDetector interface {
+	// CollisionDetected reports whether any block written so far is
+	// part of a crafted collision.
+	CollisionDetected() bool
+}
+
+// disturbanceVector describes the message differences of a near-collision attack.
+type disturbanceVector struct {
+	// testt is the step whose state the compression is recomputed from.
+	testt int
+	// dm is the difference of each expanded message word.
+	dm [80]uint32
+}
+
+// disturbanceVectors are the ones sha1dc checks, named I(K,b) and II(K,b)
+// after their type, the step K where they start and the bit b they disturb.
+var disturbanceVectors = func() []disturbanceVector {
+	type dv struct{ typ, k, b int }
+	var list []dv
+	for k := 43; k <= 52; k++ {
+		list = append(list, dv{1, k, 0})
+		if k >= 46 && k <= 51 {
+			list = append(list, dv{1, k, 2})
+		}
+	}
+	for k := 45; k <= 56; k++ {
+		list = append(list, dv{2, k, 0})
+		if k == 46 || (k >= 49 && k <= 51) {
+			list = append(list, dv{2, k, 2})
+		}
+	}
+
+	output := make([]disturbanceVector, 0, len(list))
+	for _, d := range list {
+		testt := 58
+		if d.k >= 50 {
+			testt = 65
+		}
+
+		output = append(output, disturbanceVector{testt: testt, dm: messageDifferences(d.typ, d.k, d.b)})
+	}
+
+	return output
+}()
+
+// messageDifferences computes the message differences of the disturbance vector
+// of type typ starting at step k and disturbing bit b.
+//
+// A disturbance vector is a sequence of words which, like an expanded message,
+// satisfies the message expansion of SHA-1. Words k to k+15 of it are zero,
+// except the last one which is 2^b, and type II also has 2^(b+31) in words k+1
+// and k+3. The rest of the words follow from the expansion in both directions.
+// Each disturbed bit starts a local collision, which is cancelled by differences
+// in the next five message words, rotated like the state words they affect.
+func messageDifferences(typ int, k int, b int) [80]uint32 {
+	// Five words before step 0 are needed for the local collisions.
+	const before = 5
+	var v [80 + before]uint32
+	at := func(i int) *uint32 { return &v[i+before] }
+
+	*at(k + 15) = 1 << b
+	if typ == 2 {
+		*at(k + 1) = bits.RotateLeft32(1, 31+b)
+		*at(k + 3) = bits.RotateLeft32(1, 31+b)
+	}
+
+	for i := k + 16; i < 80; i++ {
+		*at(i) = bits.RotateLeft32(*at(i - 3)^*at(i - 8)^*at(i - 14)^*at(i - 16), 1)
+	}
+	for i := k - 1; i >= -before; i-- {
+		*at(i) = bits.RotateLeft32(*at(i + 16), -1) ^ *at(i + 13) ^ *at(i + 8) ^ *at(i + 2)
+	}
+
+	var dm [80]uint32
+	for i := range dm {
+		dm[i] = *at(i) ^ bits.RotateLeft32(*at(i - 1), 5) ^ *at(i - 2) ^
+			bits.RotateLeft32(*at(i - 3), 30) ^ bits.RotateLeft32(*at(i - 4), 30) ^ bits.RotateLeft32(*at(i - 5), 30)
+	}
+
+	return dm
+}
+
+const (
+	sha1BlockSize = 64
+	sha1Init0     = 0x67452301
+	sha1Init1     = 0xEFCDAB89
+	sha1Init2     = 0x98BADCFE
+	sha1Init3     = 0x10325476
+	sha1Init4     = 0xC3D2E1F0
+)
+
+// sha1State is the working state a, b, c, d and e of the compression.
+type sha1State [5]uint32
+
+// sha1dc is a hash.Hash computing SHA-1 with collision detection.
+type sha1dc struct {
+	ihv       sha1State
+	buf       [sha1BlockSize]byte
+	nbuf      int
+	length    uint64
+	collision bool
+}
+
+// newSHA1DC returns a new hash.Hash computing SHA-1 with collision detection.
+// When a collision attack is detected, the hash of the rest of the content is
+// computed differently, like sha1dc does in its safe hash mode, so the two
+// halves of a crafted collision do not end up with the same hash.
+func newSHA1DC() hash.Hash {
+	d := &sha1dc{}
+	d.Reset()
+	return d
+}
+
+func (d *sha1dc) Reset() {
+	d.ihv = sha1State{sha1Init0, sha1Init1, sha1Init2, sha1Init3, sha1Init4}
+	d.nbuf = 0
+	d.length = 0
+	d.collision = false
+}
+
+func (d *sha1dc) Size() int {
+	return SHA1.size
+}
+
+func (d *sha1dc) BlockSize() int {
+	return sha1BlockSize
+}
+
+func (d *sha1dc) CollisionDetected() bool {
+	return d.collision
+}
+
+func (d *sha1dc) Write(p []byte) (int, error) {
+	n := len(p)
+	d.length += uint64(n)
+
+	if d.nbuf > 0 {
+		c := copy(d.buf[d.nbuf:], p)
+		d.nbuf += c
+		p = p[c:]
+		if d.nbuf < sha1BlockSize {
+			return n, nil
+		}
+
+		d.block(d.buf[:])
+		d.nbuf = 0
+	}
+
+	for len(p) >= sha1BlockSize {
+		d.block(p[:sha1BlockSize])
+		p = p[sha1BlockSize:]
+	}
+	d.nbuf = copy(d.buf[:], p)
+
+	return n, nil
+}
+
+func (d *sha1dc) Sum(in []byte) []byte {
+	// Padding is written to a copy, so d can still be written to.
+	c := *d
+
+	var pad [sha1BlockSize + 8]byte
+	pad[0] = 0x80
+	padLen := sha1BlockSize - int((c.length+8)%sha1BlockSize)
+	binary.BigEndian.PutUint64(pad[padLen:], c.length*8)
+	c.Write(pad[:padLen+8])
+
+	for _, v := range c.ihv {
+		in = binary.BigEndian.AppendUint32(in, v)
+	}
+
+	// Remember a collision in the padding, so Sum does not hide it.
+	d.collision = c.collision
+	return in
+}
+
+// block compresses a single block of p and checks it for collision attacks.
+func (d *sha1dc) block(p []byte) {
+	var w [80]uint32
+	for i := range 16 {
+		w[i] = binary.BigEndian.Uint32(p[i*4:])
+	}
+	for i := 16; i < 80; i++ {
+		w[i] = bits.RotateLeft32(w[i-3]^w[i-8]^w[i-14]^w[i-16], 1)
+	}
+
+	ihvIn := d.ihv
+	s58, s65, out := sha1Compress(ihvIn, &w)
+	d.ihv = out.add(ihvIn)
+
+	// Usually no disturbance vector is left to recompress.
+	for mask := ubcCheck(&w); mask != 0; mask &= mask - 1 {
+		dv := &disturbanceVectors[bits.TrailingZeros32(mask)]
+		var w2 [80]uint32
+		for j := range w2 {
+			w2[j] = w[j] ^ dv.dm[j]
+		}
+
+		state := s58
+		if dv.testt == 65 {
+			state = s65
+		}
+
+		if sha1Recompress(dv.testt, &w2, state) == d.ihv {
+			d.collision = true
+			// Like sha1dc, compress the block twice more, so the hash differs
+			// from the hash of the other half of the collision.
+			for range 2 {
+				d.ihv = sha1Forward(d.ihv, &w, 0, 80).add(d.ihv)
+			}
+			return
+		}
+	}
+}
+
+// add returns the word-wise sum of s and o.
+func (s sha1State) add(o sha1State) sha1State {
+	for i := range s {
+		s[i] += o[i]
+	}
+
+	return s
+}
+
+// sha1Compress computes all steps of the compression of the expanded message
+// w on s, and returns the states before steps 58 and 65, which are the only
+// ones recompressed from, and after the last step. The loops have constant
+// bounds, unlike the ones of sha1Forward, so w is indexed without checks.
+func sha1Compress(s sha1State, w *[80]uint32) (s58 sha1State, s65 sha1State, out sha1State) {
+	a, b, c, d, e := s[0], s[1], s[2], s[3], s[4]
+	for i := 0; i < 20; i++ {
+		t := bits.RotateLeft32(a, 5) + (b&c | ^b&d) + e + 0x5A827999 + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	for i := 20; i < 40; i++ {
+		t := bits.RotateLeft32(a, 5) + (b ^ c ^ d) + e + 0x6ED9EBA1 + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	for i := 40; i < 58; i++ {
+		t := bits.RotateLeft32(a, 5) + (b&c | b&d | c&d) + e + 0x8F1BBCDC + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	s58 = sha1State{a, b, c, d, e}
+	for i := 58; i < 60; i++ {
+		t := bits.RotateLeft32(a, 5) + (b&c | b&d | c&d) + e + 0x8F1BBCDC + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	for i := 60; i < 65; i++ {
+		t := bits.RotateLeft32(a, 5) + (b ^ c ^ d) + e + 0xCA62C1D6 + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	s65 = sha1State{a, b, c, d, e}
+	for i := 65; i < 80; i++ {
+		t := bits.RotateLeft32(a, 5) + (b ^ c ^ d) + e + 0xCA62C1D6 + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+
+	return s58, s65, sha1State{a, b, c, d, e}
+}
+
+// sha1Forward computes steps from up to, but not including, to of the
+// compression of the expanded message w on s, the state before step from.
+func sha1Forward(s sha1State, w *[80]uint32, from int, to int) sha1State {
+	a, b, c, d, e := s[0], s[1], s[2], s[3], s[4]
+	i := from
+	for ; i < min(to, 20); i++ {
+		t := bits.RotateLeft32(a, 5) + (b&c | ^b&d) + e + 0x5A827999 + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	for ; i < min(to, 40); i++ {
+		t := bits.RotateLeft32(a, 5) + (b ^ c ^ d) + e + 0x6ED9EBA1 + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	for ; i < min(to, 60); i++ {
+		t := bits.RotateLeft32(a, 5) + (b&c | b&d | c&d) + e + 0x8F1BBCDC + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	for ; i < to; i++ {
+		t := bits.RotateLeft32(a, 5) + (b ^ c ^ d) + e + 0xCA62C1D6 + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+
+	return sha1State{a, b, c, d, e}
+}
+
+// sha1Backward undoes the steps before step from, down to and including to,
+// of the compression of the expanded message w on s, the state before step
+// from, and returns the state before step to.
+func sha1Backward(s sha1State, w *[80]uint32, from int, to int) sha1State {
+	a, b, c, d, e := s[0], s[1], s[2], s[3], s[4]
+	i := from - 1
+	for ; i >= max(to, 60); i-- {
+		t := a
+		a, b, c, d = b, bits.RotateLeft32(c, -30), d, e
+		e = t - bits.RotateLeft32(a, 5) - (b ^ c ^ d) - 0xCA62C1D6 - w[i]
+	}
+	for ; i >= max(to, 40); i-- {
+		t := a
+		a, b, c, d = b, bits.RotateLeft32(c, -30), d, e
+		e = t - bits.RotateLeft32(a, 5) - (b&c | b&d | c&d) - 0x8F1BBCDC - w[i]
+	}
+	for ; i >= max(to, 20); i-- {
+		t := a
+		a, b, c, d = b, bits.RotateLeft32(c, -30), d, e
+		e = t - bits.RotateLeft32(a, 5) - (b ^ c ^ d) - 0x6ED9EBA1 - w[i]
+	}
+	for ; i >= to; i-- {
+		t := a
+		a, b, c, d = b, bits.RotateLeft32(c, -30), d, e
+		e = t - bits.RotateLeft32(a, 5) - (b&c | ^b&d) - 0x5A827999 - w[i]
+	}
+
+	return sha1State{a, b, c, d, e}
+}
+
+// sha1Recompress computes the output of the compression of w, given the state
+// before step t. The input ihv is recovered by undoing the steps before t.
+func sha1Recompress(t int, w *[80]uint32, state sha1State) sha1State {
+	ihv := sha1Backward(state, w, t, 0)
+	return sha1Forward(state, w, t, 80).add(ihv)
+}
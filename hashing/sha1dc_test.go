@@ -0,0 +1,106 @@
+package hashing
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// readShattered returns the first 320 bytes of shattered-n.pdf from
+// https://shattered.io. Those of shattered-1.pdf and shattered-2.pdf have the
+// same SHA-1 hash, they only differ in their last two blocks.
+func readShattered(t *testing.T, n int) []byte {
+	b, err := os.ReadFile(fmt.Sprintf("testdata/shattered-%d.bin", n))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestSHA1DCMatchesSHA1(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 55, 56, 63, 64, 65, 119, 128, 1000, 4096, 100000} {
+		b := make([]byte, n)
+		r.Read(b)
+
+		want := sha1.Sum(b)
+		got, err := SHA1.Sum(b)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", n, err)
+		}
+		if !bytes.Equal(got, want[:]) {
+			t.Errorf("%d bytes: got %x, want %x", n, got, want)
+		}
+
+		// Writing in pieces which do not line up with blocks.
+		h := SHA1.New()
+		for rest := b; len(rest) > 0; {
+			k := min(len(rest), 1+r.Intn(100))
+			h.Write(rest[:k])
+			rest = rest[k:]
+		}
+		if got = h.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Errorf("%d bytes written in pieces: got %x, want %x", n, got, want)
+		}
+	}
+}
+
+func TestSHA1DCDetectsSHAttered(t *testing.T) {
+	shattered1, shattered2 := readShattered(t, 1), readShattered(t, 2)
+	if sha1.Sum(shattered1) != sha1.Sum(shattered2) {
+		t.Fatal("the SHAttered prefixes do not collide")
+	}
+
+	sum1, err := SHA1.Sum(shattered1)
+	if err != ErrCollisionAttack {
+		t.Errorf("shattered-1: got error %v, want %v", err, ErrCollisionAttack)
+	}
+
+	sum2, err := SHA1.Sum(shattered2)
+	if err != ErrCollisionAttack {
+		t.Errorf("shattered-2: got error %v, want %v", err, ErrCollisionAttack)
+	}
+
+	if bytes.Equal(sum1, sum2) {
+		t.Errorf("both halves of the collision hash to %x", sum1)
+	}
+
+	// The blocks before the collision are ordinary.
+	if _, err = SHA1.Sum(shattered1[:192]); err != nil {
+		t.Errorf("the shared prefix: %v", err)
+	}
+}
+
+func TestSHA1DCWriteDoesNotAllocate(t *testing.T) {
+	data := make([]byte, 1<<16)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	h := newSHA1DC()
+	if n := testing.AllocsPerRun(10, func() { h.Write(data) }); n != 0 {
+		t.Errorf("writing %v bytes allocates %v times", len(data), n)
+	}
+}
+
+func BenchmarkSHA1DC(b *testing.B) {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for range b.N {
+		SHA1.Sum(data)
+	}
+}
+
+func BenchmarkCryptoSHA1(b *testing.B) {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for range b.N {
+		sha1.Sum(data)
+	}
+}
@@ -0,0 +1,216 @@
+package hashing
+
+// The unavoidable bit conditions below are taken from ubc_check.c of sha1dc by
+// Marc Stevens and Dan Shumow (https://github.com/cr-marcstevens/sha1collisiondetection),
+// which is distributed under the MIT license.
+//
+// A block which is part of a collision attack with a disturbance vector has
+// to satisfy certain conditions on the bits of its expanded message, no matter
+// how the attack was done. Ordinary blocks meet all conditions of a disturbance
+// vector very rarely, so only the disturbance vectors whose conditions hold
+// have to be recompressed. About one in twenty random blocks has one left.
+
+// The bits of the disturbance vectors in a mask returned by ubcCheck, in the
+// order of disturbanceVectors.
+const (
+	dvI43_0 uint32 = 1 << iota
+	dvI44_0
+	dvI45_0
+	dvI46_0
+	dvI46_2
+	dvI47_0
+	dvI47_2
+	dvI48_0
+	dvI48_2
+	dvI49_0
+	dvI49_2
+	dvI50_0
+	dvI50_2
+	dvI51_0
+	dvI51_2
+	dvI52_0
+	dvII45_0
+	dvII46_0
+	dvII46_2
+	dvII47_0
+	dvII48_0
+	dvII49_0
+	dvII49_2
+	dvII50_0
+	dvII50_2
+	dvII51_0
+	dvII51_2
+	dvII52_0
+	dvII53_0
+	dvII54_0
+	dvII55_0
+	dvII56_0
+)
+
+// ubcCheck returns the mask of the disturbance vectors whose unavoidable bit
+// conditions are all met by the expanded message w. Each line is a condition:
+// bit a of w[i] XOR bit b of w[j] has to be 0, or 1 when it is negated, in
+// every block which is part of an attack with the disturbance vectors it
+// clears. Like ubc_check.c, the conditions are written out and do not branch
+// on the bits of w, which are random for ordinary data.
+func ubcCheck(w *[80]uint32) uint32 {
+	mask := ^uint32(0)
+	mask &^= (dvI48_0 | dvI51_0 | dvI52_0 | dvII45_0 | dvII46_0 | dvII50_0 | dvII51_0) & -((w[44]>>29 ^ w[45]>>29) & 1)
+	mask &^= (dvI46_0 | dvII45_0 | dvII50_0 | dvII51_0 | dvII55_0 | dvII56_0) & -((w[49]>>29 ^ w[50]>>29) & 1)
+	mask &^= (dvI45_0 | dvI52_0 | dvII49_0 | dvII50_0 | dvII54_0 | dvII55_0) & -((w[48]>>29 ^ w[49]>>29) & 1)
+	mask &^= (dvI47_0 | dvI49_0 | dvI51_0 | dvII45_0 | dvII51_0 | dvII56_0) & -((w[47]>>4 ^ w[50]>>29) & 1)
+	mask &^= (dvI44_0 | dvI51_0 | dvII48_0 | dvII49_0 | dvII53_0 | dvII54_0) & -((w[47]>>29 ^ w[48]>>29) & 1)
+	mask &^= (dvI46_0 | dvI48_0 | dvI50_0 | dvI52_0 | dvII50_0 | dvII55_0) & -((w[46]>>4 ^ w[49]>>29) & 1)
+	mask &^= (dvI43_0 | dvI50_0 | dvII47_0 | dvII48_0 | dvII52_0 | dvII53_0) & -((w[46]>>29 ^ w[47]>>29) & 1)
+	mask &^= (dvI45_0 | dvI47_0 | dvI49_0 | dvI51_0 | dvII49_0 | dvII54_0) & -((w[45]>>4 ^ w[48]>>29) & 1)
+	mask &^= (dvI49_0 | dvI52_0 | dvII46_0 | dvII47_0 | dvII51_0 | dvII52_0) & -((w[45]>>29 ^ w[46]>>29) & 1)
+	mask &^= (dvI44_0 | dvI46_0 | dvI48_0 | dvI50_0 | dvII48_0 | dvII53_0) & -((w[44]>>4 ^ w[47]>>29) & 1)
+	mask &^= (dvI43_0 | dvI45_0 | dvI47_0 | dvI49_0 | dvII47_0 | dvII52_0) & -((w[43]>>4 ^ w[46]>>29) & 1)
+	mask &^= (dvI47_0 | dvI50_0 | dvI51_0 | dvII45_0 | dvII49_0 | dvII50_0) & -((w[43]>>29 ^ w[44]>>29) & 1)
+	mask &^= (dvI44_0 | dvI46_0 | dvI48_0 | dvI52_0 | dvII46_0 | dvII51_0) & -((w[42]>>4 ^ w[45]>>29) & 1)
+	mask &^= (dvI43_0 | dvI45_0 | dvI47_0 | dvI51_0 | dvII45_0 | dvII50_0) & -((w[41]>>4 ^ w[44]>>29) & 1)
+	mask &^= (dvI44_0 | dvI47_0 | dvI48_0 | dvII46_0 | dvII47_0 | dvII56_0) & -((w[40]>>29 ^ w[41]>>29) & 1)
+	mask &^= (dvI51_0 | dvII47_0 | dvII50_0 | dvII55_0 | dvII56_0) & -((w[54]>>29 ^ w[55]>>29) & 1)
+	mask &^= (dvI50_0 | dvII46_0 | dvII49_0 | dvII54_0 | dvII55_0) & -((w[53]>>29 ^ w[54]>>29) & 1)
+	mask &^= (dvI49_0 | dvII45_0 | dvII48_0 | dvII53_0 | dvII54_0) & -((w[52]>>29 ^ w[53]>>29) & 1)
+	mask &^= (dvI50_0 | dvI52_0 | dvII46_0 | dvII48_0 | dvII54_0) & -((w[50]>>4 ^ w[53]>>29) & 1)
+	mask &^= (dvI47_0 | dvII46_0 | dvII51_0 | dvII52_0 | dvII56_0) & -((w[50]>>29 ^ w[51]>>29) & 1)
+	mask &^= (dvI49_0 | dvI51_0 | dvII45_0 | dvII47_0 | dvII53_0) & -((w[49]>>4 ^ w[52]>>29) & 1)
+	mask &^= (dvI48_0 | dvI50_0 | dvI52_0 | dvII46_0 | dvII52_0) & -((w[48]>>4 ^ w[51]>>29) & 1)
+	mask &^= (dvI46_0 | dvI49_0 | dvI50_0 | dvII48_0 | dvII49_0) & -((w[42]>>29 ^ w[43]>>29) & 1)
+	mask &^= (dvI45_0 | dvI48_0 | dvI49_0 | dvII47_0 | dvII48_0) & -((w[41]>>29 ^ w[42]>>29) & 1)
+	mask &^= (dvI44_0 | dvI46_0 | dvI50_0 | dvII49_0 | dvII56_0) & -((w[40]>>4 ^ w[43]>>29) & 1)
+	mask &^= (dvI43_0 | dvI45_0 | dvI49_0 | dvII48_0 | dvII55_0) & -((w[39]>>4 ^ w[42]>>29) & 1)
+	mask &^= (dvI44_0 | dvI48_0 | dvII47_0 | dvII54_0 | dvII56_0) & -((w[38]>>4 ^ w[41]>>29) & 1)
+	mask &^= (dvI43_0 | dvI47_0 | dvII46_0 | dvII53_0 | dvII55_0) & -((w[37]>>4 ^ w[40]>>29) & 1)
+	mask &^= (dvI52_0 | dvII48_0 | dvII51_0 | dvII56_0) & -((w[55]>>29 ^ w[56]>>29) & 1)
+	mask &^= (dvI52_0 | dvII48_0 | dvII50_0 | dvII56_0) & -((w[52]>>4 ^ w[55]>>29) & 1)
+	mask &^= (dvI51_0 | dvII47_0 | dvII49_0 | dvII55_0) & -((w[51]>>4 ^ w[54]>>29) & 1)
+	mask &^= (dvI48_0 | dvII47_0 | dvII52_0 | dvII53_0) & -((w[51]>>29 ^ w[52]>>29) & 1)
+	mask &^= (dvI46_0 | dvI49_0 | dvII45_0 | dvII48_0) & -((w[36]>>4 ^ w[40]>>29) & 1)
+	mask &^= (dvI52_0 | dvII48_0 | dvII49_0) & -((^(w[53]>>29 ^ w[56]>>29)) & 1)
+	mask &^= (dvI50_0 | dvII46_0 | dvII47_0) & -((^(w[51]>>29 ^ w[54]>>29)) & 1)
+	mask &^= (dvI49_0 | dvI51_0 | dvII45_0) & -((^(w[50]>>29 ^ w[52]>>29)) & 1)
+	mask &^= (dvI48_0 | dvI50_0 | dvI52_0) & -((^(w[49]>>29 ^ w[51]>>29)) & 1)
+	mask &^= (dvI47_0 | dvI49_0 | dvI51_0) & -((^(w[48]>>29 ^ w[50]>>29)) & 1)
+	mask &^= (dvI46_0 | dvI48_0 | dvI50_0) & -((^(w[47]>>29 ^ w[49]>>29)) & 1)
+	mask &^= (dvI45_0 | dvI47_0 | dvI49_0) & -((^(w[46]>>29 ^ w[48]>>29)) & 1)
+	mask &^= (dvI47_2 | dvI49_2 | dvI51_2) & -((w[45]>>6 ^ w[47]>>6) & 1)
+	mask &^= (dvI44_0 | dvI46_0 | dvI48_0) & -((^(w[45]>>29 ^ w[47]>>29)) & 1)
+	mask &^= (dvI46_2 | dvI48_2 | dvI50_2) & -((w[44]>>6 ^ w[46]>>6) & 1)
+	mask &^= (dvI43_0 | dvI45_0 | dvI47_0) & -((^(w[44]>>29 ^ w[46]>>29)) & 1)
+	mask &^= (dvI48_2 | dvII46_2 | dvII51_2) & -((^(w[41]>>1 ^ w[42]>>6)) & 1)
+	mask &^= (dvI47_2 | dvI51_2 | dvII50_2) & -((^(w[40]>>1 ^ w[41]>>6)) & 1)
+	mask &^= (dvI44_0 | dvI46_0 | dvII56_0) & -((^(w[40]>>4 ^ w[42]>>4)) & 1)
+	mask &^= (dvI46_2 | dvI50_2 | dvII49_2) & -((^(w[39]>>1 ^ w[40]>>6)) & 1)
+	mask &^= (dvI43_0 | dvI45_0 | dvII55_0) & -((^(w[39]>>4 ^ w[41]>>4)) & 1)
+	mask &^= (dvI44_0 | dvII54_0 | dvII56_0) & -((^(w[38]>>4 ^ w[40]>>4)) & 1)
+	mask &^= (dvI43_0 | dvII53_0 | dvII55_0) & -((^(w[37]>>4 ^ w[39]>>4)) & 1)
+	mask &^= (dvI47_2 | dvI50_2 | dvII46_2) & -((^(w[36]>>1 ^ w[37]>>6)) & 1)
+	mask &^= (dvI45_0 | dvI48_0 | dvII47_0) & -((w[35]>>4 ^ w[39]>>29) & 1)
+	mask &^= (dvI48_0 | dvII48_0) & -((^(w[63] ^ w[64]>>5)) & 1)
+	mask &^= (dvI45_0 | dvII45_0) & -((^(w[63]>>1 ^ w[64]>>6)) & 1)
+	mask &^= (dvI47_0 | dvII47_0) & -((^(w[62] ^ w[63]>>5)) & 1)
+	mask &^= (dvI46_0 | dvII46_0) & -((^(w[61] ^ w[62]>>5)) & 1)
+	mask &^= (dvI46_2 | dvII46_2) & -((^(w[61]>>2 ^ w[62]>>7)) & 1)
+	mask &^= (dvI45_0 | dvII45_0) & -((^(w[60] ^ w[61]>>5)) & 1)
+	mask &^= (dvII51_0 | dvII54_0) & -((w[58]>>29 ^ w[59]>>29) & 1)
+	mask &^= (dvII50_0 | dvII53_0) & -((w[57]>>29 ^ w[58]>>29) & 1)
+	mask &^= (dvII52_0 | dvII54_0) & -((w[56]>>4 ^ w[59]>>29) & 1)
+	mask &^= (dvII51_0 | dvII52_0) & -((^(w[56]>>29 ^ w[59]>>29)) & 1)
+	mask &^= (dvII49_0 | dvII52_0) & -((w[56]>>29 ^ w[57]>>29) & 1)
+	mask &^= (dvII51_0 | dvII53_0) & -((w[55]>>4 ^ w[58]>>29) & 1)
+	mask &^= (dvII50_0 | dvII52_0) & -((w[54]>>4 ^ w[57]>>29) & 1)
+	mask &^= (dvII49_0 | dvII51_0) & -((w[53]>>4 ^ w[56]>>29) & 1)
+	mask &^= (dvI50_2 | dvII46_2) & -((w[50]>>6 ^ w[51]>>1) & 1)
+	mask &^= (dvI50_2 | dvII46_2) & -((w[48]>>6 ^ w[50]>>6) & 1)
+	mask &^= (dvI51_0 | dvI52_0) & -((^(w[48]>>29 ^ w[55]>>29)) & 1)
+	mask &^= (dvI49_2 | dvI51_2) & -((w[47]>>6 ^ w[49]>>6) & 1)
+	mask &^= (dvI47_2 | dvII51_2) & -((w[47]>>6 ^ w[48]>>1) & 1)
+	mask &^= (dvI48_2 | dvI50_2) & -((w[46]>>6 ^ w[48]>>6) & 1)
+	mask &^= (dvI46_2 | dvII50_2) & -((w[46]>>6 ^ w[47]>>1) & 1)
+	mask &^= (dvI51_2 | dvII49_2) & -((^(w[44]>>1 ^ w[45]>>6)) & 1)
+	mask &^= (dvI47_2 | dvI49_2) & -((w[43]>>6 ^ w[45]>>6) & 1)
+	mask &^= (dvI46_2 | dvI48_2) & -((w[42]>>6 ^ w[44]>>6) & 1)
+	mask &^= (dvII46_2 | dvII51_2) & -((w[42]>>6 ^ w[43]>>1) & 1)
+	mask &^= (dvI51_2 | dvII50_2) & -((w[41]>>6 ^ w[42]>>1) & 1)
+	mask &^= (dvI50_2 | dvII49_2) & -((w[40]>>6 ^ w[41]>>1) & 1)
+	mask &^= (dvI52_0 | dvII51_0) & -((w[39]>>4 ^ w[43]>>29) & 1)
+	mask &^= (dvI51_0 | dvII50_0) & -((w[38]>>4 ^ w[42]>>29) & 1)
+	mask &^= (dvI48_2 | dvI51_2) & -((^(w[37]>>1 ^ w[38]>>6)) & 1)
+	mask &^= (dvI50_0 | dvII49_0) & -((w[37]>>4 ^ w[41]>>29) & 1)
+	mask &^= (dvII52_0 | dvII54_0) & -((^(w[36]>>4 ^ w[38]>>4)) & 1)
+	mask &^= (dvI46_2 | dvI49_2) & -((^(w[35]>>1 ^ w[36]>>6)) & 1)
+	mask &^= (dvI51_0 | dvII47_0) & -((w[35]>>3 ^ w[39]>>28) & 1)
+	mask &^= dvI43_0 & -((^(w[61]>>1 ^ w[62]>>6)) & 1)
+	mask &^= dvI43_0 & -((w[59]>>5 ^ w[63]>>30) & 1)
+	mask &^= dvI43_0 & -((^(w[58] ^ w[63]>>30)) & 1)
+	mask &^= dvI44_0 & -((^(w[62]>>1 ^ w[63]>>6)) & 1)
+	mask &^= dvI44_0 & -((w[60]>>5 ^ w[64]>>30) & 1)
+	mask &^= dvI44_0 & -((^(w[59] ^ w[64]>>30)) & 1)
+	mask &^= dvI46_2 & -((w[40]>>6 ^ w[42]>>6) & 1)
+	mask &^= dvI47_2 & -((^(w[62]>>2 ^ w[63]>>7)) & 1)
+	mask &^= dvI47_2 & -((w[41]>>6 ^ w[43]>>6) & 1)
+	mask &^= dvI48_2 & -((^(w[63]>>2 ^ w[64]>>7)) & 1)
+	mask &^= dvI48_2 & -((w[48]>>6 ^ w[49]>>1) & 1)
+	mask &^= dvI49_2 & -((w[49]>>6 ^ w[50]>>1) & 1)
+	mask &^= dvI49_2 & -((^(w[42]>>1 ^ w[50]>>1)) & 1)
+	mask &^= dvI49_2 & -((w[39]>>6 ^ w[40]>>1) & 1)
+	mask &^= dvI49_2 & -((^(w[38]>>1 ^ w[40]>>1)) & 1)
+	mask &^= dvI50_0 & -((^(w[36]>>4 ^ w[37]>>4)) & 1)
+	mask &^= dvI50_2 & -((^(w[43]>>1 ^ w[51]>>1)) & 1)
+	mask &^= dvI51_0 & -((^(w[37]>>4 ^ w[38]>>4)) & 1)
+	mask &^= dvI51_2 & -((w[51]>>6 ^ w[52]>>1) & 1)
+	mask &^= dvI51_2 & -((w[49]>>6 ^ w[51]>>6) & 1)
+	mask &^= dvI51_2 & -((w[37]>>1 ^ w[37]>>6) & 1)
+	mask &^= dvI51_2 & -((w[35]>>5 ^ w[39]>>30) & 1)
+	mask &^= dvI52_0 & -((^(w[38]>>4 ^ w[39]>>4)) & 1)
+	mask &^= dvII46_2 & -((^(w[47]>>1 ^ w[51]>>1)) & 1)
+	mask &^= dvII48_0 & -((w[36]>>3 ^ w[40]>>28) & 1)
+	mask &^= dvII48_0 & -((^(w[35]>>30 ^ w[40]>>28)) & 1)
+	mask &^= dvII49_0 & -((w[37]>>3 ^ w[41]>>28) & 1)
+	mask &^= dvII49_0 & -((^(w[36]>>30 ^ w[41]>>28)) & 1)
+	mask &^= dvII49_2 & -((w[53]>>6 ^ w[54]>>1) & 1)
+	mask &^= dvII49_2 & -((w[51]>>6 ^ w[53]>>6) & 1)
+	mask &^= dvII49_2 & -((^(w[50]>>1 ^ w[54]>>1)) & 1)
+	mask &^= dvII49_2 & -((w[45]>>6 ^ w[46]>>1) & 1)
+	mask &^= dvII49_2 & -((w[37]>>5 ^ w[41]>>30) & 1)
+	mask &^= dvII49_2 & -((^(w[36] ^ w[41]>>30)) & 1)
+	mask &^= dvII50_0 & -((^(w[55]>>29 ^ w[58]>>29)) & 1)
+	mask &^= dvII50_0 & -((w[38]>>3 ^ w[42]>>28) & 1)
+	mask &^= dvII50_0 & -((^(w[37]>>30 ^ w[42]>>28)) & 1)
+	mask &^= dvII50_2 & -((w[54]>>6 ^ w[55]>>1) & 1)
+	mask &^= dvII50_2 & -((w[52]>>6 ^ w[54]>>6) & 1)
+	mask &^= dvII50_2 & -((^(w[51]>>1 ^ w[55]>>1)) & 1)
+	mask &^= dvII50_2 & -((^(w[45]>>1 ^ w[47]>>1)) & 1)
+	mask &^= dvII50_2 & -((w[38]>>5 ^ w[42]>>30) & 1)
+	mask &^= dvII50_2 & -((^(w[37] ^ w[42]>>30)) & 1)
+	mask &^= dvII51_0 & -((w[39]>>3 ^ w[43]>>28) & 1)
+	mask &^= dvII51_0 & -((^(w[38]>>30 ^ w[43]>>28)) & 1)
+	mask &^= dvII51_2 & -((w[55]>>6 ^ w[56]>>1) & 1)
+	mask &^= dvII51_2 & -((w[53]>>6 ^ w[55]>>6) & 1)
+	mask &^= dvII51_2 & -((^(w[52]>>1 ^ w[56]>>1)) & 1)
+	mask &^= dvII51_2 & -((^(w[46]>>1 ^ w[48]>>1)) & 1)
+	mask &^= dvII51_2 & -((w[39]>>5 ^ w[43]>>30) & 1)
+	mask &^= dvII51_2 & -((^(w[38] ^ w[43]>>30)) & 1)
+	mask &^= dvII52_0 & -((w[59]>>29 ^ w[60]>>29) & 1)
+	mask &^= dvII52_0 & -((w[40]>>3 ^ w[44]>>28) & 1)
+	mask &^= dvII52_0 & -((w[40]>>4 ^ w[44]>>29) & 1)
+	mask &^= dvII52_0 & -((^(w[39]>>30 ^ w[44]>>28)) & 1)
+	mask &^= dvII53_0 & -((^(w[58]>>29 ^ w[61]>>29)) & 1)
+	mask &^= dvII53_0 & -((w[57]>>4 ^ w[61]>>29) & 1)
+	mask &^= dvII53_0 & -((w[41]>>3 ^ w[45]>>28) & 1)
+	mask &^= dvII53_0 & -((w[41]>>4 ^ w[45]>>29) & 1)
+	mask &^= dvII54_0 & -((w[58]>>4 ^ w[62]>>29) & 1)
+	mask &^= dvII54_0 & -((w[42]>>3 ^ w[46]>>28) & 1)
+	mask &^= dvII54_0 & -((w[42]>>4 ^ w[46]>>29) & 1)
+	mask &^= dvII55_0 & -((w[59]>>4 ^ w[63]>>29) & 1)
+	mask &^= dvII55_0 & -((w[57]>>4 ^ w[59]>>29) & 1)
+	mask &^= dvII55_0 & -((w[43]>>3 ^ w[47]>>28) & 1)
+	mask &^= dvII55_0 & -((w[43]>>4 ^ w[47]>>29) & 1)
+	mask &^= dvII56_0 & -((w[60]>>4 ^ w[64]>>29) & 1)
+	mask &^= dvII56_0 & -((w[44]>>3 ^ w[48]>>28) & 1)
+	mask &^= dvII56_0 & -((w[44]>>4 ^ w[48]>>29) & 1)
+
+	return mask
+}
@@ -0,0 +1,136 @@
+package history
+
+import (
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"container/heap"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrStopWalk can be returned by the function passed to Walk to stop
+	// walking without Walk returning an error.
+	ErrStopWalk = errors.New("stop walking history")
+)
+
+// FetchCommit retrieves the Commit with hash from the object database.
+func FetchCommit(hash string) (structures.Commit, error) {
+	o, err := storage.FetchByHash(hash)
+	if err != nil {
+		return structures.Commit{}, err
+	}
+
+	if !structures.IsCommitB(o.Content) {
+		return structures.Commit{}, fmt.Errorf("object %v is not a commit", o.Hash)
+	}
+
+	return structures.NewCommitFromObject(o)
+}
+
+// Walk calls fn for every commit reachable from the commits with hashes in
+// start, including themselves, each of them once. Commits are visited from
+// the newest to the oldest by their CommitDate, so a commit usually comes
+// after all of its descendants. When fn returns ErrStopWalk, Walk stops and
+// returns nil, any other error is returned as is.
+func Walk(start []string, fn func(c structures.Commit) error) error {
+	return walk(start, nil, fn)
+}
+
+// WalkRange is Walk, but leaves out the commits reachable from the commits
+// in exclude, so git's "A..B" are the commits WalkRange visits for B and A.
+func WalkRange(include []string, exclude []string, fn func(c structures.Commit) error) error {
+	excluded := make(map[string]bool)
+	err := Walk(exclude, func(c structures.Commit) error {
+		excluded[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return walk(include, excluded, fn)
+}
+
+// walk implements Walk, commits in excluded and their parents are not visited.
+func walk(start []string, excluded map[string]bool, fn func(c structures.Commit) error) error {
+	var q commitQueue
+	seen := make(map[string]bool)
+	push := func(hash string) error {
+		if seen[hash] || excluded[hash] {
+			return nil
+		}
+		seen[hash] = true
+
+		c, err := FetchCommit(hash)
+		if err != nil {
+			return err
+		}
+
+		heap.Push(&q, queuedCommit{commit: c, order: len(seen)})
+		return nil
+	}
+
+	for _, h := range start {
+		if err := push(h); err != nil {
+			return err
+		}
+	}
+
+	for q.Len() > 0 {
+		c := heap.Pop(&q).(queuedCommit).commit
+		if err := fn(c); err != nil {
+			if errors.Is(err, ErrStopWalk) {
+				return nil
+			}
+
+			return err
+		}
+
+		for _, p := range c.ParentHashes {
+			if err := push(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// queuedCommit is a commit waiting in a commitQueue. order is the order the
+// commit was found in, which breaks ties between commits with the same date.
+type queuedCommit struct {
+	commit structures.Commit
+	order  int
+}
+
+// commitQueue is a heap.Interface with the newest commit on top.
+type commitQueue []queuedCommit
+
+func (q commitQueue) Len() int {
+	return len(q)
+}
+
+func (q commitQueue) Less(i, j int) bool {
+	if !q[i].commit.CommitDate.Equal(q[j].commit.CommitDate) {
+		return q[i].commit.CommitDate.After(q[j].commit.CommitDate)
+	}
+
+	return q[i].order < q[j].order
+}
+
+func (q commitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *commitQueue) Push(x any) {
+	*q = append(*q, x.(queuedCommit))
+}
+
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return c
+}
@@ -0,0 +1,81 @@
+package history
+
+import (
+	"armanVersionControl/structures"
+	"slices"
+)
+
+// ancestors returns the hashes of the commits reachable from the commits with
+// hashes in start, including themselves.
+func ancestors(start []string) (map[string]bool, error) {
+	reachable := make(map[string]bool)
+	err := Walk(start, func(c structures.Commit) error {
+		reachable[c.Hash] = true
+		return nil
+	})
+
+	return reachable, err
+}
+
+// IsAncestor reports whether the commit with hash ancestor is reachable from
+// the commit with hash descendant. A commit is an ancestor of itself.
+func IsAncestor(ancestor string, descendant string) (bool, error) {
+	found := false
+	err := Walk([]string{descendant}, func(c structures.Commit) error {
+		if c.Hash == ancestor {
+			found = true
+			return ErrStopWalk
+		}
+
+		return nil
+	})
+
+	return found, err
+}
+
+// MergeBases returns the lowest common ancestors of the commits with hashes a
+// and b: the commits reachable from both, which are not reachable from
+// another such commit. There is usually one of them, none when a and b have
+// no common history and several after criss-cross merges. They are sorted
+// from the newest to the oldest by their CommitDate.
+func MergeBases(a string, b string) ([]string, error) {
+	fromA, err := ancestors([]string{a})
+	if err != nil {
+		return nil, err
+	}
+
+	// The common ancestors which are found first walking back from b are the
+	// candidates, the history behind them is common as well.
+	var candidates []string
+	seen := make(map[string]bool)
+	err = Walk([]string{b}, func(c structures.Commit) error {
+		if fromA[c.Hash] && !seen[c.Hash] {
+			candidates = append(candidates, c.Hash)
+		}
+		if fromA[c.Hash] || seen[c.Hash] {
+			for _, p := range c.ParentHashes {
+				seen[p] = true
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var bases []string
+	for i, c := range candidates {
+		others := slices.Concat(candidates[:i], candidates[i+1:])
+		reachable, err := ancestors(others)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reachable[c] {
+			bases = append(bases, c)
+		}
+	}
+
+	return bases, nil
+}
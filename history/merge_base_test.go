@@ -0,0 +1,108 @@
+package history
+
+import (
+	"armanVersionControl/hashing"
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// initTempRepo changes into a new repository in a temporary directory for the
+// rest of the test.
+func initTempRepo(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err = storage.Init(hashing.SHA1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commitGraph stores commits with an empty tree, the nth of them committed n
+// minutes after the first.
+type commitGraph struct {
+	t        *testing.T
+	treeHash string
+	start    time.Time
+	n        int
+}
+
+func newCommitGraph(t *testing.T) *commitGraph {
+	h, err := (&structures.Tree{}).StoreTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &commitGraph{t: t, treeHash: h, start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// commit stores a commit with parents and returns its hash.
+func (g *commitGraph) commit(message string, parents ...string) string {
+	g.t.Helper()
+
+	date := g.start.Add(time.Duration(g.n) * time.Minute)
+	g.n++
+	h, err := structures.New(g.treeHash, parents, "A", "a@x", date, "C", "c@x", date, message+"\n").StoreCommit()
+	if err != nil {
+		g.t.Fatal(err)
+	}
+
+	return h
+}
+
+func TestMergeBases(t *testing.T) {
+	initTempRepo(t)
+	g := newCommitGraph(t)
+
+	// a3 merges b2 into a2, and xa and yb both merge x1 and y1, one into
+	// the other and the other way around.
+	root := g.commit("root")
+	a1 := g.commit("a1", root)
+	b1 := g.commit("b1", root)
+	a2 := g.commit("a2", a1)
+	b2 := g.commit("b2", b1)
+	a3 := g.commit("a3", a2, b2)
+	x1 := g.commit("x1", root)
+	y1 := g.commit("y1", root)
+	xa := g.commit("xa", x1, y1)
+	yb := g.commit("yb", y1, x1)
+	unrelated := g.commit("unrelated")
+
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []string
+	}{
+		{"same commit", a2, a2, []string{a2}},
+		{"ancestor", a1, a2, []string{a1}},
+		{"descendant", a2, a1, []string{a1}},
+		{"fork", a2, b2, []string{root}},
+		{"after a merge", a3, b2, []string{b2}},
+		{"no common history", a2, unrelated, nil},
+		// Both sides merged the other one, so neither of x1 and y1 is
+		// better than the other. The newest comes first.
+		{"criss-cross", xa, yb, []string{y1, x1}},
+		{"criss-cross reversed", yb, xa, []string{y1, x1}},
+	}
+
+	for _, tt := range tests {
+		got, err := MergeBases(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package mailbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// fromLineDate is the date of the line which starts each message, which
+	// git writes as well, because the line only separates messages.
+	fromLineDate = "Mon Sep 17 00:00:00 2001"
+	// dateLayout is the layout of the Date header.
+	dateLayout = "Mon, 2 Jan 2006 15:04:05 -0700"
+	// signature ends the patch of each message.
+	signature = "-- \navc\n"
+)
+
+var (
+	ErrNoMessages = errors.New("no messages found in the mailbox")
+)
+
+// fromLineRegexp matches the "From <sender> <date>" lines which separate the
+// messages of a mailbox.
+var fromLineRegexp = regexp.MustCompile(`^From \S+ \w{3} \w{3} [ \d]\d \d\d:\d\d:\d\d \d{4}\n?$`)
+
+// subjectPrefixRegexp matches the prefixes of a subject which are not part of
+// the message, like "[PATCH 1/2]" and "Re:".
+var subjectPrefixRegexp = regexp.MustCompile(`^\s*(\[[^\]]*\]|(?i:re):)\s*`)
+
+// Message is a commit as an email of a mailbox.
+type Message struct {
+	// Hash is the hash of the commit.
+	Hash        string
+	Author      string
+	AuthorEmail string
+	// Date is when the change was authored.
+	Date time.Time
+	// Subject is the first paragraph of the commit message, in a single line.
+	Subject string
+	// Body is the rest of the commit message.
+	Body string
+	// Patch is the diff of the commit, which may start with its statistics.
+	Patch string
+}
+
+// SplitMessage splits a commit message into the subject and the body of a
+// Message. Lines of the first paragraph are joined with spaces.
+func SplitMessage(message string) (subject string, body string) {
+	message = strings.TrimSpace(message)
+	subject, body, _ = strings.Cut(message, "\n\n")
+
+	return strings.Join(strings.Fields(subject), " "), strings.TrimSpace(body)
+}
+
+// CommitMessage returns the commit message of m.
+func (m Message) CommitMessage() string {
+	if m.Body == "" {
+		return m.Subject + "\n"
+	}
+
+	return m.Subject + "\n\n" + m.Body + "\n"
+}
+
+// Write writes m to w as a message of a mailbox. The subject is prefixed with
+// "[PATCH n/total]", or only "[PATCH]" when total is 1.
+func Write(w io.Writer, m Message, n int, total int) error {
+	prefix := "[PATCH]"
+	if total > 1 {
+		prefix = fmt.Sprintf("[PATCH %v/%v]", n, total)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "From %v %v\n", m.Hash, fromLineDate)
+	fmt.Fprintf(bw, "From: %v\n", formatAddress(m.Author, m.AuthorEmail))
+	fmt.Fprintf(bw, "Date: %v\n", m.Date.Format(dateLayout))
+	fmt.Fprintf(bw, "Subject: %v\n", mime.QEncoding.Encode("utf-8", prefix+" "+m.Subject))
+	bw.WriteString("MIME-Version: 1.0\n")
+	bw.WriteString("Content-Type: text/plain; charset=UTF-8\n")
+	bw.WriteString("Content-Transfer-Encoding: 8bit\n\n")
+
+	if m.Body != "" {
+		bw.WriteString(m.Body + "\n\n")
+	}
+	bw.WriteString("---\n")
+	bw.WriteString(m.Patch)
+	bw.WriteString(signature + "\n")
+
+	return bw.Flush()
+}
+
+// formatAddress formats name and email as the address of a header. Like git
+// does, name is only quoted or encoded when it has to be.
+func formatAddress(name string, email string) string {
+	plain := name != "" && strings.IndexFunc(name, func(r rune) bool {
+		return r > '~' || (r < ' ') || strings.ContainsRune(`()<>[]:;@\,."`, r)
+	}) < 0
+	if plain {
+		return fmt.Sprintf("%v <%v>", name, email)
+	}
+
+	return (&mail.Address{Name: name, Address: email}).String()
+}
+
+// Read reads the messages of the mailbox r. A mailbox without any
+// "From <sender> <date>" line is read as a single message.
+func Read(r io.Reader) ([]Message, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []*bytes.Buffer
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if fromLineRegexp.Match(line) || (len(raw) == 0 && len(bytes.TrimSpace(line)) > 0) {
+			raw = append(raw, &bytes.Buffer{})
+		}
+		if len(raw) > 0 && !fromLineRegexp.Match(line) {
+			raw[len(raw)-1].Write(line)
+		}
+	}
+
+	var messages []Message
+	for i, b := range raw {
+		m, err := parseMessage(b.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("message %v: %w", i+1, err)
+		}
+
+		messages = append(messages, m)
+	}
+
+	if len(messages) == 0 {
+		return nil, ErrNoMessages
+	}
+
+	return messages, nil
+}
+
+// parseMessage parses a single message of a mailbox. The body of the message
+// is the commit message up to a "---" line or the first patch, the rest is
+// the patch.
+func parseMessage(b []byte) (Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(b))
+	if err != nil {
+		return Message{}, err
+	}
+
+	var m Message
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return Message{}, fmt.Errorf("invalid From header: %q", msg.Header.Get("From"))
+	}
+	m.Author, m.AuthorEmail = from[0].Name, from[0].Address
+	if m.Author == "" {
+		m.Author, _, _ = strings.Cut(m.AuthorEmail, "@")
+	}
+
+	// Without a date, the change is authored now, like a new commit.
+	m.Date = time.Now().Truncate(time.Second)
+	if msg.Header.Get("Date") != "" {
+		if m.Date, err = msg.Header.Date(); err != nil {
+			return Message{}, fmt.Errorf("invalid Date header: %w", err)
+		}
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid Subject header: %w", err)
+	}
+	for {
+		s := subjectPrefixRegexp.ReplaceAllString(subject, "")
+		if s == subject {
+			break
+		}
+		subject = s
+	}
+	m.Subject = strings.Join(strings.Fields(subject), " ")
+
+	var body io.Reader = msg.Body
+	switch strings.ToLower(msg.Header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	text, err := io.ReadAll(body)
+	if err != nil {
+		return Message{}, err
+	}
+
+	lines := strings.SplitAfter(string(text), "\n")
+	end := len(lines)
+	for i, l := range lines {
+		if strings.TrimRight(l, "\r\n") == "---" || strings.HasPrefix(l, "diff --git ") {
+			end = i
+			break
+		}
+	}
+
+	m.Body = strings.TrimSpace(strings.Join(lines[:end], ""))
+	m.Patch = strings.Join(lines[end:], "")
+
+	return m, nil
+}
@@ -0,0 +1,157 @@
+package reachability
+
+import (
+	"armanVersionControl/refs"
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"armanVersionControl/track"
+	"errors"
+	"fmt"
+)
+
+// Root is a starting point of the reachability walk.
+type Root struct {
+	// Hash is the hash of the object.
+	Hash string
+	// Source describes where the root comes from, e.g. a ref name,
+	// "index" or "reflog of HEAD".
+	Source string
+}
+
+// MissingObjectError represents an error for when a reachable object
+// does not exist in the object database.
+type MissingObjectError struct {
+	// Hash is the hash of the missing object.
+	Hash string
+	// From describes what points to the missing object.
+	From string
+}
+
+func (m *MissingObjectError) Error() string {
+	return fmt.Sprintf("%v points to missing object %v", m.From, m.Hash)
+}
+
+// Roots returns the starting points of the reachability walk, which are HEAD,
+// every ref, the commit being merged, every blob in the index and every hash
+// recorded in a reflog.
+func Roots() ([]Root, error) {
+	var roots []Root
+
+	head, err := refs.Resolve(refs.HEAD)
+	if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+		return nil, err
+	}
+	if head != "" {
+		roots = append(roots, Root{Hash: head, Source: refs.HEAD})
+	}
+
+	all, err := refs.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range all {
+		roots = append(roots, Root{Hash: r.Hash, Source: r.Name})
+	}
+
+	mergeHead, _, merging, err := refs.ReadMergeHead()
+	if err != nil {
+		return nil, err
+	}
+	if merging {
+		roots = append(roots, Root{Hash: mergeHead, Source: refs.MergeHead})
+	}
+
+	index, err := track.FetchIndex()
+	if err != nil && !errors.Is(err, track.ErrIndexNotFound) {
+		return nil, err
+	}
+	for _, ie := range index.Entries {
+		roots = append(roots, Root{Hash: ie.EntryHash, Source: "index"})
+	}
+
+	logs, err := refs.ListReflogs()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range logs {
+		entries, err := refs.ReadReflog(name)
+		if err != nil {
+			return nil, err
+		}
+
+		source := "reflog of " + name
+		for _, e := range entries {
+			for _, h := range []string{e.OldHash, e.NewHash} {
+				if h != "" {
+					roots = append(roots, Root{Hash: h, Source: source})
+				}
+			}
+		}
+	}
+
+	return roots, nil
+}
+
+// Reachable walks the objects reachable from roots, following commit trees
+// and parents and tree entries, and returns the set of their hashes. Blobs are
+// only checked to exist, they are never read beyond their header. It fails
+// with a MissingObjectError when a reachable object does not exist.
+func Reachable(roots []Root) (map[string]bool, error) {
+	type item struct {
+		hash string
+		from string
+	}
+
+	var stack []item
+	for _, r := range roots {
+		stack = append(stack, item{hash: r.Hash, from: r.Source})
+	}
+
+	reachable := make(map[string]bool)
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[it.hash] {
+			continue
+		}
+
+		_, info, err := structures.FetchInfo(it.hash)
+		if err != nil {
+			if errors.Is(err, storage.ErrObjectNotFound) {
+				return nil, &MissingObjectError{Hash: it.hash, From: it.from}
+			}
+
+			return nil, fmt.Errorf("object %v: %w", it.hash, err)
+		}
+		reachable[it.hash] = true
+
+		if info.Kind == structures.ObjectBlob {
+			continue
+		}
+
+		o, err := storage.FetchByHash(it.hash)
+		if err != nil {
+			return nil, err
+		}
+
+		obj, err := structures.Decode(o)
+		if err != nil {
+			return nil, fmt.Errorf("object %v: %w", it.hash, err)
+		}
+
+		from := fmt.Sprintf("%v %v", info.Kind, it.hash)
+		switch v := obj.(type) {
+		case structures.Tree:
+			for _, te := range v.Entries {
+				stack = append(stack, item{hash: te.EntryHash, from: from})
+			}
+		case structures.Commit:
+			stack = append(stack, item{hash: v.TreeHash, from: from})
+			for _, p := range v.ParentHashes {
+				stack = append(stack, item{hash: p, from: from})
+			}
+		}
+	}
+
+	return reachable, nil
+}
@@ -0,0 +1,74 @@
+package refs
+
+import (
+	"armanVersionControl/storage"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// MergeHead is the name of the file which holds the commit being merged
+	// while a merge with conflicts is not committed yet.
+	MergeHead = "MERGE_HEAD"
+	// mergeMsg is the name of the file which holds the message of the commit
+	// of that merge.
+	mergeMsg = "MERGE_MSG"
+)
+
+// WriteMergeHead records that the commit with hash is being merged into HEAD
+// and that message is the message of the merge commit.
+func WriteMergeHead(hash string, message string) error {
+	ok, err := storage.ExistsMainDir()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return storage.ErrRepoNotInitialized
+	}
+
+	if err = os.WriteFile(path.Join(storage.MainDir, mergeMsg), []byte(message), filePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path.Join(storage.MainDir, MergeHead), []byte(hash+"\n"), filePerm)
+}
+
+// ReadMergeHead returns the hash of the commit being merged and the message
+// of the merge commit. ok is false when no merge is in progress.
+func ReadMergeHead() (hash string, message string, ok bool, err error) {
+	b, err := os.ReadFile(path.Join(storage.MainDir, MergeHead))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+
+	hash = strings.TrimSpace(string(b))
+	if hash == "" {
+		return "", "", false, fmt.Errorf("%v is empty", MergeHead)
+	}
+
+	m, err := os.ReadFile(path.Join(storage.MainDir, mergeMsg))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", "", false, err
+	}
+
+	return hash, string(m), true, nil
+}
+
+// RemoveMergeHead forgets about the merge in progress, if any.
+func RemoveMergeHead() error {
+	for _, name := range []string{MergeHead, mergeMsg} {
+		err := os.Remove(path.Join(storage.MainDir, name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
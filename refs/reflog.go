@@ -0,0 +1,184 @@
+package refs
+
+import (
+	"armanVersionControl/config"
+	"armanVersionControl/storage"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	logsDir = path.Join(storage.MainDir, "logs")
+)
+
+// ReflogEntry is a single change of a ref, recorded in its reflog.
+// Each entry is stored in a line as
+//
+//	<old hash> SP <new hash> SP <name> SP <<email>> SP <unix time> SP <zone> TAB <message>
+//
+// where old hash is all zeros when the ref was created.
+type ReflogEntry struct {
+	// OldHash is the hash the ref pointed to before the change,
+	// it is empty when the ref was created.
+	OldHash string
+	// NewHash is the hash the ref points to after the change.
+	NewHash string
+	// Commiter is who changed the ref and when.
+	Commiter config.Identity
+	// Message describes the change.
+	Message string
+}
+
+// reflogFileName returns the path of the reflog of the ref with name.
+func reflogFileName(name string) string {
+	return path.Join(logsDir, name)
+}
+
+// zeroHash returns the hash which is recorded when there is no hash.
+func zeroHash() (string, error) {
+	a, err := storage.ObjectFormat()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Repeat("0", a.HexLen()), nil
+}
+
+// appendReflog records a change of the ref with name from oldHash to newHash.
+func appendReflog(name string, oldHash string, newHash string, message string) error {
+	c, err := config.Commiter()
+	if err != nil {
+		// A ref can be updated without a known identity, unlike a commit.
+		c = config.Identity{Name: "unknown", Date: time.Now()}
+	}
+
+	if oldHash == "" {
+		if oldHash, err = zeroHash(); err != nil {
+			return err
+		}
+	}
+
+	// Keep every entry on its own line.
+	message = strings.Join(strings.Fields(message), " ")
+	line := fmt.Sprintf("%v %v %v <%v> %v %v\t%v\n", oldHash, newHash, c.Name, c.Email, c.Date.Unix(), c.Date.Format("-0700"), message)
+
+	fn := reflogFileName(name)
+	if err = os.MkdirAll(filepath.Dir(fn), dirPerm); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_APPEND, filePerm)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteString(line)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// ReadReflog returns the entries of the reflog of the ref with name, oldest
+// first. A ref without a reflog has no entries.
+func ReadReflog(name string) ([]ReflogEntry, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+
+	rf, err := os.ReadFile(reflogFileName(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var entries []ReflogEntry
+	sc := bufio.NewScanner(bytes.NewReader(rf))
+	for n := 1; sc.Scan(); n++ {
+		e, err := parseReflogLine(sc.Text())
+		if err != nil {
+			return nil, fmt.Errorf("invalid reflog of %v, line %v: %w", name, n, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, sc.Err()
+}
+
+// parseReflogLine parses a single line of a reflog.
+func parseReflogLine(line string) (ReflogEntry, error) {
+	head, message, _ := strings.Cut(line, "\t")
+
+	fields := strings.Fields(head)
+	// old hash, new hash, at least one word of name, email, time and zone.
+	if len(fields) < 6 {
+		return ReflogEntry{}, fmt.Errorf("expected at least 6 fields but got %v", len(fields))
+	}
+
+	unix, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return ReflogEntry{}, err
+	}
+
+	zone, err := time.Parse("-0700", fields[len(fields)-1])
+	if err != nil {
+		return ReflogEntry{}, err
+	}
+
+	e := ReflogEntry{
+		OldHash: fields[0],
+		NewHash: fields[1],
+		Commiter: config.Identity{
+			Name:  strings.Join(fields[2:len(fields)-3], " "),
+			Email: strings.Trim(fields[len(fields)-3], "<>"),
+			Date:  time.Unix(unix, 0).In(zone.Location()),
+		},
+		Message: message,
+	}
+	if strings.Trim(e.OldHash, "0") == "" {
+		e.OldHash = ""
+	}
+
+	return e, nil
+}
+
+// ListReflogs returns the names of all refs which have a reflog.
+func ListReflogs() ([]string, error) {
+	var output []string
+	err := filepath.WalkDir(logsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == logsDir {
+				return fs.SkipAll
+			}
+
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		name, err := filepath.Rel(logsDir, p)
+		if err != nil {
+			return err
+		}
+
+		output = append(output, filepath.ToSlash(name))
+		return nil
+	})
+
+	return output, err
+}
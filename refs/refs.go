@@ -0,0 +1,390 @@
+package refs
+
+import (
+	"armanVersionControl/storage"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// HEAD is the name of the ref which points to the current branch.
+	HEAD = "HEAD"
+	// DefaultBranch is the branch HEAD points to in a new repository.
+	DefaultBranch = "refs/heads/main"
+	// symbolicPrefix is the start of a ref file that points to another ref.
+	symbolicPrefix = "ref: "
+	// lockSuffix is the suffix of the lock file of a ref which is being updated.
+	lockSuffix = ".lock"
+)
+
+var (
+	refsDir             = path.Join(storage.MainDir, "refs")
+	filePerm            = os.FileMode(0660)
+	dirPerm             = os.FileMode(0777)
+	ErrRefNotFound      = errors.New("ref not found")
+	ErrInvalidRefName   = errors.New("invalid ref name")
+	ErrSymbolicRefDepth = errors.New("symbolic refs are nested too deeply")
+)
+
+// RefLockedError represents an error for when a ref is being updated by
+// another process, or a previous update was interrupted.
+type RefLockedError struct {
+	// Name is the name of the ref.
+	Name string
+}
+
+func (r *RefLockedError) Error() string {
+	return fmt.Sprintf("ref %v is locked, remove %v if no other avc process is running", r.Name, refFileName(r.Name)+lockSuffix)
+}
+
+// RefChangedError represents an error for when a ref does not point to the
+// expected hash anymore.
+type RefChangedError struct {
+	// Name is the name of the ref.
+	Name string
+	// Expected is the hash the ref was expected to point to.
+	Expected string
+	// Actual is the hash the ref points to.
+	Actual string
+}
+
+func (r *RefChangedError) Error() string {
+	return fmt.Sprintf("ref %v points to %v, expected %v", r.Name, r.Actual, r.Expected)
+}
+
+// Ref is a named pointer to an object, usually a commit.
+type Ref struct {
+	// Name is the full name of the ref, e.g. refs/heads/main.
+	Name string
+	// Hash is the hash of the object the ref points to.
+	Hash string
+}
+
+// ValidateName checks whether name can be used as a ref name. A ref name is
+// either HEAD or a slash separated path starting with refs/, whose parts do
+// not start with '.', do not end with ".lock" and do not contain spaces,
+// control characters or any of ~^:?*[\.
+func ValidateName(name string) error {
+	if name == HEAD {
+		return nil
+	}
+
+	rest, ok := strings.CutPrefix(name, "refs/")
+	if !ok {
+		return fmt.Errorf("%w: %q should start with refs/", ErrInvalidRefName, name)
+	}
+
+	for _, part := range strings.Split(rest, "/") {
+		if part == "" || strings.HasPrefix(part, ".") || strings.HasSuffix(part, lockSuffix) ||
+			strings.Contains(part, "..") || strings.ContainsAny(part, " ~^:?*[\\\x7f") {
+			return fmt.Errorf("%w: %q", ErrInvalidRefName, name)
+		}
+
+		for _, r := range part {
+			if r < ' ' {
+				return fmt.Errorf("%w: %q", ErrInvalidRefName, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// refFileName returns the path of the file of the ref with name.
+func refFileName(name string) string {
+	return path.Join(storage.MainDir, name)
+}
+
+// readRef reads the ref file of name and returns either the hash or the
+// name of the ref it points to.
+func readRef(name string) (hash string, target string, err error) {
+	if err = ValidateName(name); err != nil {
+		return "", "", err
+	}
+
+	ok, err := storage.ExistsMainDir()
+	if err != nil {
+		return "", "", err
+	}
+	if !ok {
+		return "", "", storage.ErrRepoNotInitialized
+	}
+
+	rf, err := os.ReadFile(refFileName(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", fmt.Errorf("%w: %v", ErrRefNotFound, name)
+		}
+
+		return "", "", err
+	}
+
+	s := strings.TrimSpace(string(rf))
+	if t, ok := strings.CutPrefix(s, symbolicPrefix); ok {
+		return "", t, nil
+	}
+
+	return s, "", nil
+}
+
+// ReadSymbolic returns the name of the ref that name points to. ok is false
+// when name is not a symbolic ref.
+func ReadSymbolic(name string) (target string, ok bool, err error) {
+	_, target, err = readRef(name)
+	return target, target != "", err
+}
+
+// Target follows the symbolic refs starting at name and returns the name of
+// the ref which holds a hash, or would hold it when it does not exist yet.
+func Target(name string) (string, error) {
+	for range 5 {
+		_, target, err := readRef(name)
+		if errors.Is(err, ErrRefNotFound) || (err == nil && target == "") {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		name = target
+	}
+
+	return "", ErrSymbolicRefDepth
+}
+
+// Resolve returns the hash name points to, following symbolic refs.
+func Resolve(name string) (string, error) {
+	target, err := Target(name)
+	if err != nil {
+		return "", err
+	}
+
+	hash, _, err := readRef(target)
+	return hash, err
+}
+
+// Expand returns the full name of the ref that the short name refers to. Like
+// git, name itself is tried first and then refs/<name>, refs/tags/<name> and
+// refs/heads/<name>, so main refers to refs/heads/main unless a tag with the
+// same name exists.
+func Expand(name string) (string, error) {
+	for _, full := range []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name} {
+		if ValidateName(full) != nil {
+			continue
+		}
+
+		_, _, err := readRef(full)
+		if err == nil {
+			return full, nil
+		}
+		if !errors.Is(err, ErrRefNotFound) {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("%w: %v", ErrRefNotFound, name)
+}
+
+// WriteSymbolic makes name point to the ref target, e.g. HEAD to refs/heads/main.
+func WriteSymbolic(name string, target string) error {
+	if err := ValidateName(target); err != nil {
+		return err
+	}
+
+	return writeRef(name, symbolicPrefix+target)
+}
+
+// Update makes the ref with name point to hash and records the change in the
+// reflog of the ref with message. When name is a symbolic ref, the ref it
+// points to is updated and the change is recorded in the reflogs of both.
+// When oldHash is not empty, the ref is only updated if it points to oldHash.
+// The ref is locked before it is compared to oldHash, so no other update can
+// happen in between.
+func Update(name string, hash string, oldHash string, message string) error {
+	target, err := Target(name)
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockRef(target)
+	if err != nil {
+		return err
+	}
+
+	current, _, err := readRef(target)
+	if err != nil && !errors.Is(err, ErrRefNotFound) {
+		releaseRef(lock, target)
+		return err
+	}
+
+	if oldHash != "" && current != oldHash {
+		releaseRef(lock, target)
+		return &RefChangedError{Name: target, Expected: oldHash, Actual: current}
+	}
+
+	if err = commitRef(lock, target, hash); err != nil {
+		return err
+	}
+
+	logged := []string{target}
+	if target != name {
+		logged = append(logged, name)
+	}
+	for _, n := range logged {
+		if err = appendReflog(n, current, hash, message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the ref with name and its reflog. Symbolic refs are deleted
+// themselves rather than the ref they point to.
+func Delete(name string) error {
+	if _, _, err := readRef(name); err != nil {
+		return err
+	}
+
+	if err := os.Remove(refFileName(name)); err != nil {
+		return err
+	}
+
+	err := os.Remove(reflogFileName(name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// writeRef replaces the content of the ref file of name with content. The
+// new content is written to a lock file first and then renamed, so readers
+// never see a partially written ref and concurrent updates fail.
+func writeRef(name string, content string) error {
+	lock, err := lockRef(name)
+	if err != nil {
+		return err
+	}
+
+	return commitRef(lock, name, content)
+}
+
+// lockRef creates the lock file of the ref with name. Until it is committed
+// with commitRef or released with releaseRef, other updates of the ref fail
+// with a RefLockedError.
+func lockRef(name string) (*os.File, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+
+	ok, err := storage.ExistsMainDir()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, storage.ErrRepoNotInitialized
+	}
+
+	fn := refFileName(name)
+	if err = os.MkdirAll(filepath.Dir(fn), dirPerm); err != nil {
+		return nil, err
+	}
+
+	lock, err := os.OpenFile(fn+lockSuffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, filePerm)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, &RefLockedError{Name: name}
+		}
+
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// commitRef writes content to lock, the lock file of the ref with name, and
+// renames it to the ref file. The lock file is removed when that fails.
+func commitRef(lock *os.File, name string, content string) error {
+	fn := refFileName(name)
+	_, err := lock.WriteString(content + "\n")
+	if cerr := lock.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(fn+lockSuffix, fn)
+	}
+	if err != nil {
+		os.Remove(fn + lockSuffix)
+		return err
+	}
+
+	return nil
+}
+
+// releaseRef removes lock, the lock file of the ref with name, without
+// changing the ref.
+func releaseRef(lock *os.File, name string) {
+	lock.Close()
+	os.Remove(refFileName(name) + lockSuffix)
+}
+
+// List returns all refs under refs/ sorted by name. Symbolic refs are
+// resolved and refs which point to a missing ref are skipped.
+func List() ([]Ref, error) {
+	ok, err := storage.ExistsMainDir()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, storage.ErrRepoNotInitialized
+	}
+
+	var output []Ref
+	err = filepath.WalkDir(refsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == refsDir {
+				return fs.SkipAll
+			}
+
+			return err
+		}
+
+		if d.IsDir() || strings.HasSuffix(p, lockSuffix) {
+			return nil
+		}
+
+		name, err := filepath.Rel(storage.MainDir, p)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+
+		hash, err := Resolve(name)
+		if err != nil {
+			if errors.Is(err, ErrRefNotFound) || errors.Is(err, ErrInvalidRefName) {
+				return nil
+			}
+
+			return err
+		}
+
+		output = append(output, Ref{Name: name, Hash: hash})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(output, func(a, b Ref) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return output, nil
+}
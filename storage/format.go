@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"armanVersionControl/hashing"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	// objectFormatFileName is the file which holds the name of the hash
+	// algorithm of the repository. Repositories created before the hash
+	// algorithm could be chosen do not have it and use hashing.Default.
+	objectFormatFileName = path.Join(MainDir, "objectformat")
+	// objectFormat caches the hash algorithm of the repository, it never
+	// changes after the repository is initialized.
+	objectFormat *hashing.Algorithm
+)
+
+// ObjectFormat returns the hash algorithm objects of the repository are
+// identified with. It is hashing.Default outside of a repository.
+func ObjectFormat() (hashing.Algorithm, error) {
+	if objectFormat != nil {
+		return *objectFormat, nil
+	}
+
+	rf, err := os.ReadFile(objectFormatFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return hashing.Default, nil
+		}
+
+		return hashing.Algorithm{}, err
+	}
+
+	a, err := hashing.ParseAlgorithm(strings.TrimSpace(string(rf)))
+	if err != nil {
+		return hashing.Algorithm{}, err
+	}
+
+	objectFormat = &a
+	return a, nil
+}
+
+// writeObjectFormat records a as the hash algorithm of the repository.
+func writeObjectFormat(a hashing.Algorithm) error {
+	if err := os.WriteFile(objectFormatFileName, []byte(a.Name()+"\n"), 0444); err != nil {
+		return err
+	}
+
+	objectFormat = &a
+	return nil
+}
@@ -5,13 +5,14 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-// TODO change file perm? should other users see this? in git all have read access only, why?
 // TODO add pager instead of reading whole file?
 
 // HashCollisionError represents an error for hash collisions.
@@ -27,26 +28,41 @@ func (h *HashCollisionError) Error() string {
 	return fmt.Sprintf("hash collision detected. Possible matches:\n%s", strings.Join(h.Collisions, "\n"))
 }
 
-// ObjectDuplicateError represents an error for when an object
-// with the same hash already exists in the object database.
-type ObjectDuplicateError struct {
-	// Hash is the object hash.
+// CollisionAttackError represents an error for when content is part of a
+// crafted hash collision, which is never stored in the object database.
+type CollisionAttackError struct {
+	// Hash is the hash the hash algorithm computes for the content once the
+	// collision is detected, it differs from the colliding hash.
 	Hash string
 }
 
-func (o *ObjectDuplicateError) Error() string {
-	return fmt.Sprintf("object hash of %v already exists in the object database store", o.Hash)
+func (c *CollisionAttackError) Error() string {
+	return fmt.Sprintf("content is part of a crafted hash collision and is rejected, its safe hash is %v", c.Hash)
+}
+
+func (c *CollisionAttackError) Unwrap() error {
+	return hashing.ErrCollisionAttack
 }
 
 var (
 	ErrHashIsShort            = errors.New("provided hash is short, it should be at least 2 characters")
 	ErrObjectNotFound         = errors.New("object not found")
+	ErrInvalidHash            = errors.New("invalid hash")
 	ErrDirectoryIsNotExpected = errors.New("directory is not expected in a directory of object database")
 )
 
+const (
+	// tempObjectPrefix is the prefix of the temporary files objects are
+	// written to before they are renamed to their hash.
+	tempObjectPrefix = "tmp_obj_"
+)
+
 var (
-	objectDir             = path.Join(MainDir, "objects")
-	filePerm  os.FileMode = 0770
+	objectDir = path.Join(MainDir, "objects")
+	// filePerm is read-only for everyone, like in git. Objects are never
+	// modified after they are written, their name is the hash of their
+	// content, so making them writable would only allow corrupting them.
+	filePerm os.FileMode = 0444
 )
 
 // Object represents any data in the object database.
@@ -57,7 +73,17 @@ type Object struct {
 	Content []byte
 }
 
-// Store will save content in the object database.
+// Store will save content in the object database and return its hash.
+// Storing content which is already in the object database returns the
+// hash of the existing object, so any number of writers can store the
+// same content at the same time.
+//
+// The object is written to a temporary file in its directory first, which is
+// synced and then renamed to the hash of the content, and at last the directory
+// is synced, as is objectDir when the directory is new. This way a crash never
+// leaves a partially written object behind.
+// Content which is part of a crafted hash collision is rejected with a
+// CollisionAttackError, so it can never replace an existing object.
 func Store(content []byte) (hash string, e error) {
 	ok, err := ExistsMainDir()
 	if err != nil {
@@ -68,81 +94,214 @@ func Store(content []byte) (hash string, e error) {
 		return "", ErrRepoNotInitialized
 	}
 
-	hashHex := ComputeHash(content)
+	hashHex, err := ComputeHash(content)
+	if err != nil {
+		return "", err
+	}
 
 	dir := path.Join(objectDir, hashHex[:2])
-	fileName := hashHex[2:]
-	filePath := path.Join(dir, fileName)
+	filePath := path.Join(dir, hashHex[2:])
+
+	// An object with a different size under the same name was not written
+	// completely, e.g. before writes were atomic, so it is replaced. An
+	// existing object gets a new modification time, so gc does not prune it
+	// as an old unreachable object before it is referenced. When that is not
+	// possible, it is written again instead.
+	if s, err := os.Stat(filePath); err == nil && s.Size() == int64(len(content)) {
+		now := time.Now()
+		if os.Chtimes(filePath, now, now) == nil {
+			return hashHex, nil
+		}
+	}
 
-	ok, err = objectExists(path.Join(dir, fileName))
-	if err != nil {
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		if err = os.MkdirAll(dir, dirPerm); err != nil {
+			return "", err
+		}
+
+		// The new directory is an entry of objectDir, which has to be
+		// persisted as well for the object to survive a crash.
+		if err = syncDir(objectDir); err != nil {
+			return "", err
+		}
+	} else if err != nil {
 		return "", err
 	}
 
-	if ok {
-		return "", &ObjectDuplicateError{Hash: hashHex}
+	if err = writeFileAtomic(dir, filePath, content); err != nil {
+		return "", err
 	}
 
-	if err = mkdirAllIfDoesNotExists(dir, dirPerm); err != nil {
+	return hashHex, nil
+}
+
+// writeFileAtomic writes content to name through a temporary file in dir,
+// which should be the directory of name.
+func writeFileAtomic(dir string, name string, content []byte) error {
+	f, err := os.CreateTemp(dir, tempObjectPrefix)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, filePerm)
+	}
+	if err == nil {
+		// Another writer might have renamed the same object meanwhile,
+		// replacing it is fine because the content is the same.
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir makes sure the entries of dir, e.g. a renamed file, are persisted.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// ComputeHash will compute a hash based on the content with the hash
+// algorithm of the repository and return the generated hash. It fails with
+// a CollisionAttackError when content is part of a crafted collision.
+func ComputeHash(content []byte) (string, error) {
+	a, err := ObjectFormat()
+	if err != nil {
 		return "", err
 	}
 
-	err = os.WriteFile(filePath, content, filePerm)
+	sum, err := a.Sum(content)
 	if err != nil {
+		if errors.Is(err, hashing.ErrCollisionAttack) {
+			return "", &CollisionAttackError{Hash: hex.EncodeToString(sum)}
+		}
+
 		return "", err
 	}
 
-	return hashHex, err
+	return hex.EncodeToString(sum), nil
 }
 
-// ComputeHash will compute a hash based on the content and return
-// the generated hash.
-func ComputeHash(content []byte) string {
-	return hex.EncodeToString(hashing.Sha1(content))
+// ValidateHash checks whether hash is a full hash, or a prefix of one when
+// prefix is true, of the hash algorithm of the repository.
+func ValidateHash(hash string, prefix bool) error {
+	a, err := ObjectFormat()
+	if err != nil {
+		return err
+	}
+
+	if len(hash) > a.HexLen() || (!prefix && len(hash) != a.HexLen()) {
+		return fmt.Errorf("%w: %q should be %v hexadecimal characters for %v", ErrInvalidHash, hash, a.HexLen(), a)
+	}
+
+	for _, c := range hash {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return fmt.Errorf("%w: %q", ErrInvalidHash, hash)
+		}
+	}
+
+	return nil
 }
 
 // FetchByHash will fetch an object from object database by its hash.
 func FetchByHash(hash string) (Object, error) {
-	ok, err := ExistsMainDir()
+	fullHash, name, err := resolveHash(hash)
+	if err != nil {
+		return Object{}, err
+	}
+
+	rf, err := os.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Object{}, ErrObjectNotFound
+		}
+
+		return Object{}, err
+	}
+
+	return Object{Hash: fullHash, Content: rf}, nil
+}
+
+// FetchPrefixByHash is like FetchByHash, but only reads up to the first n
+// bytes of the object. It is used to read the header of an object without
+// reading the whole object.
+func FetchPrefixByHash(hash string, n int) (Object, error) {
+	fullHash, name, err := resolveHash(hash)
 	if err != nil {
 		return Object{}, err
 	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Object{}, ErrObjectNotFound
+		}
+
+		return Object{}, err
+	}
+	defer f.Close()
+
+	b := make([]byte, n)
+	read, err := io.ReadFull(f, b)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return Object{}, err
+	}
+
+	return Object{Hash: fullHash, Content: b[:read]}, nil
+}
+
+// resolveHash finds the object whose hash starts with hash and returns
+// its full hash and the path of its file in the object database.
+func resolveHash(hash string) (fullHash string, name string, e error) {
+	ok, err := ExistsMainDir()
+	if err != nil {
+		return "", "", err
+	}
 	if !ok {
-		return Object{}, ErrRepoNotInitialized
+		return "", "", ErrRepoNotInitialized
 	}
 
 	if len(hash) < 2 {
-		return Object{}, ErrHashIsShort
+		return "", "", ErrHashIsShort
+	}
+
+	if err = ValidateHash(hash, true); err != nil {
+		return "", "", err
 	}
 
 	dirName := hash[:2]
 	dirPath := path.Join(objectDir, dirName)
 	if _, err := os.Stat(dirPath); err != nil {
 		if os.IsNotExist(err) {
-			return Object{}, ErrObjectNotFound
+			return "", "", ErrObjectNotFound
 		}
 
-		return Object{}, err
+		return "", "", err
 	}
 
 	objectsInDir, err := fetchAllFileNamesInDir(dirPath)
 	if err != nil {
-		return Object{}, err
+		return "", "", err
 	}
 	if len(objectsInDir) == 0 {
-		return Object{}, ErrObjectNotFound
-	}
-	readFile := func(name string) (Object, error) {
-		rf, err := os.ReadFile(name)
-		if err != nil {
-			if os.IsNotExist(err) {
-				return Object{}, ErrObjectNotFound
-			}
-
-			return Object{}, err
-		}
-
-		return Object{Hash: filepath.Base(name), Content: rf}, nil
+		return "", "", ErrObjectNotFound
 	}
 
 	prependToAll := func(co []string, s string) []string {
@@ -159,28 +318,29 @@ func FetchByHash(hash string) (Object, error) {
 		// If user provided hash length is 2 and there is only
 		// one object in that dirPath, return it.
 		if len(objectsInDir) == 1 {
-			return readFile(path.Join(dirPath, objectsInDir[0]))
+			return dirName + objectsInDir[0], path.Join(dirPath, objectsInDir[0]), nil
 		}
 
-		return Object{}, &HashCollisionError{Collisions: prependToAll(objectsInDir, dirName)}
+		return "", "", &HashCollisionError{Collisions: prependToAll(objectsInDir, dirName)}
 	}
 
 	var candidates []string
 	for _, c := range objectsInDir {
-		if strings.Contains(dirName+c, hash) {
+		if strings.HasPrefix(dirName+c, hash) {
 			candidates = append(candidates, c)
 		}
 	}
 
 	if len(candidates) == 0 {
-		return Object{}, ErrObjectNotFound
+		return "", "", ErrObjectNotFound
 	}
 
 	if len(candidates) > 1 {
-		return Object{}, &HashCollisionError{Collisions: prependToAll(candidates, dirName)}
+		return "", "", &HashCollisionError{Collisions: prependToAll(candidates, dirName)}
 	}
 
-	return readFile(path.Join(dirPath, candidates[0]))
+	// The object hash is its directory name followed by its file name.
+	return dirName + candidates[0], path.Join(dirPath, candidates[0]), nil
 }
 
 // FetchAllObjectNames will fetch all object names from object database.
@@ -217,6 +377,9 @@ func FetchAllObjectNames() ([]string, error) {
 			if f.IsDir() {
 				return nil, ErrDirectoryIsNotExpected
 			}
+			if isTempObject(f.Name()) {
+				continue
+			}
 
 			output = append(output, d.Name()+f.Name())
 		}
@@ -225,6 +388,53 @@ func FetchAllObjectNames() ([]string, error) {
 	return output, nil
 }
 
+// ObjectModTime returns when the object with the full hash was written.
+func ObjectModTime(hash string) (time.Time, error) {
+	if len(hash) < 2 {
+		return time.Time{}, ErrHashIsShort
+	}
+
+	s, err := os.Stat(path.Join(objectDir, hash[:2], hash[2:]))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, ErrObjectNotFound
+		}
+
+		return time.Time{}, err
+	}
+
+	return s.ModTime(), nil
+}
+
+// Remove deletes the object with the full hash from the object database,
+// and its directory when it becomes empty.
+func Remove(hash string) error {
+	if len(hash) < 2 {
+		return ErrHashIsShort
+	}
+
+	dir := path.Join(objectDir, hash[:2])
+	if err := os.Remove(path.Join(dir, hash[2:])); err != nil {
+		if os.IsNotExist(err) {
+			return ErrObjectNotFound
+		}
+
+		return err
+	}
+
+	names, err := fetchAllFileNamesInDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		// Another object might have been written in dir meanwhile,
+		// in which case dir is not empty and is kept.
+		_ = os.Remove(dir)
+	}
+
+	return nil
+}
+
 // fetchAllFileNamesInDir will fetch all file names in a dir.
 func fetchAllFileNamesInDir(dirName string) ([]string, error) {
 	dir, err := os.ReadDir(dirName)
@@ -241,6 +451,9 @@ func fetchAllFileNamesInDir(dirName string) ([]string, error) {
 		if f.IsDir() {
 			return nil, ErrDirectoryIsNotExpected
 		}
+		if isTempObject(f.Name()) {
+			continue
+		}
 
 		output = append(output, f.Name())
 	}
@@ -248,15 +461,48 @@ func fetchAllFileNamesInDir(dirName string) ([]string, error) {
 	return output, nil
 }
 
-// objectExists checks whether name exists
-func objectExists(name string) (bool, error) {
-	if _, err := os.Stat(name); err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return false, nil
+// isTempObject checks whether name is the name of a temporary file which
+// an object is being written to, rather than the name of an object.
+func isTempObject(name string) bool {
+	return strings.HasPrefix(name, tempObjectPrefix)
+}
+
+// RemoveTempObjects removes the temporary files of object writes that were
+// modified before the given time. They are left behind when avc is killed
+// while writing an object, writes which are still running are newer.
+func RemoveTempObjects(before time.Time) ([]string, error) {
+	ok, err := ExistsMainDir()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRepoNotInitialized
+	}
+
+	matches, err := filepath.Glob(path.Join(objectDir, "*", tempObjectPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, m := range matches {
+		s, err := os.Stat(m)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return removed, err
+		}
+		if !s.ModTime().Before(before) {
+			continue
 		}
 
-		return false, err
+		if err = os.Remove(m); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, m)
 	}
 
-	return true, nil
+	return removed, nil
 }
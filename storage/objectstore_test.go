@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"armanVersionControl/hashing"
+	"errors"
+	"os"
+	"testing"
+)
+
+// initTempRepo changes into a new repository with objectFormat in a
+// temporary directory for the rest of the test.
+func initTempRepo(t *testing.T, objectFormat hashing.Algorithm) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err = Init(objectFormat); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreRejectsCollisionAttack(t *testing.T) {
+	content, err := os.ReadFile("../hashing/testdata/shattered-1.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initTempRepo(t, hashing.SHA1)
+
+	_, err = Store(content)
+	var cae *CollisionAttackError
+	if !errors.As(err, &cae) {
+		t.Fatalf("got error %v, want a CollisionAttackError", err)
+	}
+	if !errors.Is(err, hashing.ErrCollisionAttack) {
+		t.Errorf("%v does not wrap %v", err, hashing.ErrCollisionAttack)
+	}
+
+	if _, err = FetchByHash(cae.Hash); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("fetching the rejected object: got error %v, want %v", err, ErrObjectNotFound)
+	}
+}
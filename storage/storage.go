@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"armanVersionControl/hashing"
 	"errors"
 	"os"
 )
@@ -25,8 +26,9 @@ func ExistsMainDir() (bool, error) {
 	return true, err
 }
 
-// Init will initialize an empty avc repository.
-func Init() error {
+// Init will initialize an empty avc repository whose objects are
+// identified with objectFormat.
+func Init(objectFormat hashing.Algorithm) error {
 	ok, err := ExistsMainDir()
 	if err != nil {
 		return err
@@ -35,7 +37,11 @@ func Init() error {
 		return ErrAlreadyInitialized
 	}
 
-	return mkdirAllIfDoesNotExists(MainDir, dirPerm)
+	if err = mkdirAllIfDoesNotExists(MainDir, dirPerm); err != nil {
+		return err
+	}
+
+	return writeObjectFormat(objectFormat)
 }
 
 // mkdirAllIfDoesNotExists will make directories if they do not exist
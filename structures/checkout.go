@@ -0,0 +1,71 @@
+package structures
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+)
+
+var (
+	dirPerm os.FileMode = 0777
+)
+
+// ReadEntryContent reads the content to store in a Blob for the file at name.
+// For a symbolic link the content is the link target, otherwise it is the
+// content of the file.
+func ReadEntryContent(name string, mode EntryMode) ([]byte, error) {
+	switch mode {
+	case ModeSymlink:
+		target, err := os.Readlink(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(target), nil
+	case ModeRegular, ModeExecutable:
+		return os.ReadFile(name)
+	}
+
+	return nil, fmt.Errorf("can not read content of '%v' with mode %v", name, mode)
+}
+
+// WriteEntry writes content to name in the working tree based on mode.
+// Regular files are written with 0666 and executable files with 0777,
+// both are reduced by the umask. A symbolic link is created pointing to
+// content. Any existing file at name is replaced.
+func WriteEntry(name string, mode EntryMode, content []byte) error {
+	if err := os.MkdirAll(path.Dir(name), dirPerm); err != nil {
+		return err
+	}
+
+	// Remove the previous file first, because os.WriteFile will not change
+	// permission of an existing file and os.Symlink fails if name exists.
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	switch mode {
+	case ModeSymlink:
+		return os.Symlink(string(content), name)
+	case ModeRegular:
+		return os.WriteFile(name, content, 0o666)
+	case ModeExecutable:
+		return os.WriteFile(name, content, 0o777)
+	}
+
+	return fmt.Errorf("can not write '%v' with mode %v", name, mode)
+}
+
+// RestoreModifiedDate sets the modification time of name to mtime. Nothing is
+// done when mtime is zero (not recorded) or name is a symbolic link, because
+// os.Chtimes follows links and would change the target instead.
+func RestoreModifiedDate(name string, mode EntryMode, mtime time.Time) error {
+	if mtime.IsZero() || mode == ModeSymlink {
+		return nil
+	}
+
+	return os.Chtimes(name, mtime, mtime)
+}
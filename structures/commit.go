@@ -1,18 +1,27 @@
 package structures
 
 import (
+	"armanVersionControl/storage"
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
 const (
 	// currentCommitVersion represents the latest (current) version of Commit.
 	currentCommitVersion uint16 = 0
+	// commitMagicNumber represents the Commit unique identifier.
+	commitMagicNumber uint16 = 300
 	// currentCommitSignature represents the latest (current) signature of Commit.
-	currentCommitSignature uint16 = 300 + currentCommitVersion
+	currentCommitSignature = commitMagicNumber + currentCommitVersion
 )
 
-var currentCommitHeader = []byte(fmt.Sprintf("%v \u0000", currentCommitSignature))
+var (
+	ErrNotACommit = errors.New("not a valid Commit")
+)
 
 // Commit represents the structure of a basic commit.
 // The signature value of a Commit ranges from 300 to 399.
@@ -21,23 +30,29 @@ var currentCommitHeader = []byte(fmt.Sprintf("%v \u0000", currentCommitSignature
 // of the commit structure. For example, a Signature value of 321
 // indicates that this file is a basic commit with a structure version of 21.
 type Commit struct {
-	// ParentHash represents the hash of the
-	// previous commit which this commit is based on.
-	// In case of the root commit, ParentHash would be empty.
-	ParentHash string
+	// Hash represents Commit hash (AKA filename) that is stored in avc object store.
+	Hash string
+	// TreeHash represents the hash of the Tree which is the snapshot
+	// of the working directory when this commit was created.
+	TreeHash string
+	// ParentHashes represents the hashes of the previous commits which
+	// this commit is based on. A merge commit has more than one parent
+	// and in case of the root commit, ParentHashes would be empty.
+	ParentHashes []string
 	// Author is the name of the author.
 	Author string
 	// AuthorEmail is the email of the author.
 	AuthorEmail string
+	// AuthorDate is the date when the change was originally made.
+	AuthorDate time.Time
 	// Commiter is the name of the commiter.
 	Commiter string
 	// CommiterEmail is the email of the commiter.
 	CommiterEmail string
 	// CommitDate is the date when this commit was created
 	CommitDate time.Time
-	// Tree represents the snapshot of the working directory
-	// when this commit was created.
-	Tree Tree
+	// Message is the commit message.
+	Message string
 }
 
 // IsCommit checks whether the signature is a Commit signature.
@@ -45,21 +60,204 @@ func IsCommit(signature uint16) bool {
 	return signature >= 300 && signature <= 399
 }
 
+// IsCommitB checks whether the content starts with the correct Commit
+// header (AKA signature).
+func IsCommitB(content []byte) bool {
+	return isKind(content, ObjectCommit)
+}
+
+// ObjectKind returns ObjectCommit.
+func (c Commit) ObjectKind() ObjectKind {
+	return ObjectCommit
+}
+
 // IsRoot will check whether c is a root commit
 func (c Commit) IsRoot() bool {
-	return c.ParentHash == ""
+	return len(c.ParentHashes) == 0
 }
 
 // New will create a new Commit.
-func New(parentHash string, author string, authorEmail string, commiter string,
-	commiterEmail string, commitDate time.Time, tree Tree) *Commit {
+func New(treeHash string, parentHashes []string, author string, authorEmail string, authorDate time.Time,
+	commiter string, commiterEmail string, commitDate time.Time, message string) *Commit {
 	return &Commit{
-		ParentHash:    parentHash,
+		TreeHash:      treeHash,
+		ParentHashes:  parentHashes,
 		Author:        author,
 		AuthorEmail:   authorEmail,
+		AuthorDate:    authorDate,
 		Commiter:      commiter,
 		CommiterEmail: commiterEmail,
 		CommitDate:    commitDate,
-		Tree:          tree,
+		Message:       message,
+	}
+}
+
+// FileRepresent will create a file representation of a Commit in binary format.
+// After the header, TreeHash, the number of parents followed by each parent hash,
+// author, author email, author date, commiter, commiter email, commit date and
+// the message are written. Strings and dates are prefixed with their length.
+func (c Commit) FileRepresent() ([]byte, error) {
+	if c.TreeHash == "" {
+		return nil, errors.New("commit should point to a tree")
+	}
+
+	var buf bytes.Buffer
+
+	writeBuf := func(b []byte) error {
+		if err := binary.Write(&buf, binary.BigEndian, int32(len(b))); err != nil {
+			return err
+		}
+		buf.Write(b)
+
+		return nil
+	}
+
+	if err := writeBuf([]byte(c.TreeHash)); err != nil {
+		return nil, err
+	}
+
+	if err := binary.Write(&buf, binary.BigEndian, int32(len(c.ParentHashes))); err != nil {
+		return nil, err
+	}
+	for _, p := range c.ParentHashes {
+		if err := writeBuf([]byte(p)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range []any{c.Author, c.AuthorEmail, c.AuthorDate, c.Commiter, c.CommiterEmail, c.CommitDate, c.Message} {
+		var b []byte
+		switch v := f.(type) {
+		case string:
+			b = []byte(v)
+		case time.Time:
+			// MarshalBinary keeps the zone offset, so the date is shown
+			// the same way it was when the commit was created.
+			var err error
+			if b, err = v.MarshalBinary(); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := writeBuf(b); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(EncodeHeader(currentCommitSignature, buf.Len()), buf.Bytes()...), nil
+}
+
+// NewCommitFromObject creates a Commit from storage.Object.
+func NewCommitFromObject(o storage.Object) (Commit, error) {
+	info, err := identifyAs(o.Content, ObjectCommit)
+	if err != nil {
+		return Commit{}, err
+	}
+
+	r := bytes.NewReader(o.Content[info.HeaderLen:])
+	readInt := func() (int32, error) {
+		var i int32
+		err := binary.Read(r, binary.BigEndian, &i)
+		return i, err
+	}
+	readBuf := func() ([]byte, error) {
+		count, err := readInt()
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 || int(count) > r.Len() {
+			return nil, ErrNotACommit
+		}
+
+		buf := make([]byte, count)
+		if _, err = r.Read(buf); err != nil && count > 0 {
+			return nil, err
+		}
+
+		return buf, nil
+	}
+	readString := func(s *string) error {
+		b, err := readBuf()
+		*s = string(b)
+		return err
+	}
+	readTime := func(t *time.Time) error {
+		b, err := readBuf()
+		if err != nil {
+			return err
+		}
+
+		return t.UnmarshalBinary(b)
+	}
+
+	c := Commit{Hash: o.Hash}
+	if err = readString(&c.TreeHash); err != nil {
+		return Commit{}, err
+	}
+
+	parents, err := readInt()
+	if err != nil {
+		return Commit{}, err
+	}
+	for range parents {
+		var p string
+		if err = readString(&p); err != nil {
+			return Commit{}, err
+		}
+		c.ParentHashes = append(c.ParentHashes, p)
+	}
+
+	err = errors.Join(
+		readString(&c.Author),
+		readString(&c.AuthorEmail),
+		readTime(&c.AuthorDate),
+		readString(&c.Commiter),
+		readString(&c.CommiterEmail),
+		readTime(&c.CommitDate),
+		readString(&c.Message),
+	)
+	if err != nil {
+		return Commit{}, err
+	}
+
+	if r.Len() != 0 {
+		return Commit{}, ErrNotACommit
 	}
+
+	return c, nil
+}
+
+// StoreCommit will store Commit in the avc object store.
+// Returns the hash of Commit when stored in avc repository.
+func (c Commit) StoreCommit() (string, error) {
+	b, err := c.FileRepresent()
+	if err != nil {
+		return "", err
+	}
+
+	return storage.Store(b)
+}
+
+// FetchTree retrieves the Tree of the commit from the object database.
+func (c Commit) FetchTree() (Tree, error) {
+	o, err := storage.FetchByHash(c.TreeHash)
+	if err != nil {
+		return Tree{}, err
+	}
+
+	return NewTreeFromObject(o)
+}
+
+func (c Commit) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("tree %v\n", c.TreeHash))
+	for _, p := range c.ParentHashes {
+		sb.WriteString(fmt.Sprintf("parent %v\n", p))
+	}
+	sb.WriteString(fmt.Sprintf("author %v <%v> %v\n", c.Author, c.AuthorEmail, c.AuthorDate.Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("commiter %v <%v> %v\n", c.Commiter, c.CommiterEmail, c.CommitDate.Format(time.RFC3339)))
+	sb.WriteString("\n")
+	sb.WriteString(c.Message)
+
+	return sb.String()
 }
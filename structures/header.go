@@ -0,0 +1,123 @@
+package structures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Every object starts with a header which is laid out as:
+//
+//	"avc"			3 bytes, marks the header format.
+//	signature		2 bytes, the kind and the version of the object.
+//	payload length	unsigned varint, the length of the content after the header.
+//
+// The signature is stored in BigEndian because that is the network byte order,
+// plus that's how git represents numbers in the file as well. Knowing the payload
+// length from the header means the size of an object is known without reading
+// the whole object, and a truncated object can be detected.
+//
+// Objects written before this header format start with a legacy header, which is
+// the two signature bytes formatted with "%v \u0000", e.g. "[0 200] \x00" for a
+// Tree version 0. Legacy objects are still readable, their payload is everything
+// after the header. New objects are always written with the current header, so
+// storing the content of a legacy object again results in a new object and hash.
+
+const (
+	// headerMagic marks the start of the current header format.
+	headerMagic = "avc"
+	// MaxHeaderLen is the maximum length of the current header format in bytes.
+	MaxHeaderLen = len(headerMagic) + 2 + binary.MaxVarintLen64
+)
+
+var (
+	ErrInvalidHeader = errors.New("content does not start with a valid header")
+	ErrSizeMismatch  = errors.New("payload length does not match the length in the header")
+)
+
+// header represents a parsed header.
+type header struct {
+	signature uint16
+	// length is the length of the header in bytes.
+	length int
+	// payloadLen is the payload length stored in the header,
+	// it is -1 for legacy headers which do not store it.
+	payloadLen int64
+}
+
+// EncodeHeader returns the header of an object or a file with signature
+// and a payload of payloadLen bytes.
+func EncodeHeader(signature uint16, payloadLen int) []byte {
+	b := []byte(headerMagic)
+	b = binary.BigEndian.AppendUint16(b, signature)
+	return binary.AppendUvarint(b, uint64(payloadLen))
+}
+
+// parseHeader parses the header at the start of content. content can be only
+// the first bytes of an object, the payload length is not checked here.
+func parseHeader(content []byte) (header, error) {
+	if !bytes.HasPrefix(content, []byte(headerMagic)) {
+		signature, headerLen, err := parseLegacySignature(content)
+		if err != nil {
+			return header{}, err
+		}
+
+		return header{signature: signature, length: headerLen, payloadLen: -1}, nil
+	}
+
+	b := content[len(headerMagic):]
+	if len(b) < 2 {
+		return header{}, ErrInvalidHeader
+	}
+	signature := binary.BigEndian.Uint16(b)
+
+	payloadLen, n := binary.Uvarint(b[2:])
+	if n <= 0 || payloadLen > 1<<62 {
+		return header{}, ErrInvalidHeader
+	}
+
+	return header{signature: signature, length: len(headerMagic) + 2 + n, payloadLen: int64(payloadLen)}, nil
+}
+
+// parseLegacySignature parses the legacy header at the start of content and
+// returns the signature it carries together with the length of the header in bytes.
+func parseLegacySignature(content []byte) (signature uint16, headerLen int, err error) {
+	end := bytes.Index(content, []byte(" \u0000"))
+	if end < 0 {
+		return 0, 0, ErrInvalidHeader
+	}
+
+	var hi, lo uint8
+	n, err := fmt.Sscanf(string(content[:end]), "[%d %d]", &hi, &lo)
+	if err != nil || n != 2 {
+		return 0, 0, ErrInvalidHeader
+	}
+
+	// Make sure the header round trips, otherwise any content that happens
+	// to look similar to a header would be accepted.
+	signature = uint16(hi)<<8 | uint16(lo)
+	headerLen = end + len(" \u0000")
+	if !bytes.Equal(content[:headerLen], []byte(fmt.Sprintf("%v \u0000", []byte{hi, lo}))) {
+		return 0, 0, ErrInvalidHeader
+	}
+
+	return signature, headerLen, nil
+}
+
+// SplitHeader splits content into the signature of its header and the
+// payload that comes after the header. It fails with ErrSizeMismatch when
+// the payload length does not match the length in the header.
+func SplitHeader(content []byte) (signature uint16, payload []byte, err error) {
+	h, err := parseHeader(content)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	payload = content[h.length:]
+	if h.payloadLen >= 0 && int64(len(payload)) != h.payloadLen {
+		return 0, nil, ErrSizeMismatch
+	}
+
+	return h.signature, payload, nil
+}
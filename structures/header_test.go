@@ -0,0 +1,75 @@
+package structures
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	tests := []struct {
+		signature  uint16
+		payloadLen int
+	}{
+		{currentBlobSignature, 0},
+		{currentTreeSignature, 1},
+		{currentCommitSignature, 127},
+		{currentBlobSignature, 128},
+		{499, 1 << 20},
+	}
+
+	for _, tt := range tests {
+		payload := bytes.Repeat([]byte{'x'}, tt.payloadLen)
+		h := EncodeHeader(tt.signature, tt.payloadLen)
+		if len(h) > MaxHeaderLen {
+			t.Errorf("header of %v bytes is longer than MaxHeaderLen %v", len(h), MaxHeaderLen)
+		}
+
+		signature, got, err := SplitHeader(append(h, payload...))
+		if err != nil {
+			t.Errorf("SplitHeader(%v, %v bytes): %v", tt.signature, tt.payloadLen, err)
+			continue
+		}
+		if signature != tt.signature || !bytes.Equal(got, payload) {
+			t.Errorf("SplitHeader(%v, %v bytes) = %v, %v bytes", tt.signature, tt.payloadLen, signature, len(got))
+		}
+	}
+}
+
+func TestSplitLegacyHeader(t *testing.T) {
+	content := append([]byte(fmt.Sprintf("%v \u0000", []byte{0, 200})), "entries"...)
+
+	signature, payload, err := SplitHeader(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature != 200 || string(payload) != "entries" {
+		t.Errorf("SplitHeader(%q) = %v, %q, want 200, %q", content, signature, payload, "entries")
+	}
+}
+
+func TestSplitHeaderRejectsInvalidHeaders(t *testing.T) {
+	valid := EncodeHeader(currentBlobSignature, 3)
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    error
+	}{
+		{"empty", nil, ErrInvalidHeader},
+		{"no header", []byte("hello"), ErrInvalidHeader},
+		{"truncated signature", valid[:4], ErrInvalidHeader},
+		{"missing payload length", valid[:5], ErrInvalidHeader},
+		{"truncated payload length", EncodeHeader(currentBlobSignature, 300)[:6], ErrInvalidHeader},
+		{"legacy lookalike", []byte("[0 0200] \u0000"), ErrInvalidHeader},
+		{"short payload", append(valid, "ab"...), ErrSizeMismatch},
+		{"long payload", append(valid, "abcd"...), ErrSizeMismatch},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := SplitHeader(tt.content); !errors.Is(err, tt.want) {
+			t.Errorf("%v: got error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package structures
+
+import (
+	"armanVersionControl/storage"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ObjectKind represents the type of an object stored in the object database.
+type ObjectKind int32
+
+func (k ObjectKind) String() string {
+	return []string{"blob", "tree", "commit"}[k]
+}
+
+const (
+	ObjectBlob ObjectKind = iota
+	ObjectTree
+	ObjectCommit
+)
+
+// TypedObject is implemented by every structure that is stored as an object
+// in the object database.
+type TypedObject interface {
+	// ObjectKind returns the kind of the object.
+	ObjectKind() ObjectKind
+}
+
+// UnknownSignatureError represents an error for content whose header carries
+// a signature that does not belong to any known object kind.
+type UnknownSignatureError struct {
+	Signature uint16
+}
+
+func (u *UnknownSignatureError) Error() string {
+	return fmt.Sprintf("signature %v does not belong to any known object kind", u.Signature)
+}
+
+// UnsupportedVersionError represents an error for an object whose structure
+// version is newer than the versions this build of avc can read.
+type UnsupportedVersionError struct {
+	Kind ObjectKind
+	// Version is the version of the object.
+	Version uint16
+	// Current is the latest version this build of avc supports.
+	Current uint16
+}
+
+func (u *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("%v version %v is not supported, the latest supported version is %v", u.Kind, u.Version, u.Current)
+}
+
+// objectType describes an object kind in the registry.
+type objectType struct {
+	kind ObjectKind
+	// magicNumber is the first signature of the kind, the following 99
+	// signatures are the next versions of the kind.
+	magicNumber uint16
+	// currentVersion is the version new objects are written with.
+	currentVersion uint16
+	// errNotKind is returned when content is expected to be of this kind but is not.
+	errNotKind error
+	// decode decodes a stored object of this kind.
+	decode func(o storage.Object) (TypedObject, error)
+}
+
+// registry holds every object kind, ordered by ObjectKind. Signatures from 400
+// to 499 are reserved for the Index, which is not stored in the object database.
+var registry []objectType
+
+func init() {
+	registry = []objectType{
+		{
+			kind:           ObjectBlob,
+			magicNumber:    blobMagicNumber,
+			currentVersion: currentBlobVersion,
+			errNotKind:     ErrNotABlob,
+			decode: func(o storage.Object) (TypedObject, error) {
+				return NewBlobFromB(o.Content)
+			},
+		},
+		{
+			kind:           ObjectTree,
+			magicNumber:    treeMagicNumber,
+			currentVersion: currentTreeVersion,
+			errNotKind:     ErrNotATree,
+			decode: func(o storage.Object) (TypedObject, error) {
+				return NewTreeFromObject(o)
+			},
+		},
+		{
+			kind:           ObjectCommit,
+			magicNumber:    commitMagicNumber,
+			currentVersion: currentCommitVersion,
+			errNotKind:     ErrNotACommit,
+			decode: func(o storage.Object) (TypedObject, error) {
+				return NewCommitFromObject(o)
+			},
+		},
+	}
+}
+
+// ParseObjectKind returns the ObjectKind named name, e.g. "tree".
+func ParseObjectKind(name string) (ObjectKind, error) {
+	i := slices.IndexFunc(registry, func(ot objectType) bool {
+		return ot.kind.String() == name
+	})
+	if i < 0 {
+		return 0, fmt.Errorf("unknown object type %q, it should either be blob, tree or commit", name)
+	}
+
+	return registry[i].kind, nil
+}
+
+// ObjectInfo describes a stored object based on its header.
+type ObjectInfo struct {
+	// Kind is the kind of the object.
+	Kind ObjectKind
+	// Version is the structure version of the object.
+	Version uint16
+	// HeaderLen is the length of the header in bytes, the payload
+	// of the object starts right after it.
+	HeaderLen int
+	// Size is the length of the payload in bytes. It is -1 when only
+	// the header of a legacy object is identified.
+	Size int64
+	// Legacy is true when the object has a legacy header.
+	Legacy bool
+}
+
+// Identify reads the header of content, which is a whole stored object, and
+// reports which object it is. Objects with a version newer than the supported
+// ones are still identified, Decode reports them with an UnsupportedVersionError.
+func Identify(content []byte) (ObjectInfo, error) {
+	info, err := IdentifyHeader(content)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	payloadLen := int64(len(content) - info.HeaderLen)
+	if info.Legacy {
+		info.Size = payloadLen
+	}
+
+	if info.Size != payloadLen {
+		return ObjectInfo{}, ErrSizeMismatch
+	}
+
+	return info, nil
+}
+
+// IdentifyHeader is like Identify, but prefix only needs to be the first
+// MaxHeaderLen bytes of the object. The size of legacy objects is unknown
+// from their header, so ObjectInfo.Size is -1 for them.
+func IdentifyHeader(prefix []byte) (ObjectInfo, error) {
+	h, err := parseHeader(prefix)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	ot, err := lookupSignature(h.signature)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{
+		Kind:      ot.kind,
+		Version:   h.signature - ot.magicNumber,
+		HeaderLen: h.length,
+		Size:      h.payloadLen,
+		Legacy:    h.payloadLen < 0,
+	}, nil
+}
+
+// FetchInfo identifies the object with hash and returns its full hash. Only
+// the header of the object is read, unless it is a legacy object whose size
+// can only be known by reading the whole object.
+func FetchInfo(hash string) (string, ObjectInfo, error) {
+	o, err := storage.FetchPrefixByHash(hash, MaxHeaderLen)
+	if err != nil {
+		return "", ObjectInfo{}, err
+	}
+
+	info, err := IdentifyHeader(o.Content)
+	if err != nil {
+		return "", ObjectInfo{}, err
+	}
+
+	if info.Legacy {
+		if o, err = storage.FetchByHash(o.Hash); err != nil {
+			return "", ObjectInfo{}, err
+		}
+
+		if info, err = Identify(o.Content); err != nil {
+			return "", ObjectInfo{}, err
+		}
+	}
+
+	return o.Hash, info, nil
+}
+
+// Decode decodes any stored object into its structure, which is either a
+// Blob, a Tree or a Commit.
+func Decode(o storage.Object) (TypedObject, error) {
+	info, err := Identify(o.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return registry[info.Kind].decode(o)
+}
+
+// Encode prepends the current header of kind to payload and returns the
+// result, which is how the object is stored in the object database. payload
+// is checked to be a valid object of kind.
+func Encode(kind ObjectKind, payload []byte) ([]byte, error) {
+	ot := registry[kind]
+	b := append(EncodeHeader(ot.magicNumber+ot.currentVersion, len(payload)), payload...)
+	if _, err := ot.decode(storage.Object{Content: b}); err != nil {
+		return nil, fmt.Errorf("content is not a valid %v: %w", kind, err)
+	}
+
+	return b, nil
+}
+
+// isKind checks whether content is an object of kind, regardless of its version.
+func isKind(content []byte, kind ObjectKind) bool {
+	info, err := Identify(content)
+	return err == nil && info.Kind == kind
+}
+
+// identifyAs identifies content and makes sure it is an object of kind with
+// a supported version.
+func identifyAs(content []byte, kind ObjectKind) (ObjectInfo, error) {
+	info, err := Identify(content)
+	if err != nil {
+		var use *UnknownSignatureError
+		if errors.Is(err, ErrInvalidHeader) || errors.As(err, &use) {
+			return ObjectInfo{}, registry[kind].errNotKind
+		}
+
+		return ObjectInfo{}, err
+	}
+
+	if info.Kind != kind {
+		return ObjectInfo{}, registry[kind].errNotKind
+	}
+
+	if current := registry[kind].currentVersion; info.Version > current {
+		return ObjectInfo{}, &UnsupportedVersionError{Kind: kind, Version: info.Version, Current: current}
+	}
+
+	return info, nil
+}
+
+// lookupSignature finds the object kind signature belongs to.
+func lookupSignature(signature uint16) (objectType, error) {
+	for _, ot := range registry {
+		if signature >= ot.magicNumber && signature < ot.magicNumber+100 {
+			return ot, nil
+		}
+	}
+
+	return objectType{}, &UnknownSignatureError{Signature: signature}
+}
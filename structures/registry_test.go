@@ -0,0 +1,160 @@
+package structures
+
+import (
+	"armanVersionControl/storage"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	testBlobHash = "2e65efe2a145dda7ee51d1741299f848e5bf752e"
+	testTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+)
+
+func TestIdentifyAndDecodeRoundTrip(t *testing.T) {
+	tree := Tree{Entries: []*TreeEntry{
+		{Kind: KindBlob, Mode: ModeExecutable, EntryHash: testBlobHash, Name: "run.sh"},
+		{Kind: KindTree, Mode: ModeTree, EntryHash: testTreeHash, Name: "sub"},
+		{Kind: KindBlob, Mode: ModeSymlink, EntryHash: testBlobHash, Name: "link"},
+	}}
+	treeContent, err := tree.FileRepresent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("", 3600))
+	commit := New(testTreeHash, []string{testBlobHash}, "A", "a@x", date, "C", "c@x", date, "message\n")
+	commitContent, err := commit.FileRepresent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blob := Blob{Content: []byte("hello\n")}
+
+	tests := []struct {
+		kind    ObjectKind
+		content []byte
+		want    TypedObject
+	}{
+		{ObjectBlob, blob.FileRepresent(), blob},
+		{ObjectTree, treeContent, tree},
+		{ObjectCommit, commitContent, *commit},
+	}
+
+	for _, tt := range tests {
+		info, err := Identify(tt.content)
+		if err != nil {
+			t.Errorf("Identify %v: %v", tt.kind, err)
+			continue
+		}
+
+		want := ObjectInfo{
+			Kind:      tt.kind,
+			Version:   registry[tt.kind].currentVersion,
+			HeaderLen: info.HeaderLen,
+			Size:      int64(len(tt.content) - info.HeaderLen),
+		}
+		if info != want {
+			t.Errorf("Identify %v = %+v, want %+v", tt.kind, info, want)
+		}
+
+		got, err := Decode(storage.Object{Content: tt.content})
+		if err != nil {
+			t.Errorf("Decode %v: %v", tt.kind, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Decode %v = %+v, want %+v", tt.kind, got, tt.want)
+		}
+
+		encoded, err := Encode(tt.kind, tt.content[info.HeaderLen:])
+		if err != nil {
+			t.Errorf("Encode %v: %v", tt.kind, err)
+		}
+		if !bytes.Equal(encoded, tt.content) {
+			t.Errorf("Encode %v does not round-trip", tt.kind)
+		}
+	}
+}
+
+func TestDecodeLegacyObjects(t *testing.T) {
+	legacy := func(signature uint16, payload []byte) []byte {
+		return append([]byte(fmt.Sprintf("%v \u0000", []byte{byte(signature >> 8), byte(signature)})), payload...)
+	}
+
+	blob := legacy(blobMagicNumber, []byte("hello"))
+	info, err := Identify(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ObjectInfo{Kind: ObjectBlob, HeaderLen: len(blob) - 5, Size: 5, Legacy: true}
+	if info != want {
+		t.Errorf("Identify legacy blob = %+v, want %+v", info, want)
+	}
+
+	info, err = IdentifyHeader(blob[:min(len(blob), MaxHeaderLen)])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Legacy || info.Size != -1 {
+		t.Errorf("IdentifyHeader legacy blob = %+v, want an unknown size", info)
+	}
+
+	// Version 0 trees have no modes, their blobs are regular files.
+	var payload bytes.Buffer
+	for _, f := range []any{int32(KindBlob), int32(len(testBlobHash)), []byte(testBlobHash), int32(len("a.txt")), []byte("a.txt")} {
+		binary.Write(&payload, binary.BigEndian, f)
+	}
+	got, err := Decode(storage.Object{Content: legacy(treeMagicNumber, payload.Bytes())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTree := Tree{Entries: []*TreeEntry{{Kind: KindBlob, Mode: ModeRegular, EntryHash: testBlobHash, Name: "a.txt"}}}
+	if !reflect.DeepEqual(got, wantTree) {
+		t.Errorf("Decode legacy tree = %+v, want %+v", got, wantTree)
+	}
+}
+
+func TestIdentifyRejectsTruncatedObjects(t *testing.T) {
+	content := Blob{Content: []byte("hello\n")}.FileRepresent()
+
+	if _, err := Identify(content[:len(content)-1]); !errors.Is(err, ErrSizeMismatch) {
+		t.Errorf("truncated payload: got error %v, want %v", err, ErrSizeMismatch)
+	}
+
+	// The header alone is enough to identify the object.
+	info, err := IdentifyHeader(content[:len(content)-6])
+	if err != nil || info.Size != 6 {
+		t.Errorf("IdentifyHeader = %+v, %v, want a size of 6", info, err)
+	}
+
+	for n := range len(content) - 6 {
+		if _, err := IdentifyHeader(content[:n]); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("header truncated to %v bytes: got error %v, want %v", n, err, ErrInvalidHeader)
+		}
+	}
+}
+
+func TestIdentifyRejectsUnknownSignatures(t *testing.T) {
+	var use *UnknownSignatureError
+	if _, err := Identify(EncodeHeader(999, 0)); !errors.As(err, &use) || use.Signature != 999 {
+		t.Errorf("got error %v, want an UnknownSignatureError for 999", err)
+	}
+
+	// Newer versions are identified, but not decoded.
+	content := EncodeHeader(blobMagicNumber+currentBlobVersion+1, 0)
+	info, err := Identify(content)
+	if err != nil || info.Kind != ObjectBlob {
+		t.Errorf("Identify newer blob = %+v, %v", info, err)
+	}
+
+	var uve *UnsupportedVersionError
+	if _, err = Decode(storage.Object{Content: content}); !errors.As(err, &uve) {
+		t.Errorf("Decode newer blob: got error %v, want an UnsupportedVersionError", err)
+	}
+}
@@ -6,11 +6,12 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path"
 	"slices"
 	"strings"
-	"time"
 )
 
 type EntryKind int32
@@ -24,62 +25,94 @@ const (
 	KindBlob
 )
 
+// EntryMode represents the file mode of a TreeEntry. The values are the
+// same octal modes git uses, so they read familiar in listings.
+type EntryMode uint32
+
+func (m EntryMode) String() string {
+	return fmt.Sprintf("%06o", uint32(m))
+}
+
 const (
-	// currentTreeVersion represents the latest (current) version of Tree.
-	currentTreeVersion uint16 = 0
-	// treeMagicNumber represents the Tree unique identifier.
-	treeMagicNumber = 200
+	// ModeTree is the mode of a subdirectory.
+	ModeTree EntryMode = 0o040000
+	// ModeRegular is the mode of a regular, non-executable file.
+	ModeRegular EntryMode = 0o100644
+	// ModeExecutable is the mode of a regular file with the executable bit set.
+	ModeExecutable EntryMode = 0o100755
+	// ModeSymlink is the mode of a symbolic link. The content of the
+	// Blob is the link target.
+	ModeSymlink EntryMode = 0o120000
 )
 
-var (
-	// currentTreeSignature represents the latest (current) signature of Tree.
-	currentTreeSignature []byte
+// IsValid checks whether m is one of the known modes.
+func (m EntryMode) IsValid() bool {
+	return m == ModeTree || m == ModeRegular || m == ModeExecutable || m == ModeSymlink
+}
 
-	// currentTreeHeader represents the first few bytes of the file representation
-	// of a Tree. If any file starts with this header, we will know it's a Tree.
-	currentTreeHeader []byte
-)
+// Kind returns the EntryKind an entry with mode m holds.
+func (m EntryMode) Kind() EntryKind {
+	if m == ModeTree {
+		return KindTree
+	}
 
-var (
-	ErrNotATree = errors.New("not a valid Tree")
-)
+	return KindBlob
+}
 
-func init() {
-	currentTreeSignature = make([]byte, 2)
-	// BigEndian is chosen because that is the network byte order
-	// and will save few bytes when storing it in the file. Plus
-	// that's how git represents numbers in the file as well.
-	_, err := binary.Encode(currentTreeSignature, binary.BigEndian, treeMagicNumber+currentTreeVersion)
-	if err != nil {
-		panic(err)
+// ModeFromFileMode converts a file system mode to an EntryMode.
+// Only directories, regular files and symbolic links are supported.
+func ModeFromFileMode(fm fs.FileMode) (EntryMode, error) {
+	switch {
+	case fm.IsDir():
+		return ModeTree, nil
+	case fm&fs.ModeSymlink != 0:
+		return ModeSymlink, nil
+	case fm.IsRegular():
+		if fm.Perm()&0o111 != 0 {
+			return ModeExecutable, nil
+		}
+
+		return ModeRegular, nil
 	}
 
-	// Since Go file-level variables are initialized before the init function,
-	// I can't place this line where currentTreeHeader is defined.
-	// Otherwise, currentTreeSignature will be nil when currentTreeHeader is initialized.
-	// git adds a null byte in the header before the content starts. I don't think I'll need it,
-	// but oh well, who cares if there's a null byte in the header even if I don't need it?
-	currentTreeHeader = []byte(fmt.Sprintf("%v \u0000", currentTreeSignature))
+	return 0, fmt.Errorf("file mode %v is not supported", fm)
 }
 
+const (
+	// currentTreeVersion represents the latest (current) version of Tree.
+	// Version 1 added EntryMode to each TreeEntry.
+	currentTreeVersion uint16 = 1
+	// treeMagicNumber represents the Tree unique identifier.
+	treeMagicNumber uint16 = 200
+	// currentTreeSignature represents the latest (current) signature of Tree.
+	currentTreeSignature = treeMagicNumber + currentTreeVersion
+)
+
+var (
+	ErrNotATree           = errors.New("not a valid Tree")
+	ErrInvalidEntryName   = errors.New("invalid tree entry name")
+	ErrDuplicateEntryName = errors.New("duplicate tree entry name")
+	ErrUnsortedEntries    = errors.New("tree entries are not in canonical order")
+)
+
 // TreeEntry represents a single entry in a Tree structure.
 // Each entry can either be a subdirectory (Tree) or a file (Blob), but not
 // both. The Kind field specifies what type this entry holds.
 type TreeEntry struct {
 	// Kind specifies type of the entry.
 	Kind EntryKind
+	// Mode is the file mode of the entry, it is ModeTree when Kind is KindTree.
+	Mode EntryMode
 	// tree is accessible when Kind is KindTree
 	tree *Tree
 	// blob is accessible when Kind is KindBlob
 	blob *Blob
 	// EntryHash is the hash of the Blob's or the Tree's.
 	EntryHash string
-	// Name represents the file or directory name.
+	// Name represents the file or directory name. Nothing else about the
+	// file, like its modification time, owner or extended attributes, is
+	// recorded, so the same content always has the same hash.
 	Name string
-	// CreatedDate represents the creation date time of a Blob or Tree.
-	CreatedDate time.Time
-	// ModifiedDate represents the last date time of when TreeEntry was changed.
-	ModifiedDate time.Time
 }
 
 // Tree represents the structure of a directory and its files.
@@ -99,13 +132,72 @@ func IsTreeS(signature uint16) bool {
 // IsTreeB checks whether the content starts with the correct Tree
 // header (AKA signature).
 func IsTreeB(content []byte) bool {
-	if len(content) < len(currentTreeHeader) {
-		return false
+	return isKind(content, ObjectTree)
+}
+
+// ObjectKind returns ObjectTree.
+func (t Tree) ObjectKind() ObjectKind {
+	return ObjectTree
+}
+
+// ValidateEntryName checks whether name can be used as a TreeEntry.Name.
+// A name can not be empty, "." or "..", and can not contain '/' or NUL.
+func ValidateEntryName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("%w: %q", ErrInvalidEntryName, name)
 	}
 
-	b := content[:len(currentTreeHeader)]
-	return slices.Equal(b, currentTreeHeader) ||
-		IsTreeS(binary.BigEndian.Uint16(b))
+	return nil
+}
+
+// compareEntries defines the canonical order of entries in a Tree, which is
+// the byte-wise order of their names. Names are unique in a Tree, so this
+// order does not depend on how the entries were collected.
+func compareEntries(a, b *TreeEntry) int {
+	return strings.Compare(a.Name, b.Name)
+}
+
+// SortEntries sorts t.Entries in the canonical order.
+func (t *Tree) SortEntries() {
+	slices.SortFunc(t.Entries, compareEntries)
+}
+
+// validateEntries checks that every entry has a valid name and that
+// entries are in the canonical order without any duplicate names.
+func (t *Tree) validateEntries() error {
+	for i, te := range t.Entries {
+		if err := ValidateEntryName(te.Name); err != nil {
+			return err
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		c := compareEntries(t.Entries[i-1], te)
+		if c == 0 {
+			return fmt.Errorf("%w: %q", ErrDuplicateEntryName, te.Name)
+		}
+		if c > 0 {
+			return fmt.Errorf("%w: %q comes after %q", ErrUnsortedEntries, t.Entries[i-1].Name, te.Name)
+		}
+	}
+
+	return nil
+}
+
+// entryMode returns te.Mode, falling back to the default mode of te.Kind
+// for entries that were created without a mode.
+func (te *TreeEntry) entryMode() EntryMode {
+	if te.Mode != 0 {
+		return te.Mode
+	}
+
+	if te.Kind == KindTree {
+		return ModeTree
+	}
+
+	return ModeRegular
 }
 
 // FetchTree retrieves a Tree from the object database using the TreeEntry.EntryHash
@@ -159,10 +251,16 @@ func (te *TreeEntry) FetchBlob() (Blob, error) {
 }
 
 // FileRepresent will create a file representation of a Tree in binary format.
+// After the header, each entry is written as its Kind, Mode, EntryHash and
+// Name. Entries are sorted in the canonical order first, so the same entries
+// always produce the same hash.
 func (t *Tree) FileRepresent() ([]byte, error) {
-	var buf bytes.Buffer
+	t.SortEntries()
+	if err := t.validateEntries(); err != nil {
+		return nil, err
+	}
 
-	buf.Write(currentTreeHeader)
+	var buf bytes.Buffer
 
 	for _, te := range t.Entries {
 		err := binary.Write(&buf, binary.BigEndian, int32(te.Kind))
@@ -170,6 +268,11 @@ func (t *Tree) FileRepresent() ([]byte, error) {
 			return nil, err
 		}
 
+		err = binary.Write(&buf, binary.BigEndian, uint32(te.entryMode()))
+		if err != nil {
+			return nil, err
+		}
+
 		err = binary.Write(&buf, binary.BigEndian, int32(len(te.EntryHash)))
 		if err != nil {
 			return nil, err
@@ -183,7 +286,7 @@ func (t *Tree) FileRepresent() ([]byte, error) {
 		buf.WriteString(te.Name)
 	}
 
-	return buf.Bytes(), nil
+	return append(EncodeHeader(currentTreeSignature, buf.Len()), buf.Bytes()...), nil
 }
 
 // NewTreeFromPath creates a new Tree form a path but does not store the result
@@ -203,30 +306,39 @@ func NewTreeFromPath(name string) (Tree, error) {
 		}
 
 		te := TreeEntry{Name: d.Name()}
+		entryPath := path.Join(name, d.Name())
 
 		if d.Type().IsDir() {
-			t, err := NewTreeFromPath(path.Join(name, d.Name()))
+			t, err := NewTreeFromPath(entryPath)
 			if err != nil {
 				return Tree{}, err
 			}
 
 			te.Kind = KindTree
+			te.Mode = ModeTree
 			te.tree = &t
 
 			tree.Entries = append(tree.Entries, &te)
 			continue
 		}
 
-		if !d.Type().IsRegular() {
-			return Tree{}, fmt.Errorf("directory entries should either be a directory or regualr file which '%v' does not follow", path.Join(name, d.Name()))
+		info, err := d.Info()
+		if err != nil {
+			return Tree{}, err
 		}
 
-		c, err := os.ReadFile(path.Join(name, d.Name()))
+		mode, err := ModeFromFileMode(info.Mode())
+		if err != nil {
+			return Tree{}, fmt.Errorf("directory entries should either be a directory, regular file or symbolic link which '%v' does not follow", entryPath)
+		}
+
+		c, err := ReadEntryContent(entryPath, mode)
 		if err != nil {
 			return Tree{}, err
 		}
 
 		te.Kind = KindBlob
+		te.Mode = mode
 		te.blob = &Blob{Content: c}
 
 		tree.Entries = append(tree.Entries, &te)
@@ -235,14 +347,65 @@ func NewTreeFromPath(name string) (Tree, error) {
 	return tree, nil
 }
 
+// NewTreeFromEntries creates a Tree from entries whose Name is a slash
+// separated path relative to the root of the Tree, with a subtree for every
+// directory on the way. Like NewTreeFromPath nothing is stored, but the hashes
+// of the Tree and its subtrees are computed, so the EntryHash of every entry
+// is expected to be filled in.
+func NewTreeFromEntries(entries []*TreeEntry) (Tree, error) {
+	root := &Tree{}
+	dirs := map[string]*Tree{"": root}
+
+	var dir func(name string) *Tree
+	dir = func(name string) *Tree {
+		if t, ok := dirs[name]; ok {
+			return t
+		}
+
+		parent, base := path.Split(name)
+		t := &Tree{}
+		p := dir(strings.TrimSuffix(parent, "/"))
+		p.Entries = append(p.Entries, &TreeEntry{Kind: KindTree, Mode: ModeTree, Name: base, tree: t})
+		dirs[name] = t
+
+		return t
+	}
+
+	for _, te := range entries {
+		parent, base := path.Split(te.Name)
+		e := *te
+		e.Name = base
+
+		t := dir(strings.TrimSuffix(parent, "/"))
+		t.Entries = append(t.Entries, &e)
+	}
+
+	for _, t := range dirs {
+		t.SortEntries()
+		if err := t.validateEntries(); err != nil {
+			return Tree{}, err
+		}
+	}
+
+	h, err := root.ComputeHash()
+	if err != nil {
+		return Tree{}, err
+	}
+	root.Hash = h
+
+	return *root, nil
+}
+
 // NewTreeFromObject creates a Tree from objectstore.Object.
 func NewTreeFromObject(o storage.Object) (Tree, error) {
-	if !IsTreeB(o.Content) {
-		return Tree{}, ErrNotATree
+	info, err := identifyAs(o.Content, ObjectTree)
+	if err != nil {
+		return Tree{}, err
 	}
 	t := Tree{Hash: o.Hash}
+	version := info.Version
 
-	r := bytes.NewReader(o.Content[len(currentTreeHeader):])
+	r := bytes.NewReader(o.Content[info.HeaderLen:])
 
 	//pos := 0
 	for r.Len() > 0 {
@@ -250,14 +413,18 @@ func NewTreeFromObject(o storage.Object) (Tree, error) {
 
 		readBuf := func() ([]byte, error) {
 			countBuf := make([]byte, 4)
-			_, err := r.Read(countBuf)
+			_, err := io.ReadFull(r, countBuf)
 			if err != nil {
 				return nil, err
 			}
 
 			count := int32(binary.BigEndian.Uint32(countBuf))
+			if count < 0 || int(count) > r.Len() {
+				return nil, ErrNotATree
+			}
+
 			buf := make([]byte, count)
-			_, err = r.Read(buf)
+			_, err = io.ReadFull(r, buf)
 			if err != nil {
 				return nil, err
 			}
@@ -267,12 +434,25 @@ func NewTreeFromObject(o storage.Object) (Tree, error) {
 
 		// Parsing Kind
 		intBuf := make([]byte, 4)
-		_, err := r.Read(intBuf)
+		_, err := io.ReadFull(r, intBuf)
 		if err != nil {
 			return Tree{}, err
 		}
 		te.Kind = EntryKind(int32(binary.BigEndian.Uint32(intBuf)))
 
+		// Parsing Mode, version 0 had no modes so every blob is a regular file.
+		te.Mode = te.entryMode()
+		if version >= 1 {
+			_, err = io.ReadFull(r, intBuf)
+			if err != nil {
+				return Tree{}, err
+			}
+			te.Mode = EntryMode(binary.BigEndian.Uint32(intBuf))
+			if !te.Mode.IsValid() || te.Mode.Kind() != te.Kind {
+				return Tree{}, fmt.Errorf("invalid mode %v for an entry of kind %v", te.Mode, te.Kind)
+			}
+		}
+
 		// Parsing EntryHash
 		buf, err := readBuf()
 		if err != nil {
@@ -290,20 +470,103 @@ func NewTreeFromObject(o storage.Object) (Tree, error) {
 		t.Entries = append(t.Entries, &te)
 	}
 
+	if err = t.validateEntries(); err != nil {
+		return Tree{}, err
+	}
+
 	return t, nil
 }
 
+// Walk calls fn for each entry of t and all of its subtrees in the canonical
+// order, fetching subtrees from the object database as needed. The path
+// given to fn is the slash separated path of the entry relative to t.
+// Subtrees are visited right after fn is called with their entry, unless
+// fn returns fs.SkipDir for that entry.
+func (t *Tree) Walk(fn func(name string, te *TreeEntry) error) error {
+	return t.walk("", fn)
+}
+
+func (t *Tree) walk(prefix string, fn func(name string, te *TreeEntry) error) error {
+	for _, te := range t.Entries {
+		name := prefix + te.Name
+		err := fn(name, te)
+		if errors.Is(err, fs.SkipDir) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if te.Kind != KindTree {
+			continue
+		}
+
+		sub, err := te.FetchTree()
+		if err != nil {
+			return err
+		}
+
+		if err = sub.walk(name+"/", fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FetchTreeish retrieves the Tree with hash from the object database. When
+// hash belongs to a Commit, the Tree of the Commit is returned instead.
+func FetchTreeish(hash string) (Tree, error) {
+	o, err := storage.FetchByHash(hash)
+	if err != nil {
+		return Tree{}, err
+	}
+
+	if IsCommitB(o.Content) {
+		c, err := NewCommitFromObject(o)
+		if err != nil {
+			return Tree{}, err
+		}
+
+		return c.FetchTree()
+	}
+
+	if !IsTreeB(o.Content) {
+		return Tree{}, fmt.Errorf("object %v is neither a tree nor a commit", o.Hash)
+	}
+
+	return NewTreeFromObject(o)
+}
+
 // StoreTree will store Tree and all its Entries in the object store
-// and return the computed hash for Tree.
+// and return the computed hash for Tree. Entries that are only referenced
+// by their EntryHash are expected to be in the object store already.
 func (t *Tree) StoreTree() (string, error) {
+	return t.hash(true)
+}
+
+// ComputeHash computes the hash Tree gets when it is stored in the object
+// database without storing anything. Like StoreTree, the EntryHash of every
+// loaded entry is filled in.
+func (t *Tree) ComputeHash() (string, error) {
+	return t.hash(false)
+}
+
+// hash computes the hash of t and all its loaded entries and stores
+// them in the object store when store is true.
+func (t *Tree) hash(store bool) (string, error) {
 	for _, te := range t.Entries {
+		if te.tree == nil && te.blob == nil && te.EntryHash != "" {
+			continue
+		}
+
 		if te.Kind == KindTree {
 			teTree, err := te.FetchTree()
 			if err != nil {
 				return "", err
 			}
 
-			h, err := teTree.StoreTree()
+			h, err := teTree.hash(store)
 			if err != nil {
 				return "", err
 			}
@@ -320,7 +583,13 @@ func (t *Tree) StoreTree() (string, error) {
 		if err != nil {
 			return "", err
 		}
-		h, err := teBlob.StoreBlob()
+
+		var h string
+		if store {
+			h, err = teBlob.StoreBlob()
+		} else {
+			h, err = teBlob.ComputeHash()
+		}
 		if err != nil {
 			return "", err
 		}
@@ -333,14 +602,11 @@ func (t *Tree) StoreTree() (string, error) {
 		return "", err
 	}
 
-	h, err := storage.Store(b)
-	// Reuse the previous object of there is a duplicate error
-	var ode *storage.ObjectDuplicateError
-	if errors.As(err, &ode) {
-		return ode.Hash, nil
+	if !store {
+		return storage.ComputeHash(b)
 	}
 
-	return h, err
+	return storage.Store(b)
 }
 
 func (t *Tree) String() string {
@@ -350,18 +616,9 @@ func (t *Tree) String() string {
 
 	for _, te := range t.Entries {
 		sb.WriteString(fmt.Sprintf("Kind: %v ", te.Kind))
+		sb.WriteString(fmt.Sprintf("Mode: %v ", te.entryMode()))
 		sb.WriteString(fmt.Sprintf("Hash: %v ", te.EntryHash))
 		sb.WriteString(fmt.Sprintf("name: %v ", te.Name))
-		c, err := te.CreatedDate.MarshalText()
-		if err != nil {
-			panic(err)
-		}
-		sb.WriteString(fmt.Sprintf("created date: %v ", string(c)))
-		m, err := te.ModifiedDate.MarshalText()
-		if err != nil {
-			panic(err)
-		}
-		sb.WriteString(fmt.Sprintf("modified date: %v ", string(m)))
 
 		sb.WriteRune('\n')
 	}
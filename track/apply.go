@@ -0,0 +1,475 @@
+package track
+
+import (
+	"armanVersionControl/diff"
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrPatchFailed = errors.New("patch does not apply")
+	ErrConflicts   = errors.New("patch applied with conflicts")
+)
+
+// ApplyOptions controls what Apply applies patches to.
+type ApplyOptions struct {
+	// Check only checks whether the patches apply, without changing anything.
+	Check bool
+	// Cached applies the patches to the Index instead of the working tree.
+	Cached bool
+	// Index applies the patches to both the Index and the working tree. The
+	// patched files have to be the same in both.
+	Index bool
+	// ThreeWay merges the patch of a file whose hunks do not apply with
+	// diff.Merge, using the Blob of its "index" line as the base.
+	ThreeWay bool
+}
+
+// ApplyResult is the outcome of applying the patch of a single file.
+type ApplyResult struct {
+	Patch diff.FilePatch
+	// Hunks has a result for each hunk of the patch.
+	Hunks []diff.HunkResult
+	// Merged is set when the patch was merged because of ThreeWay, and
+	// Conflicts is the number of conflicts left in the file then.
+	Merged    bool
+	Conflicts int
+	// Err is why the patch did not apply.
+	Err error
+}
+
+// patchedFile is the content of a file patches are applied to.
+type patchedFile struct {
+	content []byte
+	mode    structures.EntryMode
+	exists  bool
+	// entry is the Index entry of the file, when it has one.
+	entry *IndexEntry
+}
+
+// patcher applies patches to the files of the working tree or the Index,
+// remembering the result of each patch for the patches after it.
+type patcher struct {
+	opts  ApplyOptions
+	index Index
+	files map[string]*patchedFile
+	// changed are the paths whose file was patched, in order.
+	changed []string
+}
+
+// Apply applies patches to the working tree, the Index or both, see
+// ApplyOptions. Either all of them are applied or nothing is changed: when any
+// patch does not apply, ErrPatchFailed is returned and the results tell why.
+// Files which ThreeWay merged with conflicts are written with conflict markers,
+// and ErrConflicts is returned then. Conflicts can not be applied to the Index.
+func Apply(patches []diff.FilePatch, opts ApplyOptions) ([]ApplyResult, error) {
+	p := patcher{opts: opts, files: make(map[string]*patchedFile)}
+	if p.usesIndex() {
+		i, err := FetchIndex()
+		if err != nil && !errors.Is(err, ErrIndexNotFound) {
+			return nil, err
+		}
+		p.index = i
+	}
+
+	var results []ApplyResult
+	failed, conflicts := false, false
+	for _, fp := range patches {
+		r := p.apply(fp)
+		if r.Err != nil {
+			failed = true
+		}
+		if r.Conflicts > 0 {
+			conflicts = true
+			if p.usesIndex() {
+				r.Err = errors.New("conflicts can not be applied to the index")
+				failed = true
+			}
+		}
+
+		results = append(results, r)
+	}
+
+	if failed {
+		return results, ErrPatchFailed
+	}
+
+	if !opts.Check {
+		if err := p.write(); err != nil {
+			return results, err
+		}
+	}
+
+	if conflicts {
+		return results, ErrConflicts
+	}
+
+	return results, nil
+}
+
+// apply applies fp to the files of p.
+func (p *patcher) apply(fp diff.FilePatch) ApplyResult {
+	r := ApplyResult{Patch: fp}
+	fail := func(format string, args ...any) ApplyResult {
+		r.Err = fmt.Errorf(format, args...)
+		return r
+	}
+
+	for _, name := range []string{fp.SourcePath(), fp.Path} {
+		if err := validatePatchPath(name); err != nil {
+			return fail("%w", err)
+		}
+		if err := p.checkLeadingDirs(name); err != nil {
+			return fail("%w", err)
+		}
+	}
+
+	src, err := p.load(fp.SourcePath())
+	if err != nil {
+		return fail("%w", err)
+	}
+
+	if fp.Kind == diff.ChangeAdded && src.exists {
+		return fail("'%v' already exists in %v", fp.Path, p.target())
+	}
+	if fp.Kind != diff.ChangeAdded && !src.exists {
+		return fail("'%v' does not exist in %v", fp.SourcePath(), p.target())
+	}
+	if fp.Binary {
+		return fail("'%v' is binary, binary patches are not supported", fp.Path)
+	}
+
+	content, hunks, err := diff.Apply(src.content, fp.Hunks)
+	r.Hunks = hunks
+	if err != nil {
+		if !p.opts.ThreeWay || fp.Kind == diff.ChangeAdded {
+			return fail("'%v': %w", fp.SourcePath(), err)
+		}
+
+		if content, r.Conflicts, err = threeWayMerge(fp, src.content); err != nil {
+			return fail("'%v': %w", fp.SourcePath(), err)
+		}
+		r.Merged = true
+	}
+
+	if fp.Kind == diff.ChangeDeleted {
+		if len(content) > 0 {
+			return fail("'%v' is not empty after removing the lines of the patch", fp.Path)
+		}
+
+		p.update(fp.Path, &patchedFile{})
+		return r
+	}
+
+	dst := src
+	if fp.Kind == diff.ChangeRenamed || fp.Kind == diff.ChangeCopied {
+		if dst, err = p.load(fp.Path); err != nil {
+			return fail("%w", err)
+		}
+		if dst.exists {
+			return fail("'%v' already exists in %v", fp.Path, p.target())
+		}
+	}
+
+	mode := fp.NewMode
+	if mode == 0 {
+		mode = structures.ModeRegular
+		if src.exists {
+			mode = src.mode
+		}
+	}
+
+	if fp.Kind == diff.ChangeRenamed {
+		p.update(fp.SourcePath(), &patchedFile{})
+	}
+	p.update(fp.Path, &patchedFile{content: content, mode: mode, exists: true, entry: dst.entry})
+
+	return r
+}
+
+// validatePatchPath checks that name, a path of a patch, is a clean path
+// inside the avc repository and outside of the avc directory.
+func validatePatchPath(name string) error {
+	n, err := normalizeName(name)
+	if err != nil {
+		return err
+	}
+	if n != name || path.IsAbs(name) {
+		return fmt.Errorf("'%v' is not a valid path", name)
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == storage.MainDir {
+			return fmt.Errorf("'%v' is inside the avc directory", name)
+		}
+	}
+
+	return nil
+}
+
+// checkLeadingDirs checks that no leading directory of name is a symbolic
+// link or a file, as the patches so far left it, or else as the Index or the
+// working tree has it. Otherwise a patch could write through a symbolic link
+// to a directory outside of the repository.
+func (p *patcher) checkLeadingDirs(name string) error {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		f, ok := p.files[dir]
+		if !ok && p.usesIndex() {
+			idx := slices.IndexFunc(p.index.Entries, func(ie IndexEntry) bool {
+				return ie.Name == dir
+			})
+			if idx >= 0 {
+				f, ok = &patchedFile{mode: p.index.Entries[idx].Mode, exists: true}, true
+			}
+		}
+
+		if ok {
+			if f.exists {
+				return leadingDirError(name, dir, f.mode == structures.ModeSymlink)
+			}
+			continue
+		}
+
+		if !p.opts.Cached {
+			if err := checkWorkingTreeDir(name, dir); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkWorkingTreeDir checks that dir, a leading directory of name, is a
+// directory in the working tree or does not exist.
+func checkWorkingTreeDir(name string, dir string) error {
+	s, err := os.Lstat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !s.IsDir() {
+		return leadingDirError(name, dir, s.Mode()&fs.ModeSymlink != 0)
+	}
+
+	return nil
+}
+
+// leadingDirError returns the error for name, whose leading directory dir is
+// a symbolic link or a file.
+func leadingDirError(name string, dir string, symlink bool) error {
+	if symlink {
+		return fmt.Errorf("'%v' is beyond a symbolic link", name)
+	}
+
+	return fmt.Errorf("'%v' is not a directory, '%v' can not be in it", dir, name)
+}
+
+// threeWayMerge merges the changes fp makes to the Blob it was made from into
+// content, see diff.Merge.
+func threeWayMerge(fp diff.FilePatch, content []byte) ([]byte, int, error) {
+	if fp.OldHash == "" {
+		return nil, 0, errors.New("the patch does not have the hash of the file it was made from")
+	}
+
+	base, err := diff.LoadBlob(fp.SourcePath(), fp.OldMode, fp.OldHash)
+	if err != nil {
+		return nil, 0, fmt.Errorf("the file the patch was made from, %v, can not be loaded: %w", fp.OldHash, err)
+	}
+
+	theirs, _, err := diff.Apply(base, fp.Hunks)
+	if err != nil {
+		return nil, 0, fmt.Errorf("the patch does not apply to the file it was made from, %v", fp.OldHash)
+	}
+
+	merged, conflicts := diff.Merge(base, content, theirs, diff.MergeLabels{Ours: "ours", Base: "base", Theirs: "theirs"}, diff.DefaultOptions())
+	return merged, conflicts, nil
+}
+
+// usesIndex reports whether p applies patches to the Index.
+func (p *patcher) usesIndex() bool {
+	return p.opts.Cached || p.opts.Index
+}
+
+// target returns what p applies patches to, for messages.
+func (p *patcher) target() string {
+	if p.usesIndex() {
+		return "the index"
+	}
+
+	return "the working tree"
+}
+
+// load returns the file name as the patches so far left it.
+func (p *patcher) load(name string) (*patchedFile, error) {
+	if f, ok := p.files[name]; ok {
+		return f, nil
+	}
+
+	f := &patchedFile{}
+	if p.usesIndex() {
+		idx := slices.IndexFunc(p.index.Entries, func(ie IndexEntry) bool {
+			return ie.Name == name
+		})
+		if idx >= 0 {
+			ie := p.index.Entries[idx]
+			if ie.Stage != StageMerged {
+				return nil, fmt.Errorf("'%v' has conflicts", name)
+			}
+
+			c, err := diff.LoadBlob(ie.Name, ie.Mode, ie.EntryHash)
+			if err != nil {
+				return nil, fmt.Errorf("'%v': %w", name, err)
+			}
+
+			f = &patchedFile{content: c, mode: ie.Mode, exists: true, entry: &ie}
+		}
+	}
+
+	if !p.opts.Cached {
+		wf, err := loadWorkingTreeFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.opts.Index {
+			f = wf
+		} else if wf.exists != f.exists || wf.mode != f.mode || !bytes.Equal(wf.content, f.content) {
+			return nil, fmt.Errorf("'%v' in the working tree does not match the index", name)
+		}
+	}
+
+	p.files[name] = f
+	return f, nil
+}
+
+// loadWorkingTreeFile returns the file name in the working tree.
+func loadWorkingTreeFile(name string) (*patchedFile, error) {
+	s, err := os.Lstat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &patchedFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := structures.ModeFromFileMode(s.Mode())
+	if err != nil || mode == structures.ModeTree {
+		return nil, fmt.Errorf("'%v' is not a file", name)
+	}
+
+	c, err := structures.ReadEntryContent(name, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &patchedFile{content: c, mode: mode, exists: true}, nil
+}
+
+// update records f as the new file name.
+func (p *patcher) update(name string, f *patchedFile) {
+	if !slices.Contains(p.changed, name) {
+		p.changed = append(p.changed, name)
+	}
+
+	p.files[name] = f
+}
+
+// write writes the patched files to the working tree, the Index or both.
+// Deleted files are removed first, so a symbolic link a patch replaces with a
+// directory is gone before anything is written in that directory, and the
+// leading directories of each file are checked again right before it is
+// written.
+func (p *patcher) write() error {
+	if !p.opts.Cached {
+		for _, name := range p.changed {
+			if p.files[name].exists {
+				continue
+			}
+
+			if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			removeEmptyDirs(path.Dir(name))
+		}
+
+		for _, name := range p.changed {
+			f := p.files[name]
+			if !f.exists {
+				continue
+			}
+
+			for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+				if err := checkWorkingTreeDir(name, dir); err != nil {
+					return err
+				}
+			}
+			if err := structures.WriteEntry(name, f.mode, f.content); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.usesIndex() {
+		return p.writeIndex()
+	}
+
+	return nil
+}
+
+// writeIndex stores the patched files and updates their entries in the Index.
+func (p *patcher) writeIndex() error {
+	for _, name := range p.changed {
+		f := p.files[name]
+		p.index.Entries = slices.DeleteFunc(p.index.Entries, func(ie IndexEntry) bool {
+			return ie.Name == name
+		})
+		if !f.exists {
+			continue
+		}
+
+		h, err := structures.Blob{Content: f.content}.StoreBlob()
+		if err != nil {
+			return err
+		}
+
+		e := IndexEntry{EntryHash: h, Name: name, Mode: f.mode}
+		if f.entry != nil {
+			e.CreatedDate = f.entry.CreatedDate
+		}
+		// The file was written to the working tree as well, so status does
+		// not need to hash it.
+		if p.opts.Index {
+			s, err := os.Lstat(name)
+			if err != nil {
+				return err
+			}
+			e.ModifiedDate = s.ModTime()
+		}
+		p.index.Entries = append(p.index.Entries, e)
+	}
+
+	return p.index.saveIndex()
+}
+
+// removeEmptyDirs removes dir and its parents as long as they are empty.
+func removeEmptyDirs(dir string) {
+	for dir != "." && dir != "/" {
+		if os.Remove(dir) != nil {
+			return
+		}
+
+		dir = path.Dir(dir)
+	}
+}
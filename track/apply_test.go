@@ -0,0 +1,120 @@
+package track
+
+import (
+	"armanVersionControl/diff"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parsePatch parses patch and fails the test when it is not valid.
+func parsePatch(t *testing.T, patch string) []diff.FilePatch {
+	t.Helper()
+
+	patches, err := diff.ParsePatch(strings.NewReader(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return patches
+}
+
+// newFilePatch returns the patch adding name with a single line.
+func newFilePatch(name string, mode string, line string) string {
+	return "diff --git a/" + name + " b/" + name + "\nnew file mode " + mode + "\n--- /dev/null\n+++ b/" + name +
+		"\n@@ -0,0 +1 @@\n+" + line + "\n"
+}
+
+func TestApplyRefusesToWriteThroughSymlink(t *testing.T) {
+	initTempRepo(t)
+	outside := t.TempDir()
+
+	if err := os.Symlink(outside, "link"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"symbolic link in the working tree", newFilePatch("link/evil", "100644", "evil")},
+		{
+			"symbolic link added by an earlier patch",
+			newFilePatch("dir", "120000", outside) + "\\ No newline at end of file\n" + newFilePatch("dir/evil", "100644", "evil"),
+		},
+	}
+
+	for _, tt := range tests {
+		results, err := Apply(parsePatch(t, tt.patch), ApplyOptions{})
+		if !errors.Is(err, ErrPatchFailed) {
+			t.Fatalf("%v: got error %v, want %v", tt.name, err, ErrPatchFailed)
+		}
+
+		last := results[len(results)-1]
+		if last.Err == nil || !strings.Contains(last.Err.Error(), "beyond a symbolic link") {
+			t.Errorf("%v: got error %v for '%v', want it to be beyond a symbolic link", tt.name, last.Err, last.Patch.Path)
+		}
+		if _, err := os.Lstat(filepath.Join(outside, "evil")); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%v: the file outside of the repository was written", tt.name)
+		}
+	}
+
+	if _, err := os.Lstat("dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("the symbolic link of the failed patch set was written")
+	}
+}
+
+func TestApplyChangesNothingWhenAPatchFails(t *testing.T) {
+	initTempRepo(t)
+	writeFile(t, "a.txt", "a\n")
+	writeFile(t, "b.txt", "b\n")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := FetchIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the patch of b.txt does not apply, it expects other content.
+	patch := "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n" +
+		newFilePatch("new/c.txt", "100644", "c") +
+		"diff --git a/b.txt b/b.txt\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-x\n+B\n"
+
+	for _, opts := range []ApplyOptions{{}, {Index: true}} {
+		results, err := Apply(parsePatch(t, patch), opts)
+		if !errors.Is(err, ErrPatchFailed) {
+			t.Fatalf("%+v: got error %v, want %v", opts, err, ErrPatchFailed)
+		}
+		if results[0].Err != nil || results[1].Err != nil || results[2].Err == nil {
+			t.Errorf("%+v: got results %+v, want only the patch of b.txt to fail", opts, results)
+		}
+
+		for name, want := range map[string]string{"a.txt": "a\n", "b.txt": "b\n"} {
+			if c, err := os.ReadFile(name); err != nil || string(c) != want {
+				t.Errorf("%+v: '%v' is %q, %v, want %q", opts, name, c, err, want)
+			}
+		}
+		if _, err := os.Lstat("new"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%+v: the directory of the added file was created", opts)
+		}
+
+		after, err := FetchIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(after.Entries) != len(before.Entries) {
+			t.Fatalf("%+v: the index has %v entries, want %v", opts, len(after.Entries), len(before.Entries))
+		}
+		for i := range after.Entries {
+			if after.Entries[i].Name != before.Entries[i].Name || after.Entries[i].EntryHash != before.Entries[i].EntryHash {
+				t.Errorf("%+v: the index entry of '%v' changed", opts, before.Entries[i].Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package track
+
+import (
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// CheckoutIndexOptions controls how CheckoutIndex writes entries.
+type CheckoutIndexOptions struct {
+	// Prefix is prepended to the name of each entry when writing it. It is
+	// prepended as is, so "export/" writes into the export directory.
+	Prefix string
+	// Force overwrites files that already exist.
+	Force bool
+	// RestoreMTime sets the modification time of each written file to
+	// IndexEntry.ModifiedDate.
+	RestoreMTime bool
+	// Stage is the version of files with conflicts which is written. With
+	// StageMerged, they are skipped when writing all entries and are an error
+	// when named.
+	Stage Stage
+}
+
+// CheckoutIndex writes the entries of the Index with the given names to the
+// working tree. When names is empty, all entries are written. Nothing is
+// written if any name is not in the Index or any file already exists and
+// opts.Force is false. When writing into the working tree itself, the Index
+// is updated with the modification time of the written files, unless the
+// time recorded in it was restored.
+func CheckoutIndex(names []string, opts CheckoutIndexOptions) error {
+	i, err := FetchIndex()
+	if err != nil {
+		return err
+	}
+
+	var entries []*IndexEntry
+	if len(names) == 0 {
+		for idx, ie := range i.Entries {
+			if ie.Stage == StageMerged || ie.Stage == opts.Stage {
+				entries = append(entries, &i.Entries[idx])
+			}
+		}
+	}
+
+	for _, n := range names {
+		n, err = normalizeName(n)
+		if err != nil {
+			return err
+		}
+
+		idx := slices.IndexFunc(i.Entries, func(ie IndexEntry) bool {
+			return ie.Name == n
+		})
+		if idx < 0 {
+			return fmt.Errorf("'%v' is not in the index", n)
+		}
+
+		if i.Entries[idx].Stage != StageMerged {
+			if opts.Stage == StageMerged {
+				return fmt.Errorf("'%v' has conflicts, choose the version to write", n)
+			}
+
+			idx = slices.IndexFunc(i.Entries, func(ie IndexEntry) bool {
+				return ie.Name == n && ie.Stage == opts.Stage
+			})
+			if idx < 0 {
+				return fmt.Errorf("'%v' has no %v version", n, opts.Stage)
+			}
+		}
+
+		entries = append(entries, &i.Entries[idx])
+	}
+
+	if !opts.Force {
+		var existing []string
+		for _, ie := range entries {
+			if _, err := os.Lstat(opts.Prefix + ie.Name); err == nil {
+				existing = append(existing, opts.Prefix+ie.Name)
+			}
+		}
+
+		if len(existing) > 0 {
+			return fmt.Errorf("these files already exist, use force to overwrite them:\n%v", strings.Join(existing, "\n"))
+		}
+	}
+
+	for _, ie := range entries {
+		o, err := storage.FetchByHash(ie.EntryHash)
+		if err != nil {
+			return fmt.Errorf("'%v': %w", ie.Name, err)
+		}
+
+		b, err := structures.NewBlobFromB(o.Content)
+		if err != nil {
+			return fmt.Errorf("'%v': %w", ie.Name, err)
+		}
+
+		name := opts.Prefix + ie.Name
+		if err = structures.WriteEntry(name, ie.Mode, b.Content); err != nil {
+			return err
+		}
+
+		// A restored time is the one recorded already, so it is kept.
+		if opts.RestoreMTime && !ie.ModifiedDate.IsZero() && ie.Mode != structures.ModeSymlink {
+			if err = structures.RestoreModifiedDate(name, ie.Mode, ie.ModifiedDate); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if opts.Prefix == "" {
+			s, err := os.Lstat(name)
+			if err != nil {
+				return err
+			}
+
+			ie.ModifiedDate = s.ModTime()
+		}
+	}
+
+	if opts.Prefix != "" {
+		return nil
+	}
+
+	return i.saveIndex()
+}
@@ -0,0 +1,147 @@
+package track
+
+import (
+	"armanVersionControl/hashing"
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"os"
+	"testing"
+	"time"
+)
+
+// initTempRepo changes into a new repository in a temporary directory for the
+// rest of the test.
+func initTempRepo(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err = storage.Init(hashing.SHA1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeFile writes content to name, creating its directories, and fails the
+// test when that is not possible.
+func writeFile(t *testing.T, name string, content string) {
+	t.Helper()
+
+	if err := structures.WriteEntry(name, structures.ModeRegular, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// indexEntry returns the merged entry of the Index with name.
+func indexEntry(t *testing.T, name string) IndexEntry {
+	t.Helper()
+
+	i, err := FetchIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ie := range i.Entries {
+		if ie.Name == name && ie.Stage == StageMerged {
+			return ie
+		}
+	}
+
+	t.Fatalf("'%v' is not in the index", name)
+	return IndexEntry{}
+}
+
+func TestCheckoutRestoresModifiedDateAfterReadTree(t *testing.T) {
+	initTempRepo(t)
+
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 7000, time.UTC)
+	writeFile(t, "a.txt", "a\n")
+	writeFile(t, "sub/b.txt", "b\n")
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		if err := os.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		if err := Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hash, err := WriteTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := structures.FetchTreeish(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing the files on disk and reading the tree back must not lose the
+	// times recorded in the index.
+	writeFile(t, "a.txt", "changed\n")
+	if err = os.Remove("sub/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err = ReadTree(tree, "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	err = CheckoutIndex(nil, CheckoutIndexOptions{Force: true, RestoreMTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		s, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !s.ModTime().Equal(mtime) {
+			t.Errorf("'%v' has modification time %v, want %v", name, s.ModTime(), mtime)
+		}
+
+		if got := indexEntry(t, name).ModifiedDate; !got.Equal(mtime) {
+			t.Errorf("index has modification time %v for '%v', want %v", got, name, mtime)
+		}
+	}
+
+	content, err := os.ReadFile("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "a\n" {
+		t.Errorf("a.txt has content %q, want %q", content, "a\n")
+	}
+}
+
+func TestCheckoutWithoutRestoreRecordsWriteTime(t *testing.T) {
+	initTempRepo(t)
+
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	writeFile(t, "a.txt", "a\n")
+	if err := os.Chtimes("a.txt", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckoutIndex([]string{"a.txt"}, CheckoutIndexOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := os.Stat("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ModTime().Equal(mtime) {
+		t.Errorf("a.txt kept modification time %v without restoring it", mtime)
+	}
+	if got := indexEntry(t, "a.txt").ModifiedDate; !got.Equal(s.ModTime()) {
+		t.Errorf("index has modification time %v, want the write time %v", got, s.ModTime())
+	}
+}
@@ -2,59 +2,62 @@ package track
 
 import (
 	"armanVersionControl/storage"
+	"armanVersionControl/structures"
 	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"slices"
+	"strings"
 	"syscall"
 	"time"
 )
 
 const (
 	// currentIndexVersion represents the latest (current) version of Index.
-	currentIndexVersion uint16 = 0
+	// Version 1 added structures.EntryMode to each IndexEntry, version 2
+	// added its Stage.
+	currentIndexVersion uint16 = 2
 	// indexMagicNumber represents the Index unique identifier.
-	indexMagicNumber = 400
+	indexMagicNumber uint16 = 400
+	// currentIndexSignature represents the latest (current) signature of Index.
+	currentIndexSignature = indexMagicNumber + currentIndexVersion
 )
 
 var (
-	// currentIndexSignature represents the latest (current) signature of Index.
-	currentIndexSignature []byte
-
-	// currentIndexHeader represents the first few bytes of the file representation
-	// of an Index. If any file starts with this header, we will know it's an Index.
-	currentIndexHeader []byte
-	indexFileName                  = path.Join(storage.MainDir, "index")
-	filePerm           os.FileMode = 0770
+	indexFileName             = path.Join(storage.MainDir, "index")
+	filePerm      os.FileMode = 0770
 )
 
 var (
-	ErrNotAnIndex        = errors.New("not a valid Index")
-	ErrIndexNotFound     = errors.New("index file not found")
-	ErrNameAlreadyExists = errors.New("path already exits in index")
+	ErrNotAnIndex    = errors.New("not a valid Index")
+	ErrIndexNotFound = errors.New("index file not found")
 )
 
-func init() {
-	currentIndexSignature = make([]byte, 2)
-	// BigEndian is chosen because that is the network byte order
-	// and will save few bytes when storing it in the file. Plus
-	// that's how git represents numbers in the file as well.
-	_, err := binary.Encode(currentIndexSignature, binary.BigEndian, indexMagicNumber+currentIndexVersion)
-	if err != nil {
-		panic(err)
-	}
+// Stage tells which version of a file an IndexEntry holds while a merge with
+// conflicts is not resolved yet. A path either has a single entry with
+// StageMerged, or entries with the other stages for each version of it which
+// exists.
+type Stage uint8
 
-	// Since Go file-level variables are initialized before the init function,
-	// I can't place this line where currentIndexHeader is defined.
-	// Otherwise, currentIndexSignature will be nil when currentIndexHeader is initialized.
-	// git adds a null byte in the header before the content starts. I don't think I'll need it,
-	// but oh well, who cares if there's a null byte in the header even if I don't need it?
-	currentIndexHeader = []byte(fmt.Sprintf("%v \u0000", currentIndexSignature))
+const (
+	// StageMerged is the stage of a file without conflicts.
+	StageMerged Stage = iota
+	// StageBase is the version of the merge base.
+	StageBase
+	// StageOurs is the version of HEAD.
+	StageOurs
+	// StageTheirs is the version of the commit being merged.
+	StageTheirs
+)
+
+func (s Stage) String() string {
+	return []string{"merged", "base", "ours", "theirs"}[s]
 }
 
 // IndexEntry represents each entry in Index, which can only be a regular file.
@@ -63,10 +66,15 @@ type IndexEntry struct {
 	EntryHash string
 	// Name represents the file name.
 	Name string
+	// Mode is the file mode of the entry, it is never structures.ModeTree.
+	Mode structures.EntryMode
 	// CreatedDate represents the creation date time of a Blob.
 	CreatedDate time.Time
 	// ModifiedDate represents the last date time of when IndexEntry was changed.
 	ModifiedDate time.Time
+	// Stage is StageMerged, unless the entry is a version of a file with
+	// conflicts.
+	Stage Stage
 }
 
 // Index represents a type to track blobs.
@@ -80,23 +88,26 @@ func isIndexS(signature uint16) bool {
 	return signature >= 400 && signature <= 499
 }
 
-// IsIndexB checks whether the content starts with the correct Index
-// header (AKA signature).
-func isIndexB(content []byte) bool {
-	if len(content) < len(currentIndexHeader) {
-		return false
+// indexVersion checks whether the content starts with the correct Index
+// header (AKA signature) and returns the Index version and the payload
+// after the header.
+func indexVersion(content []byte) (uint16, []byte, error) {
+	signature, payload, err := structures.SplitHeader(content)
+	if err != nil || !isIndexS(signature) {
+		return 0, nil, ErrNotAnIndex
+	}
+
+	version := signature - indexMagicNumber
+	if version > currentIndexVersion {
+		return 0, nil, fmt.Errorf("index version %v is not supported, the latest supported version is %v", version, currentIndexVersion)
 	}
 
-	b := content[:len(currentIndexHeader)]
-	return slices.Equal(b, currentIndexHeader) ||
-		isIndexS(binary.BigEndian.Uint16(b))
+	return version, payload, nil
 }
 
 func (index Index) fileRepresent() ([]byte, error) {
 	var buf bytes.Buffer
 
-	buf.Write(currentIndexHeader)
-
 	for _, ie := range index.Entries {
 		err := binary.Write(&buf, binary.BigEndian, int32(len(ie.EntryHash)))
 		if err != nil {
@@ -110,6 +121,12 @@ func (index Index) fileRepresent() ([]byte, error) {
 		}
 		buf.WriteString(ie.Name)
 
+		err = binary.Write(&buf, binary.BigEndian, uint32(ie.Mode))
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(byte(ie.Stage))
+
 		cd, err := ie.CreatedDate.MarshalBinary()
 		if err != nil {
 			return nil, err
@@ -128,26 +145,36 @@ func (index Index) fileRepresent() ([]byte, error) {
 		buf.Write(md)
 	}
 
-	return buf.Bytes(), nil
+	// The Index uses the same header as objects, see structures.EncodeHeader.
+	return append(structures.EncodeHeader(currentIndexSignature, buf.Len()), buf.Bytes()...), nil
 }
 
 func newIndexFromB(b []byte) (Index, error) {
-	if !isIndexB(b) {
-		return Index{}, ErrNotAnIndex
+	version, payload, err := indexVersion(b)
+	if err != nil {
+		return Index{}, err
 	}
-	r := bytes.NewReader(b[len(currentIndexHeader):])
+	// Version 0 entries have no mode, so they are all regular files.
+	hasMode := version >= 1
+	// Before version 2 there were no conflicts, so all entries are merged.
+	hasStage := version >= 2
+	r := bytes.NewReader(payload)
 
 	index := Index{}
 	readBuf := func() ([]byte, error) {
 		countBuf := make([]byte, 4)
-		_, err := r.Read(countBuf)
+		_, err := io.ReadFull(r, countBuf)
 		if err != nil {
 			return nil, err
 		}
 
 		count := int32(binary.BigEndian.Uint32(countBuf))
+		if count < 0 || int(count) > r.Len() {
+			return nil, ErrNotAnIndex
+		}
+
 		buf := make([]byte, count)
-		_, err = r.Read(buf)
+		_, err = io.ReadFull(r, buf)
 		if err != nil {
 			return nil, err
 		}
@@ -171,6 +198,25 @@ func newIndexFromB(b []byte) (Index, error) {
 		}
 		ie.Name = string(buf)
 
+		// Parsing Mode
+		ie.Mode = structures.ModeRegular
+		if hasMode {
+			modeBuf := make([]byte, 4)
+			if _, err = io.ReadFull(r, modeBuf); err != nil {
+				return Index{}, err
+			}
+			ie.Mode = structures.EntryMode(binary.BigEndian.Uint32(modeBuf))
+		}
+
+		// Parsing Stage
+		if hasStage {
+			stage, err := r.ReadByte()
+			if err != nil {
+				return Index{}, err
+			}
+			ie.Stage = Stage(stage)
+		}
+
 		// Parsing CreatedDate
 		buf, err = readBuf()
 		if err != nil {
@@ -200,34 +246,39 @@ func newIndexFromB(b []byte) (Index, error) {
 // Add will take a name (path) and add it to the current Index to prepare
 // the content to be commited. If name is a directory, all subdirectories
 // and files in the name will be added to the current Index. If name is an
-// empty directory, nothing will be added to Index.
+// empty directory, nothing will be added to Index. Symbolic links are added
+// as is, the content of their Blob is the link target. Adding a path that
+// is already in the Index replaces its entry with the current content.
 func Add(name string) error {
-	s, err := os.Stat(name)
+	s, err := os.Lstat(name)
 	if err != nil {
 		return err
 	}
 
-	add := func(n string) error {
-		i, err := fetchIndex()
+	i, err := FetchIndex()
+	if err != nil {
+		if !errors.Is(err, ErrIndexNotFound) {
+			return err
+		}
+	}
+
+	add := func(n string, mode structures.EntryMode) error {
+		c, err := structures.ReadEntryContent(n, mode)
 		if err != nil {
-			if !errors.Is(err, ErrIndexNotFound) {
-				return err
-			}
+			return err
 		}
-		exists := slices.ContainsFunc(i.Entries, func(ie IndexEntry) bool {
-			return ie.Name == n
-		})
 
-		if exists {
-			return ErrNameAlreadyExists
+		h, err := structures.Blob{Content: c}.StoreBlob()
+		if err != nil {
+			return err
 		}
 
-		rf, err := os.ReadFile(n)
+		s, err := os.Lstat(n)
 		if err != nil {
 			return err
 		}
 
-		s, err := os.Stat(n)
+		n, err = normalizeName(n)
 		if err != nil {
 			return err
 		}
@@ -239,42 +290,101 @@ func Add(name string) error {
 			cd = time.Unix(stat.Ctim.Sec, stat.Ctim.Nsec)
 		}
 		e := IndexEntry{
-			EntryHash:    storage.ComputeHash(rf),
+			EntryHash:    h,
 			Name:         n,
+			Mode:         mode,
 			CreatedDate:  cd,
-			ModifiedDate: cd,
+			ModifiedDate: s.ModTime(),
 		}
-		i.Entries = append(i.Entries, e)
 
-		return i.saveIndex()
-	}
-
-	if s.Mode().IsRegular() {
-		// Read index file and add current value to it.
-		return add(name)
+		// Adding a file with conflicts resolves them, so all of its
+		// entries are replaced.
+		i.Entries = slices.DeleteFunc(i.Entries, func(ie IndexEntry) bool {
+			return ie.Name == n
+		})
+		i.Entries = append(i.Entries, e)
+		return nil
 	}
 
 	if s.IsDir() {
-		return filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+		err = filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
 
-			if !d.Type().IsRegular() {
-				// Skip anything that is not a regular file
+			if d.IsDir() {
+				if d.Name() == storage.MainDir {
+					return filepath.SkipDir
+				}
+
 				return nil
 			}
 
-			return add(path)
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			mode, err := structures.ModeFromFileMode(info.Mode())
+			if err != nil {
+				// Skip anything that is not a regular file or a symbolic link
+				return nil
+			}
+
+			return add(path, mode)
 		})
+		if err != nil {
+			return err
+		}
+
+		return i.saveIndex()
+	}
+
+	mode, err := structures.ModeFromFileMode(s.Mode())
+	if err != nil {
+		return fmt.Errorf("type %v is not supported", s.Mode().Type())
+	}
+
+	// Read index file and add current value to it.
+	if err = add(name, mode); err != nil {
+		return err
 	}
 
-	return fmt.Errorf("type %v is not supported", s.Mode().Type())
+	return i.saveIndex()
+}
+
+// normalizeName converts name to a slash separated path relative to the
+// root of the avc repository, which is the current working directory.
+// This way the same file is always stored with the same name in Index.
+func normalizeName(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+
+		name, err = filepath.Rel(wd, name)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	n := filepath.ToSlash(filepath.Clean(name))
+	if n == ".." || strings.HasPrefix(n, "../") {
+		return "", fmt.Errorf("'%v' is outside of the avc repository", name)
+	}
+
+	return n, nil
 }
 
 // Remove will remove name from Index.
 func Remove(name string) error {
-	index, err := fetchIndex()
+	index, err := FetchIndex()
+	if err != nil {
+		return err
+	}
+
+	name, err = normalizeName(name)
 	if err != nil {
 		return err
 	}
@@ -292,9 +402,9 @@ func Remove(name string) error {
 	return index.saveIndex()
 }
 
-// fetchIndex will retrieve Index from the index file stored in
+// FetchIndex will retrieve Index from the index file stored in
 // avc repository.
-func fetchIndex() (Index, error) {
+func FetchIndex() (Index, error) {
 	ok, err := storage.ExistsMainDir()
 	if err != nil {
 		return Index{}, err
@@ -316,6 +426,51 @@ func fetchIndex() (Index, error) {
 	return newIndexFromB(rf)
 }
 
+// Validate checks that every entry of index has a valid name, mode and stage
+// and that entries are sorted by name and stage without any duplicates. A
+// name with a StageMerged entry must not have entries of other stages. It
+// does not check whether the Blobs of the entries exist.
+func (index Index) Validate() error {
+	for i, ie := range index.Entries {
+		if ie.EntryHash == "" {
+			return fmt.Errorf("entry %q has no hash", ie.Name)
+		}
+
+		if ie.Name == "" || strings.HasPrefix(ie.Name, "/") {
+			return fmt.Errorf("entry %q has an invalid name", ie.Name)
+		}
+		for _, part := range strings.Split(ie.Name, "/") {
+			if err := structures.ValidateEntryName(part); err != nil {
+				return fmt.Errorf("entry %q: %w", ie.Name, err)
+			}
+			if part == storage.MainDir {
+				return fmt.Errorf("entry %q is inside the avc directory", ie.Name)
+			}
+		}
+
+		if !ie.Mode.IsValid() || ie.Mode.Kind() != structures.KindBlob {
+			return fmt.Errorf("entry %q has an invalid mode %v", ie.Name, ie.Mode)
+		}
+
+		if ie.Stage > StageTheirs {
+			return fmt.Errorf("entry %q has an invalid stage %d", ie.Name, ie.Stage)
+		}
+
+		if i == 0 {
+			continue
+		}
+		prev := index.Entries[i-1]
+		if compareEntries(prev, ie) >= 0 {
+			return fmt.Errorf("entry %q is not sorted after %q or is a duplicate", ie.Name, prev.Name)
+		}
+		if prev.Name == ie.Name && prev.Stage == StageMerged {
+			return fmt.Errorf("entry %q is merged and has conflicts", ie.Name)
+		}
+	}
+
+	return nil
+}
+
 // saveIndex persists the current state of the Index to a file.
 func (index Index) saveIndex() error {
 	ok, err := storage.ExistsMainDir()
@@ -326,6 +481,10 @@ func (index Index) saveIndex() error {
 		return storage.ErrRepoNotInitialized
 	}
 
+	// Keep entries sorted by name, so the Index does not depend on the
+	// order paths were added in.
+	slices.SortFunc(index.Entries, compareEntries)
+
 	b, err := index.fileRepresent()
 	if err != nil {
 		return err
@@ -333,3 +492,12 @@ func (index Index) saveIndex() error {
 
 	return os.WriteFile(indexFileName, b, filePerm)
 }
+
+// compareEntries orders IndexEntry by name and then by stage.
+func compareEntries(a, b IndexEntry) int {
+	if c := strings.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+
+	return int(a.Stage) - int(b.Stage)
+}
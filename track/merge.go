@@ -0,0 +1,537 @@
+package track
+
+import (
+	"armanVersionControl/diff"
+	"armanVersionControl/structures"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMergeConflicts = errors.New("merge has conflicts")
+)
+
+// MergeConflict is a file which both sides of a merge changed in a way that
+// can not be merged automatically.
+type MergeConflict struct {
+	// Path is the path of the file.
+	Path string
+	// Reason is the kind of the conflict, e.g. "content" or "modify/delete".
+	Reason string
+	// Base, Ours and Theirs are the versions of the file, nil when it does not
+	// exist in that version.
+	Base   *IndexEntry
+	Ours   *IndexEntry
+	Theirs *IndexEntry
+	// Content is what is written to the working tree for the file: the text
+	// of both sides with conflict markers, or the version which still exists.
+	Content []byte
+	Mode    structures.EntryMode
+}
+
+// TreeMerge is the result of merging two trees with MergeTrees. It only has
+// the files whose merged version differs from ours, the tree merged into.
+type TreeMerge struct {
+	// Entries are the files which the merge adds or changes, their Blobs are
+	// stored in the object database.
+	Entries []IndexEntry
+	// Deleted are the paths of the files of ours which the merge deletes.
+	Deleted []string
+	// Conflicts are the files which could not be merged.
+	Conflicts []MergeConflict
+}
+
+// MergeTrees merges the changes ours and theirs made to base, file by file.
+// A file which only one side changed, or both changed the same way, is taken
+// from that side, including when it was added or deleted. When both sides
+// changed a text file, it is merged line by line with diff.Merge and labels,
+// and its conflicts are left between conflict markers. Any tree may be nil,
+// which is the same as an empty tree.
+//
+// Only the changes of both sides are compared, found with diff.Trees, so
+// subtrees which neither side changed are never fetched.
+func MergeTrees(base *structures.Tree, ours *structures.Tree, theirs *structures.Tree, labels diff.MergeLabels) (TreeMerge, error) {
+	var sides [2]map[string]diff.Change
+	var paths []string
+	for i, t := range []*structures.Tree{ours, theirs} {
+		changes, err := diff.Trees(base, t, diff.TreeOptions{Recursive: true})
+		if err != nil {
+			return TreeMerge{}, err
+		}
+
+		sides[i] = make(map[string]diff.Change, len(changes))
+		for _, c := range changes {
+			sides[i][c.Path] = c
+			paths = append(paths, c.Path)
+		}
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	var m TreeMerge
+	for _, name := range paths {
+		oc, oursChanged := sides[0][name]
+		tc, theirsChanged := sides[1][name]
+
+		// The version of base is the old one of either change, and the side
+		// which did not change it still has it.
+		c := oc
+		if !oursChanged {
+			c = tc
+		}
+		b := changedFile(name, c.OldMode, c.OldHash)
+		o, t := b, b
+		if oursChanged {
+			o = changedFile(name, oc.NewMode, oc.NewHash)
+		}
+		if theirsChanged {
+			t = changedFile(name, tc.NewMode, tc.NewHash)
+		}
+
+		switch {
+		case sameFile(o, t) || sameFile(b, t):
+			// ours is kept as it is.
+		case sameFile(b, o):
+			m.take(name, t)
+		default:
+			if err := m.mergeFile(name, b, o, t, labels); err != nil {
+				return TreeMerge{}, err
+			}
+		}
+	}
+
+	if err := m.checkPaths(); err != nil {
+		return TreeMerge{}, err
+	}
+
+	return m, nil
+}
+
+// changedFile returns the version of the file name with mode and hash of a
+// diff.Change, or nil when hash is empty because the file does not exist.
+func changedFile(name string, mode structures.EntryMode, hash string) *IndexEntry {
+	if hash == "" {
+		return nil
+	}
+
+	return &IndexEntry{EntryHash: hash, Name: name, Mode: mode}
+}
+
+// sameFile reports whether a and b are the same version of a file, where nil
+// is a file which does not exist.
+func sameFile(a *IndexEntry, b *IndexEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Mode == b.Mode && a.EntryHash == b.EntryHash
+}
+
+// take replaces the file name of ours with ie, or deletes it when ie is nil.
+func (m *TreeMerge) take(name string, ie *IndexEntry) {
+	if ie == nil {
+		m.Deleted = append(m.Deleted, name)
+		return
+	}
+
+	m.Entries = append(m.Entries, *ie)
+}
+
+// mergeFile merges the file name, which both sides changed differently.
+func (m *TreeMerge) mergeFile(name string, b *IndexEntry, o *IndexEntry, t *IndexEntry, labels diff.MergeLabels) error {
+	c := MergeConflict{Path: name, Base: b, Ours: o, Theirs: t}
+	if o == nil || t == nil {
+		c.Reason = "modify/delete"
+		side := o
+		if side == nil {
+			side = t
+		}
+
+		content, err := diff.LoadBlob(name, side.Mode, side.EntryHash)
+		if err != nil {
+			return fmt.Errorf("'%v': %w", name, err)
+		}
+		c.Content, c.Mode = content, side.Mode
+		m.Conflicts = append(m.Conflicts, c)
+		return nil
+	}
+
+	c.Reason = "content"
+	if b == nil {
+		c.Reason = "add/add"
+	}
+
+	// A mode which only one side changed is taken like a file.
+	mode := o.Mode
+	if b != nil && o.Mode == b.Mode {
+		mode = t.Mode
+	}
+	modeConflict := o.Mode != t.Mode && (b == nil || (o.Mode != b.Mode && t.Mode != b.Mode))
+
+	var base, ours, theirs []byte
+	for _, v := range []struct {
+		ie      *IndexEntry
+		content *[]byte
+	}{{b, &base}, {o, &ours}, {t, &theirs}} {
+		if v.ie == nil {
+			continue
+		}
+
+		content, err := diff.LoadBlob(name, v.ie.Mode, v.ie.EntryHash)
+		if err != nil {
+			return fmt.Errorf("'%v': %w", name, err)
+		}
+		*v.content = content
+	}
+
+	c.Content, c.Mode = ours, o.Mode
+	if o.Mode == structures.ModeSymlink || t.Mode == structures.ModeSymlink ||
+		diff.IsBinary(base) || diff.IsBinary(ours) || diff.IsBinary(theirs) {
+		m.Conflicts = append(m.Conflicts, c)
+		return nil
+	}
+
+	merged, conflicts := diff.Merge(base, ours, theirs, labels, diff.DefaultOptions())
+	if conflicts > 0 || modeConflict {
+		c.Content, c.Mode = merged, mode
+		m.Conflicts = append(m.Conflicts, c)
+		return nil
+	}
+
+	h, err := structures.Blob{Content: merged}.StoreBlob()
+	if err != nil {
+		return err
+	}
+
+	m.Entries = append(m.Entries, IndexEntry{EntryHash: h, Name: name, Mode: mode})
+	return nil
+}
+
+// checkPaths returns an error when a merged file is where the other side has
+// a directory. Files which neither side changed can not be in the way, the
+// tree of that side would have a file and a directory with the same path.
+func (m *TreeMerge) checkPaths() error {
+	names := make(map[string]bool)
+	for _, ie := range m.Entries {
+		names[ie.Name] = true
+	}
+	for _, c := range m.Conflicts {
+		names[c.Path] = true
+	}
+
+	for name := range names {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if names[dir] {
+				return fmt.Errorf("'%v' is a file on one side of the merge and a directory on the other", dir)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckoutMerge writes m to the Index and the working tree. The Index has to
+// match ours, the tree m was merged into, and only the files m changes are
+// written to the working tree and replaced in the Index. Conflicting files are
+// written with their MergeConflict.Content, and their versions are in the
+// Index with StageBase, StageOurs and StageTheirs. Nothing is changed when a
+// file that has to be written has changes in the working tree which are not
+// in the Index, or is not tracked.
+func CheckoutMerge(m TreeMerge) error {
+	i, err := FetchIndex()
+	if err != nil && !errors.Is(err, ErrIndexNotFound) {
+		return err
+	}
+
+	entries := make(map[string]IndexEntry)
+	for _, ie := range i.Entries {
+		entries[ie.Name] = ie
+	}
+
+	type change struct {
+		name    string
+		content []byte
+		mode    structures.EntryMode
+		exists  bool
+	}
+	// Deleted files come first, so a file which is replaced by a directory
+	// is gone before anything is written in it.
+	var changes []change
+	for _, name := range m.Deleted {
+		changes = append(changes, change{name: name})
+	}
+	for _, ie := range m.Entries {
+		if prev, ok := entries[ie.Name]; ok && sameFile(&prev, &ie) {
+			continue
+		}
+
+		content, err := diff.LoadBlob(ie.Name, ie.Mode, ie.EntryHash)
+		if err != nil {
+			return fmt.Errorf("'%v': %w", ie.Name, err)
+		}
+		changes = append(changes, change{name: ie.Name, content: content, mode: ie.Mode, exists: true})
+	}
+	for _, c := range m.Conflicts {
+		changes = append(changes, change{name: c.Path, content: c.Content, mode: c.Mode, exists: true})
+	}
+
+	var dirty []string
+	for _, c := range changes {
+		ie, ok := entries[c.name]
+		if !ok {
+			if _, err := os.Lstat(c.name); err == nil {
+				dirty = append(dirty, c.name)
+			}
+			continue
+		}
+
+		modified, err := ie.IsModified()
+		if err != nil {
+			return err
+		}
+		if deleted, _ := ie.IsDeleted(); modified && !deleted {
+			dirty = append(dirty, c.name)
+		}
+	}
+	if len(dirty) > 0 {
+		slices.Sort(dirty)
+		return fmt.Errorf("the changes to these files in the working tree would be overwritten by the merge:\n%v", strings.Join(dirty, "\n"))
+	}
+
+	for _, c := range changes {
+		if c.exists {
+			if err := structures.WriteEntry(c.name, c.mode, c.content); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.Remove(c.name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		removeEmptyDirs(path.Dir(c.name))
+	}
+
+	written := make(map[string]bool)
+	for _, c := range changes {
+		written[c.name] = true
+	}
+
+	// The entries of the files m does not change are kept as they are.
+	index := Index{}
+	replaced := make(map[string]bool)
+	for _, name := range m.Deleted {
+		replaced[name] = true
+	}
+	for _, ie := range m.Entries {
+		replaced[ie.Name] = true
+	}
+	for _, c := range m.Conflicts {
+		replaced[c.Path] = true
+	}
+	for _, ie := range i.Entries {
+		if !replaced[ie.Name] {
+			index.Entries = append(index.Entries, ie)
+		}
+	}
+
+	for _, e := range m.Entries {
+		if prev, ok := entries[e.Name]; ok {
+			e.CreatedDate = prev.CreatedDate
+			e.ModifiedDate = prev.ModifiedDate
+		}
+		if written[e.Name] {
+			s, err := os.Lstat(e.Name)
+			if err != nil {
+				return err
+			}
+			e.ModifiedDate = s.ModTime()
+		}
+
+		index.Entries = append(index.Entries, e)
+	}
+	// The working tree has the conflict markers, which are not in the Index,
+	// so the files show as modified until they are added.
+	for _, c := range m.Conflicts {
+		for stage, ie := range map[Stage]*IndexEntry{StageBase: c.Base, StageOurs: c.Ours, StageTheirs: c.Theirs} {
+			if ie == nil {
+				continue
+			}
+
+			e := *ie
+			e.Stage = stage
+			if prev, ok := entries[e.Name]; ok {
+				e.CreatedDate = prev.CreatedDate
+			}
+			index.Entries = append(index.Entries, e)
+		}
+	}
+
+	return index.saveIndex()
+}
+
+// treeFiles returns the files of t by their path.
+func treeFiles(t *structures.Tree) (map[string]*IndexEntry, error) {
+	files := make(map[string]*IndexEntry)
+	if t == nil {
+		return files, nil
+	}
+
+	err := t.Walk(func(name string, te *structures.TreeEntry) error {
+		if te.Kind != structures.KindTree {
+			files[name] = &IndexEntry{EntryHash: te.EntryHash, Name: name, Mode: te.Mode}
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// AbortMerge resets the Index to head, the tree of HEAD, and writes the files
+// whose entries in the Index differ from head, including the files with
+// conflicts, to the working tree. The changes in the working tree to other
+// files are kept. Nothing is changed when a file without conflicts which has
+// to be written has changes in the working tree which are not in the Index.
+func AbortMerge(head *structures.Tree) error {
+	i, err := FetchIndex()
+	if err != nil && !errors.Is(err, ErrIndexNotFound) {
+		return err
+	}
+
+	files, err := treeFiles(head)
+	if err != nil {
+		return err
+	}
+
+	changed := make(map[string]bool)
+	kept := make(map[string]IndexEntry)
+	created := make(map[string]time.Time)
+	var dirty []string
+	for _, ie := range i.Entries {
+		created[ie.Name] = ie.CreatedDate
+		if ie.Stage == StageMerged && sameFile(files[ie.Name], &ie) {
+			kept[ie.Name] = ie
+			continue
+		}
+		changed[ie.Name] = true
+
+		if ie.Stage == StageMerged {
+			modified, err := ie.IsModified()
+			if err != nil {
+				return err
+			}
+			if deleted, _ := ie.IsDeleted(); modified && !deleted {
+				dirty = append(dirty, ie.Name)
+			}
+		}
+	}
+	for name := range files {
+		if _, ok := kept[name]; !ok {
+			changed[name] = true
+		}
+	}
+	if len(dirty) > 0 {
+		slices.Sort(dirty)
+		return fmt.Errorf("the changes to these files in the working tree would be overwritten:\n%v", strings.Join(dirty, "\n"))
+	}
+
+	names := slices.Sorted(maps.Keys(changed))
+	// Files which HEAD does not have are removed first, so a directory HEAD
+	// has in their place can be written.
+	for _, name := range names {
+		if files[name] != nil {
+			continue
+		}
+
+		if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		removeEmptyDirs(path.Dir(name))
+	}
+
+	index := Index{}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		e, ok := kept[name]
+		if !ok {
+			e = *files[name]
+			e.CreatedDate = created[name]
+			content, err := diff.LoadBlob(name, e.Mode, e.EntryHash)
+			if err != nil {
+				return fmt.Errorf("'%v': %w", name, err)
+			}
+			if err = structures.WriteEntry(name, e.Mode, content); err != nil {
+				return err
+			}
+
+			s, err := os.Lstat(name)
+			if err != nil {
+				return err
+			}
+			e.ModifiedDate = s.ModTime()
+		}
+
+		index.Entries = append(index.Entries, e)
+	}
+
+	return index.saveIndex()
+}
+
+// Conflicts returns the files of index with conflicts, sorted by path. Their
+// Content and Mode are not set.
+func (index Index) Conflicts() []MergeConflict {
+	var conflicts []MergeConflict
+	for _, ie := range index.Entries {
+		if ie.Stage == StageMerged {
+			continue
+		}
+
+		if len(conflicts) == 0 || conflicts[len(conflicts)-1].Path != ie.Name {
+			conflicts = append(conflicts, MergeConflict{Path: ie.Name})
+		}
+
+		c := &conflicts[len(conflicts)-1]
+		switch ie.Stage {
+		case StageBase:
+			c.Base = &ie
+		case StageOurs:
+			c.Ours = &ie
+		case StageTheirs:
+			c.Theirs = &ie
+		}
+	}
+
+	for i := range conflicts {
+		c := &conflicts[i]
+		switch {
+		case c.Ours == nil || c.Theirs == nil:
+			c.Reason = "modify/delete"
+		case c.Base == nil:
+			c.Reason = "add/add"
+		default:
+			c.Reason = "content"
+		}
+	}
+
+	return conflicts
+}
+
+// resolved returns the entries of index with the files with conflicts as HEAD
+// has them: their StageOurs entry, or none when HEAD does not have them.
+func (index Index) resolved() []IndexEntry {
+	entries := make([]IndexEntry, 0, len(index.Entries))
+	for _, ie := range index.Entries {
+		if ie.Stage == StageMerged || ie.Stage == StageOurs {
+			entries = append(entries, ie)
+		}
+	}
+
+	return entries
+}
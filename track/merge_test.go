@@ -0,0 +1,248 @@
+package track
+
+import (
+	"armanVersionControl/diff"
+	"armanVersionControl/structures"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+var testMergeLabels = diff.MergeLabels{Ours: "HEAD", Base: "base", Theirs: "theirs"}
+
+// storeTree stores the Tree of regular files with the given contents by their
+// path, and fetches it back.
+func storeTree(t *testing.T, files map[string]string) *structures.Tree {
+	t.Helper()
+
+	var index Index
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		h, err := structures.Blob{Content: []byte(files[name])}.StoreBlob()
+		if err != nil {
+			t.Fatal(err)
+		}
+		index.Entries = append(index.Entries, IndexEntry{EntryHash: h, Name: name, Mode: structures.ModeRegular})
+	}
+
+	tree, err := index.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := tree.StoreTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree, err = structures.FetchTreeish(h); err != nil {
+		t.Fatal(err)
+	}
+
+	return &tree
+}
+
+// commitFiles writes files to the working tree, adds them and returns the Tree
+// of the Index, like HEAD has it after committing them.
+func commitFiles(t *testing.T, files map[string]string) *structures.Tree {
+	t.Helper()
+
+	for name, content := range files {
+		writeFile(t, name, content)
+		if err := Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := WriteTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := structures.FetchTreeish(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tree
+}
+
+// loadBlob returns the content of the Blob of ie.
+func loadBlob(t *testing.T, ie *IndexEntry) string {
+	t.Helper()
+
+	c, err := diff.LoadBlob(ie.Name, ie.Mode, ie.EntryHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(c)
+}
+
+// The base, ours and theirs trees of the merge tests. The merge changes a, c
+// and sub/x, deletes g and keeps b and e as ours has them, while d, f and m
+// conflict.
+var (
+	mergeBase = map[string]string{
+		"a": "1\n2\n3\n", "b": "b\n", "d": "d\n", "g": "g\n", "m": "m\n",
+	}
+	mergeOurs = map[string]string{
+		"a": "X\n2\n3\n", "d": "ours\n", "e": "e\n", "f": "ours\n", "g": "g\n", "m": "ours\n",
+	}
+	mergeTheirs = map[string]string{
+		"a": "1\n2\nY\n", "b": "b\n", "c": "c\n", "d": "theirs\n", "f": "theirs\n", "sub/x": "x\n",
+	}
+)
+
+func TestMergeTrees(t *testing.T) {
+	initTempRepo(t)
+
+	m, err := MergeTrees(storeTree(t, mergeBase), storeTree(t, mergeOurs), storeTree(t, mergeTheirs), testMergeLabels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make(map[string]string)
+	for _, ie := range m.Entries {
+		entries[ie.Name] = loadBlob(t, &ie)
+	}
+	wantEntries := map[string]string{"a": "X\n2\nY\n", "c": "c\n", "sub/x": "x\n"}
+	if !reflect.DeepEqual(entries, wantEntries) {
+		t.Errorf("got entries %v, want %v", entries, wantEntries)
+	}
+	if want := []string{"g"}; !slices.Equal(m.Deleted, want) {
+		t.Errorf("got deleted %v, want %v", m.Deleted, want)
+	}
+
+	tests := []struct {
+		path    string
+		reason  string
+		content string
+	}{
+		{"d", "content", "<<<<<<< HEAD\nours\n||||||| base\nd\n=======\ntheirs\n>>>>>>> theirs\n"},
+		{"f", "add/add", "<<<<<<< HEAD\nours\n||||||| base\n=======\ntheirs\n>>>>>>> theirs\n"},
+		{"m", "modify/delete", "ours\n"},
+	}
+	if len(m.Conflicts) != len(tests) {
+		t.Fatalf("got %v conflicts, want %v", len(m.Conflicts), len(tests))
+	}
+	for i, tt := range tests {
+		c := m.Conflicts[i]
+		if c.Path != tt.path || c.Reason != tt.reason || string(c.Content) != tt.content || c.Mode != structures.ModeRegular {
+			t.Errorf("got %v conflict for '%v' with %q, want %v conflict for '%v' with %q",
+				c.Reason, c.Path, c.Content, tt.reason, tt.path, tt.content)
+		}
+	}
+
+	if c := m.Conflicts[1]; c.Base != nil || c.Ours == nil || c.Theirs == nil {
+		t.Errorf("add/add conflict has versions %v, %v, %v, want no base", c.Base, c.Ours, c.Theirs)
+	}
+	if c := m.Conflicts[2]; c.Base == nil || c.Ours == nil || c.Theirs != nil {
+		t.Errorf("modify/delete conflict has versions %v, %v, %v, want no theirs", c.Base, c.Ours, c.Theirs)
+	}
+}
+
+func TestMergeTreesWithoutChanges(t *testing.T) {
+	initTempRepo(t)
+	tree := storeTree(t, mergeBase)
+
+	// Theirs is the base, or the same as ours.
+	for _, theirs := range []*structures.Tree{tree, storeTree(t, mergeOurs)} {
+		m, err := MergeTrees(tree, storeTree(t, mergeOurs), theirs, testMergeLabels)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Entries)+len(m.Deleted)+len(m.Conflicts) > 0 {
+			t.Errorf("got %+v, want nothing to change", m)
+		}
+	}
+}
+
+func TestCheckoutMergeAndAbortMerge(t *testing.T) {
+	initTempRepo(t)
+	head := commitFiles(t, mergeOurs)
+
+	m, err := MergeTrees(storeTree(t, mergeBase), head, storeTree(t, mergeTheirs), testMergeLabels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A change in the working tree which is not in the Index would be lost.
+	writeFile(t, "a", "changed\n")
+	if err = CheckoutMerge(m); err == nil {
+		t.Fatal("CheckoutMerge overwrote a changed file")
+	}
+	if _, err = os.Lstat("c"); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("CheckoutMerge wrote files although it failed")
+	}
+	writeFile(t, "a", mergeOurs["a"])
+
+	if err = CheckoutMerge(m); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"a":     "X\n2\nY\n",
+		"c":     "c\n",
+		"sub/x": "x\n",
+		"d":     "<<<<<<< HEAD\nours\n||||||| base\nd\n=======\ntheirs\n>>>>>>> theirs\n",
+		"m":     "ours\n",
+		"e":     "e\n",
+	} {
+		if c, err := os.ReadFile(name); err != nil || string(c) != want {
+			t.Errorf("'%v' is %q, %v, want %q", name, c, err, want)
+		}
+	}
+	if _, err = os.Lstat("g"); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("'g', which theirs deleted, is still in the working tree")
+	}
+
+	i, err := FetchIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conflicts []string
+	for _, c := range i.Conflicts() {
+		conflicts = append(conflicts, c.Path+" "+c.Reason)
+	}
+	if want := []string{"d content", "f add/add", "m modify/delete"}; !slices.Equal(conflicts, want) {
+		t.Errorf("got conflicts %v in the index, want %v", conflicts, want)
+	}
+	a := indexEntry(t, "a")
+	if got := loadBlob(t, &a); got != "X\n2\nY\n" {
+		t.Errorf("'a' is %q in the index, want the merged content", got)
+	}
+
+	// Giving up the merge brings back the files and the Index of HEAD.
+	if err = AbortMerge(head); err != nil {
+		t.Fatal(err)
+	}
+
+	i, err = FetchIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := treeFiles(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(i.Entries) != len(files) {
+		t.Errorf("got %v index entries, want the %v files of HEAD", len(i.Entries), len(files))
+	}
+	for _, ie := range i.Entries {
+		if ie.Stage != StageMerged || !sameFile(files[ie.Name], &ie) {
+			t.Errorf("index entry %+v is not the one of HEAD", ie)
+		}
+	}
+
+	for name, want := range mergeOurs {
+		if c, err := os.ReadFile(name); err != nil || string(c) != want {
+			t.Errorf("'%v' is %q, %v after the abort, want %q", name, c, err, want)
+		}
+	}
+	for _, name := range []string{"c", "sub"} {
+		if _, err = os.Lstat(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("'%v', which the merge added, is still there after the abort", name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package track
+
+import (
+	"armanVersionControl/structures"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ReadTree reads the entries of t into the Index. By default the Index is
+// replaced with the content of t. When merge is true, the entries of t are
+// added to the Index, replacing any entries with the same name, including
+// conflicts, and keeping the rest. When prefix is not empty, t is read as the
+// content of the prefix directory and the Index must not have any entry under
+// prefix already. Nothing is changed when an entry of t would be where the
+// Index has a file as a directory, or the other way around.
+//
+// Trees do not record modification times, so an entry of t whose content and
+// mode are the same as a merged entry of the Index keeps the dates recorded
+// there, which checkout can restore.
+func ReadTree(t structures.Tree, prefix string, merge bool) error {
+	i, err := FetchIndex()
+	if err != nil && !errors.Is(err, ErrIndexNotFound) {
+		return err
+	}
+
+	if prefix != "" {
+		prefix, err = normalizeName(prefix)
+		if err != nil {
+			return err
+		}
+
+		dir := prefix + "/"
+		overlaps := slices.ContainsFunc(i.Entries, func(ie IndexEntry) bool {
+			return ie.Name == prefix || strings.HasPrefix(ie.Name, dir)
+		})
+		if overlaps {
+			return fmt.Errorf("'%v' already exists in index", prefix)
+		}
+
+		prefix = dir
+		merge = true
+	}
+
+	recorded := make(map[string]IndexEntry, len(i.Entries))
+	for _, ie := range i.Entries {
+		if ie.Stage == StageMerged {
+			recorded[ie.Name] = ie
+		}
+	}
+
+	if !merge {
+		i.Entries = nil
+	}
+
+	err = t.Walk(func(name string, te *structures.TreeEntry) error {
+		if te.Kind == structures.KindTree {
+			return nil
+		}
+
+		e := IndexEntry{
+			EntryHash: te.EntryHash,
+			Name:      prefix + name,
+			Mode:      te.Mode,
+		}
+		if prev, ok := recorded[e.Name]; ok && prev.EntryHash == e.EntryHash && prev.Mode == e.Mode {
+			e.CreatedDate = prev.CreatedDate
+			e.ModifiedDate = prev.ModifiedDate
+		}
+
+		i.Entries = slices.DeleteFunc(i.Entries, func(ie IndexEntry) bool {
+			return ie.Name == e.Name
+		})
+		i.Entries = append(i.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = checkFileDirs(i.Entries); err != nil {
+		return err
+	}
+
+	return i.saveIndex()
+}
+
+// checkFileDirs returns an error when a directory of one of entries is the
+// name of another entry.
+func checkFileDirs(entries []IndexEntry) error {
+	names := make(map[string]bool, len(entries))
+	for _, ie := range entries {
+		names[ie.Name] = true
+	}
+
+	for _, ie := range entries {
+		for dir := path.Dir(ie.Name); dir != "."; dir = path.Dir(dir) {
+			if names[dir] {
+				return fmt.Errorf("'%v' is a file in index and can not be a directory of '%v'", dir, ie.Name)
+			}
+		}
+	}
+
+	return nil
+}
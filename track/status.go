@@ -0,0 +1,229 @@
+package track
+
+import (
+	"armanVersionControl/diff"
+	"armanVersionControl/refs"
+	"armanVersionControl/storage"
+	"armanVersionControl/structures"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// IsDeleted checks whether the file of ie no longer exists in the working tree.
+func (ie IndexEntry) IsDeleted() (bool, error) {
+	if _, err := os.Lstat(ie.Name); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	return false, nil
+}
+
+// IsModified checks whether the file of ie in the working tree is different
+// from what is recorded in the Index. A deleted file counts as modified.
+// When the mode and modification time did not change, the file is assumed to
+// be unchanged, otherwise its content is hashed and compared to ie.EntryHash.
+func (ie IndexEntry) IsModified() (bool, error) {
+	s, err := os.Lstat(ie.Name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	mode, err := structures.ModeFromFileMode(s.Mode())
+	if err != nil || mode != ie.Mode {
+		return true, nil
+	}
+
+	if s.ModTime().Equal(ie.ModifiedDate) {
+		return false, nil
+	}
+
+	c, err := structures.ReadEntryContent(ie.Name, mode)
+	if err != nil {
+		return false, err
+	}
+
+	h, err := structures.Blob{Content: c}.ComputeHash()
+	if err != nil {
+		return false, err
+	}
+
+	return h != ie.EntryHash, nil
+}
+
+// WorkingTree creates the nested Tree of the files of index as they are in
+// the working tree, without storing anything in the object database. Deleted
+// files are left out and the Blob hashes of modified files are computed from
+// their content, so the result can be compared to Index.Tree.
+func (index Index) WorkingTree() (structures.Tree, error) {
+	entries := make([]*structures.TreeEntry, 0, len(index.Entries))
+	for _, ie := range index.resolved() {
+		modified, err := ie.IsModified()
+		if err != nil {
+			return structures.Tree{}, err
+		}
+
+		te := ie.treeEntry()
+		if !modified {
+			entries = append(entries, te)
+			continue
+		}
+
+		s, err := os.Lstat(ie.Name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return structures.Tree{}, err
+		}
+
+		// A file which was replaced by a directory or something avc can not
+		// track is treated as deleted.
+		mode, err := structures.ModeFromFileMode(s.Mode())
+		if err != nil || mode == structures.ModeTree {
+			continue
+		}
+
+		c, err := structures.ReadEntryContent(ie.Name, mode)
+		if err != nil {
+			return structures.Tree{}, err
+		}
+
+		h, err := structures.Blob{Content: c}.ComputeHash()
+		if err != nil {
+			return structures.Tree{}, err
+		}
+
+		te.Mode, te.EntryHash = mode, h
+		entries = append(entries, te)
+	}
+
+	return structures.NewTreeFromEntries(entries)
+}
+
+// Untracked returns the names of files in the working tree which are not
+// in index. Only regular files and symbolic links are considered.
+func Untracked(index Index) ([]string, error) {
+	tracked := make(map[string]bool, len(index.Entries))
+	for _, ie := range index.Entries {
+		tracked[ie.Name] = true
+	}
+
+	var output []string
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == storage.MainDir {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if _, err = structures.ModeFromFileMode(d.Type()); err != nil {
+			return nil
+		}
+
+		name := filepath.ToSlash(path)
+		if !tracked[name] {
+			output = append(output, name)
+		}
+
+		return nil
+	})
+
+	return output, err
+}
+
+// Status is the difference between HEAD, the Index and the working tree.
+type Status struct {
+	// Staged are the changes of the Index compared to HEAD, which the next
+	// commit records.
+	Staged []diff.Change
+	// Unstaged are the changes of the working tree compared to the Index.
+	Unstaged []diff.Change
+	// Untracked are the names of the files in the working tree which are
+	// not in the Index.
+	Untracked []string
+	// Unmerged are the files with conflicts, which are in neither Staged nor
+	// Unstaged.
+	Unmerged []MergeConflict
+}
+
+// FetchStatus compares HEAD, the Index and the working tree. Before the first
+// commit, everything in the Index is staged. When renames is not nil, renamed
+// and copied files are detected among the staged changes with it.
+func FetchStatus(renames *diff.RenameOptions) (Status, error) {
+	index, err := FetchIndex()
+	if err != nil && !errors.Is(err, ErrIndexNotFound) {
+		return Status{}, err
+	}
+
+	var head *structures.Tree
+	hash, err := refs.Resolve(refs.HEAD)
+	if err != nil && !errors.Is(err, refs.ErrRefNotFound) {
+		return Status{}, err
+	}
+	if err == nil {
+		t, err := structures.FetchTreeish(hash)
+		if err != nil {
+			return Status{}, err
+		}
+		head = &t
+	}
+
+	it, err := index.Tree()
+	if err != nil {
+		return Status{}, err
+	}
+
+	wt, err := index.WorkingTree()
+	if err != nil {
+		return Status{}, err
+	}
+
+	var s Status
+	opts := diff.TreeOptions{Recursive: true}
+	if s.Staged, err = diff.Trees(head, &it, opts); err != nil {
+		return Status{}, err
+	}
+	if renames != nil {
+		if s.Staged, err = diff.DetectRenames(s.Staged, *renames, diff.LoadBlob, diff.LoadBlob); err != nil {
+			return Status{}, err
+		}
+	}
+
+	if s.Unstaged, err = diff.Trees(&it, &wt, opts); err != nil {
+		return Status{}, err
+	}
+
+	if s.Untracked, err = Untracked(index); err != nil {
+		return Status{}, err
+	}
+
+	s.Unmerged = index.Conflicts()
+	unmerged := make(map[string]bool)
+	for _, c := range s.Unmerged {
+		unmerged[c.Path] = true
+	}
+	isUnmerged := func(c diff.Change) bool {
+		return unmerged[c.Path]
+	}
+	s.Staged = slices.DeleteFunc(s.Staged, isUnmerged)
+	s.Unstaged = slices.DeleteFunc(s.Unstaged, isUnmerged)
+
+	return s, nil
+}
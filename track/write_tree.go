@@ -0,0 +1,63 @@
+package track
+
+import (
+	"armanVersionControl/structures"
+	"errors"
+)
+
+var (
+	ErrIndexIsEmpty    = errors.New("index is empty, there is nothing to write")
+	ErrUnmergedEntries = errors.New("index has files with conflicts, resolve them and add the files first")
+)
+
+// WriteTree creates nested Tree objects from the current Index, stores them
+// in the object database and returns the hash of the root Tree.
+// The Blobs of entries are expected to be stored already, which Add does.
+// Nothing is written while the Index has conflicts.
+func WriteTree() (string, error) {
+	i, err := FetchIndex()
+	if err != nil {
+		if errors.Is(err, ErrIndexNotFound) {
+			return "", ErrIndexIsEmpty
+		}
+
+		return "", err
+	}
+
+	if len(i.Entries) == 0 {
+		return "", ErrIndexIsEmpty
+	}
+	if len(i.Conflicts()) > 0 {
+		return "", ErrUnmergedEntries
+	}
+
+	t, err := i.Tree()
+	if err != nil {
+		return "", err
+	}
+
+	return t.StoreTree()
+}
+
+// Tree creates the nested Tree of index without storing anything in the
+// object database. Subtrees are only referenced by the returned Tree, while
+// files are referenced by the hash of their Blob. Files with conflicts are
+// in the Tree as HEAD has them, see Index.resolved.
+func (index Index) Tree() (structures.Tree, error) {
+	entries := make([]*structures.TreeEntry, 0, len(index.Entries))
+	for _, ie := range index.resolved() {
+		entries = append(entries, ie.treeEntry())
+	}
+
+	return structures.NewTreeFromEntries(entries)
+}
+
+// treeEntry creates the TreeEntry of ie, named by its full path.
+func (ie IndexEntry) treeEntry() *structures.TreeEntry {
+	return &structures.TreeEntry{
+		Kind:      structures.KindBlob,
+		Mode:      ie.Mode,
+		EntryHash: ie.EntryHash,
+		Name:      ie.Name,
+	}
+}